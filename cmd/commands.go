@@ -0,0 +1,355 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/alerts"
+	"github.com/gauravkr19/jenkins-analytics/internal/archive"
+	"github.com/gauravkr19/jenkins-analytics/internal/backfill"
+	"github.com/gauravkr19/jenkins-analytics/internal/classify"
+	"github.com/gauravkr19/jenkins-analytics/internal/config"
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/internal/envrules"
+	"github.com/gauravkr19/jenkins-analytics/internal/reports"
+	"github.com/gauravkr19/jenkins-analytics/internal/retention"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// runCommand executes a one-off maintenance subcommand instead of the server.
+func runCommand(args []string, cfg *config.EnvConfig, database *db.DB) error {
+	switch args[0] {
+	case "reclassify":
+		return reclassifyCommand(args[1:], cfg, database)
+	case "relabel-env":
+		return relabelEnvCommand(args[1:], cfg, database)
+	case "backfill":
+		return backfillCommand(args[1:], cfg, database)
+	case "migrate":
+		return migrateCommand(args[1:], database)
+	case "retention":
+		return retentionCommand(args[1:], database)
+	case "restore":
+		return restoreCommand(args[1:], database)
+	case "rollups":
+		return rollupsCommand(args[1:], database)
+	case "alerts":
+		return alertsCommand(args[1:], cfg)
+	case "reports":
+		return reportsCommand(args[1:], cfg, database)
+	default:
+		return fmt.Errorf("unknown command %q (available: reclassify, relabel-env, backfill, migrate, retention, restore, rollups, alerts, reports)", args[0])
+	}
+}
+
+// reclassify [-since YYYY-MM-DD] [-batch N]
+func reclassifyCommand(args []string, cfg *config.EnvConfig, database *db.DB) error {
+	fs := flag.NewFlagSet("reclassify", flag.ExitOnError)
+	sinceStr := fs.String("since", "", "only builds started on or after this date (YYYY-MM-DD)")
+	batch := fs.Int("batch", 500, "builds per batch")
+	fs.Parse(args)
+
+	var since time.Time
+	if *sinceStr != "" {
+		var err error
+		if since, err = time.Parse("2006-01-02", *sinceStr); err != nil {
+			return fmt.Errorf("invalid -since: %w", err)
+		}
+	}
+
+	classifier, err := classify.NewClassifier(cfg.FailureRulesFile)
+	if err != nil {
+		return err
+	}
+
+	n, err := classify.Reclassify(database, classifier, since, *batch)
+	if err != nil {
+		return err
+	}
+	log.Printf("Reclassified %d failed builds using %d rules", n, len(classifier.Rules()))
+	return nil
+}
+
+// relabel-env [-dry-run] [-since YYYY-MM-DD] [-batch N]
+// Runs the env backfill task over every build again, or over builds since a date.
+func relabelEnvCommand(args []string, cfg *config.EnvConfig, database *db.DB) error {
+	fs := flag.NewFlagSet("relabel-env", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only show how builds would be re-labelled")
+	sinceStr := fs.String("since", "", "only builds started on or after this date (YYYY-MM-DD)")
+	batch := fs.Int("batch", 1000, "builds per batch")
+	fs.Parse(args)
+
+	var since time.Time
+	if *sinceStr != "" {
+		var err error
+		if since, err = time.Parse("2006-01-02", *sinceStr); err != nil {
+			return fmt.Errorf("invalid -since: %w", err)
+		}
+	}
+
+	rules, err := envrules.LoadRules(cfg.EnvRulesFile)
+	if err != nil {
+		return err
+	}
+
+	report := &envrules.Report{}
+	task := backfill.EnvTask(rules, report)
+	p, err := backfill.NewRunner(database, task).Run(task.Name, backfill.Options{BatchSize: *batch, DryRun: *dryRun, Restart: true, Since: since})
+	if err != nil {
+		return err
+	}
+
+	verb := "Re-labelled"
+	if *dryRun {
+		verb = "Would re-label"
+		for _, c := range report.Samples {
+			fmt.Println(c)
+		}
+	}
+	for _, t := range report.Transitions() {
+		fmt.Printf("%8d  %s -> %s\n", t.Builds, t.From, t.To)
+	}
+	log.Printf("%s %d of %d builds using %d rules from %s", verb, p.Changed, p.Scanned, len(rules.Rules), cfg.EnvRulesFile)
+	return nil
+}
+
+// backfill [-dry-run] [-restart] [-batch N] [-pause D] <task>, or -list
+func backfillCommand(args []string, cfg *config.EnvConfig, database *db.DB) error {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	list := fs.Bool("list", false, "list tasks and their checkpoints")
+	dryRun := fs.Bool("dry-run", false, "only count the builds that would change")
+	restart := fs.Bool("restart", false, "ignore the checkpoint and start from the first build")
+	batch := fs.Int("batch", 1000, "builds per batch")
+	pause := fs.Duration("pause", 100*time.Millisecond, "sleep between batches")
+	fs.Parse(args)
+
+	rules, err := envrules.LoadRules(cfg.EnvRulesFile)
+	if err != nil {
+		return err
+	}
+	runner := backfill.NewRunner(database, backfill.DefaultTasks(rules)...)
+
+	if *list || fs.NArg() == 0 {
+		status, err := runner.Status()
+		if err != nil {
+			return err
+		}
+		for i, t := range runner.Tasks() {
+			p := status[i]
+			state := "not started"
+			if p.Done {
+				state = "done"
+			} else if p.AfterID > 0 {
+				state = fmt.Sprintf("at build id %d", p.AfterID)
+			}
+			fmt.Printf("%-14s v%-3d %-16s %s\n", t.Name, t.Version, state, t.Description)
+		}
+		return nil
+	}
+
+	p, err := runner.Run(fs.Arg(0), backfill.Options{BatchSize: *batch, Pause: *pause, DryRun: *dryRun, Restart: *restart})
+	if err != nil {
+		return err
+	}
+	verb := "Backfilled"
+	if *dryRun {
+		verb = "Would backfill"
+	}
+	log.Printf("%s %s v%d: %d of %d builds changed", verb, p.Task, p.Version, p.Changed, p.Scanned)
+	return nil
+}
+
+// migrate [up | down [-steps N] | status]
+func migrateCommand(args []string, database *db.DB) error {
+	action := "up"
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+
+	switch action {
+	case "up":
+		n, err := database.MigrateUp()
+		if err != nil {
+			return err
+		}
+		log.Printf("Applied %d migrations", n)
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ExitOnError)
+		steps := fs.Int("steps", 1, "number of migrations to revert")
+		fs.Parse(args)
+
+		n, err := database.MigrateDown(*steps)
+		if err != nil {
+			return err
+		}
+		log.Printf("Reverted %d migrations", n)
+	case "status":
+		status, err := database.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		for _, m := range status {
+			applied := "pending"
+			if m.AppliedAt != nil {
+				applied = m.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%03d  %-28s %s\n", m.Version, m.Name, applied)
+		}
+	default:
+		return fmt.Errorf("unknown migrate action %q (available: up, down, status)", action)
+	}
+	return nil
+}
+
+// retention [-dry-run]
+func retentionCommand(args []string, database *db.DB) error {
+	fs := flag.NewFlagSet("retention", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only show what would be removed")
+	fs.Parse(args)
+
+	cfg := config.DataRetentionConfig()
+	policies, err := retention.LoadPolicies(cfg.PoliciesFile)
+	if err != nil {
+		return err
+	}
+	store, err := archive.NewStore(config.BuildArchiveConfig())
+	if err != nil {
+		return err
+	}
+
+	report, err := retention.Run(database, policies, retention.Options{
+		PartitionAction: cfg.PartitionAction,
+		DeleteBatch:     cfg.DeleteBatch,
+		MonthsAhead:     cfg.MonthsAhead,
+		DryRun:          *dryRun,
+		Archive:         store,
+	}, time.Now())
+	if err != nil {
+		return err
+	}
+
+	verb := cfg.PartitionAction
+	if *dryRun {
+		verb = "would " + verb
+	}
+	for _, name := range report.Created {
+		fmt.Printf("created   %s\n", name)
+	}
+	for _, name := range report.Expired {
+		fmt.Printf("%-9s %s\n", cfg.PartitionAction, name)
+	}
+	for _, name := range policies.Names() {
+		fmt.Printf("%8d  expired builds under %s\n", report.Deleted[name], name)
+	}
+	for _, file := range report.Archived {
+		fmt.Printf("archived  %s/%s\n", store, file)
+	}
+	log.Printf("Retention: %s %d partitions (%d builds)", verb, len(report.Expired), report.Affected)
+	return nil
+}
+
+// restore [-table NAME] <archive file>
+func restoreCommand(args []string, database *db.DB) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	table := fs.String("table", "", "scratch table to create (default restore_<archive name>)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: restore [-table NAME] <archive file, e.g. 2025/06/01/builds-prod-20250601T030000Z.ndjson.gz>")
+	}
+
+	store, err := archive.NewStore(config.BuildArchiveConfig())
+	if err != nil {
+		return err
+	}
+	if store == nil {
+		return fmt.Errorf("no archive configured, set ARCHIVE_DIR or ARCHIVE_S3_BUCKET")
+	}
+
+	file := fs.Arg(0)
+	if *table == "" {
+		*table = archive.RestoreTable(file)
+	}
+	n, err := archive.Restore(database, store, file, *table)
+	if err != nil {
+		return err
+	}
+	log.Printf("Restored %d rows from %s/%s into table %s", n, store, file, *table)
+	return nil
+}
+
+// rollups rebuild [-since YYYY-MM-DD]
+func rollupsCommand(args []string, database *db.DB) error {
+	if len(args) == 0 || args[0] != "rebuild" {
+		return fmt.Errorf("usage: rollups rebuild [-since YYYY-MM-DD]")
+	}
+	fs := flag.NewFlagSet("rollups rebuild", flag.ExitOnError)
+	sinceStr := fs.String("since", "", "only recompute days from this date on (default every day that still has builds)")
+	fs.Parse(args[1:])
+
+	var since time.Time
+	if *sinceStr != "" {
+		var err error
+		if since, err = time.Parse("2006-01-02", *sinceStr); err != nil {
+			return fmt.Errorf("invalid -since %q: %w", *sinceStr, err)
+		}
+	}
+	n, err := database.RebuildRollups(since)
+	if err != nil {
+		return err
+	}
+	log.Printf("Rebuilt %d rollup rows", n)
+	return nil
+}
+
+// alerts test <channel>
+func alertsCommand(args []string, cfg *config.EnvConfig) error {
+	if len(args) != 2 || args[0] != "test" {
+		return fmt.Errorf("usage: alerts test <channel>")
+	}
+	if cfg.AlertRulesFile == "" {
+		return fmt.Errorf("no alert rules configured, set ALERT_RULES_FILE")
+	}
+	rules, err := alerts.LoadConfig(cfg.AlertRulesFile)
+	if err != nil {
+		return err
+	}
+
+	err = alerts.NewEngine(rules).Send(args[1], &models.Alert{
+		Rule:     "test",
+		Severity: "warning",
+		Title:    "Test alert from jenkins-analytics",
+		Message:  fmt.Sprintf("Channel %s of %s is reachable.", args[1], cfg.AlertRulesFile),
+		FiredAt:  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Sent a test alert to %s", args[1])
+	return nil
+}
+
+// reports send <name>
+func reportsCommand(args []string, cfg *config.EnvConfig, database *db.DB) error {
+	if len(args) != 2 || args[0] != "send" {
+		return fmt.Errorf("usage: reports send <name>")
+	}
+	if cfg.ReportsFile == "" {
+		return fmt.Errorf("no reports configured, set REPORT_SCHEDULES_FILE")
+	}
+	schedules, err := reports.LoadConfig(cfg.ReportsFile, reportRange)
+	if err != nil {
+		return err
+	}
+
+	run, err := reports.NewScheduler(schedules, database, reportRange).SendNow(args[1])
+	if err != nil {
+		return err
+	}
+	if run.Error != "" {
+		return fmt.Errorf("sending %s: %s", args[1], run.Error)
+	}
+	log.Printf("Sent %s to %d recipients", args[1], len(run.Recipients))
+	return nil
+}
@@ -5,11 +5,20 @@ import (
 	"os"
 	"time"
 
+	"github.com/gauravkr19/jenkins-analytics/internal/alerts"
 	"github.com/gauravkr19/jenkins-analytics/internal/api"
+	"github.com/gauravkr19/jenkins-analytics/internal/archive"
+	"github.com/gauravkr19/jenkins-analytics/internal/backfill"
+	"github.com/gauravkr19/jenkins-analytics/internal/classify"
+	"github.com/gauravkr19/jenkins-analytics/internal/compliance"
 	"github.com/gauravkr19/jenkins-analytics/internal/config"
 	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/internal/envrules"
+	"github.com/gauravkr19/jenkins-analytics/internal/igrm"
 	"github.com/gauravkr19/jenkins-analytics/internal/jenkins"
 	"github.com/gauravkr19/jenkins-analytics/internal/poller"
+	"github.com/gauravkr19/jenkins-analytics/internal/reports"
+	"github.com/gauravkr19/jenkins-analytics/internal/retention"
 	"github.com/gauravkr19/jenkins-analytics/internal/sync"
 	"github.com/gauravkr19/jenkins-analytics/internal/web"
 	"github.com/gin-gonic/gin"
@@ -18,31 +27,149 @@ import (
 func main() {
 	// Load and validate config
 	cfg := config.LoadEnvConfig()
-	// drConfig := config.DataRetentionConfig()
 
 	// Step 1: Connect to PostgreSQL
 	database, err := db.NewDB(cfg.DSN)
+	if err != nil {
+		log.Fatalf("Database connection failed: %v", err)
+	}
+
+	// Bring the schema up to date; `server migrate` manages it by hand
+	if cfg.AutoMigrate && (len(os.Args) < 2 || os.Args[1] != "migrate") {
+		if _, err := database.MigrateUp(); err != nil {
+			log.Fatalf("Database migration failed: %v", err)
+		}
+	}
+
+	// Maintenance subcommands, e.g. `server reclassify`
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:], cfg, database); err != nil {
+			log.Fatalf("%s failed: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	controllers, err := config.LoadControllers(cfg)
+	if err != nil {
+		log.Fatalf("Jenkins controller config invalid: %v", err)
+	}
+	// canonical env / deploy_env of every stored build
+	envRules, err := envrules.LoadRules(cfg.EnvRulesFile)
+	if err != nil {
+		log.Fatalf("Environment rules invalid: %v", err)
+	}
+	jenkinsClients := make([]*jenkins.JenkinsClient, 0, len(controllers))
+	for _, c := range controllers {
+		client := jenkins.NewControllerClient(c)
+		client.EnvRules = envRules
+		jenkinsClients = append(jenkinsClients, client)
+	}
 
-	// database.BackfillEnvColumn()
 	// Step 2: Initial Build for first run only
-	if err := sync.SyncInitialBuildsIfNeeded(database); err != nil {
-		log.Fatalf("Initial sync failed: %v", err)
-	}
-
-	jenkinsClient := jenkins.NewJenkinsClient(
-		os.Getenv("JENKINS_URL"),
-		os.Getenv("JENKINS_USER"),
-		os.Getenv("JENKINS_TOKEN"),
-	)
-	// Step 3: Incremental Build to add additional build records
-	poller.StartIncrementalPoller(database, jenkinsClient, 30*time.Minute)
-	// patches the status of the builds which are empty
-	poller.StartStatusPatcher(database, jenkinsClient, 3*time.Hour, 100)
-	// Data retention, delete records over config.RetentionConfig.MaxRecords
-	poller.DeletionRoutine(database, jenkinsClient, 3*time.Hour, config.DataRetentionConfig())
+	for _, client := range jenkinsClients {
+		if err := sync.SyncInitialBuildsIfNeeded(database, client); err != nil {
+			log.Fatalf("Initial sync failed: %v", err)
+		}
+	}
+
+	logConfig := config.ConsoleLogConfig()
+	for i, client := range jenkinsClients {
+		// Step 3: Incremental Build to add additional build records.
+		// Webhooks deliver builds as they happen; polling reconciles anything missed.
+		poller.StartIncrementalPoller(database, client, controllers[i].PollInterval)
+		// patches the status of the builds which are empty
+		poller.StartStatusPatcher(database, client, 3*time.Hour, 100)
+		// snapshots the build queue and finalizes builds stored while running
+		poller.StartRunningTracker(database, client, time.Minute)
+		// snapshots nodes, executors and offline state for utilisation reports
+		poller.StartAgentCollector(database, client, 5*time.Minute, 90*24*time.Hour)
+		// captures console logs of finished builds for triage
+		poller.StartLogCollector(database, client, 5*time.Minute, logConfig)
+		// stores per-stage timings of Pipeline builds from wfapi/describe
+		poller.StartStageCollector(database, client, 5*time.Minute, 100)
+		// stores JUnit results published by builds
+		poller.StartTestCollector(database, client, 5*time.Minute, 100)
+		// stores changeSet commits of builds synced without them
+		poller.StartChangeCollector(database, client, 5*time.Minute, 100)
+	}
+	// tags failed builds with a failure category from their console log
+	classifier, err := classify.NewClassifier(cfg.FailureRulesFile)
+	if err != nil {
+		log.Printf("Failure classification disabled: %v", err)
+	} else {
+		classifier.WatchFile(30 * time.Second)
+		poller.StartFailureClassifier(database, classifier, 5*time.Minute, 200)
+	}
+	// change-ticket rules of the IGRM compliance report
+	igrmPolicy, err := compliance.LoadPolicy(cfg.IGRMPolicyFile)
+	if err != nil {
+		log.Printf("IGRM compliance report disabled: %v", err)
+	}
+	// checks IGRM numbers against the change-management system
+	igrmCfg := config.IGRMValidatorSettings()
+	validator, err := igrm.NewValidator(igrmCfg)
+	if err != nil {
+		log.Printf("IGRM validation disabled: %v", err)
+	} else if validator != nil {
+		log.Printf("Validating IGRM numbers against %s", validator)
+		// only the deployments the compliance report covers, all builds without a policy
+		var env string
+		var deploysOnly bool
+		if igrmPolicy != nil {
+			env, deploysOnly = igrmPolicy.Env, igrmPolicy.DeploysOnly
+		}
+		poller.StartIGRMValidator(database, validator, env, deploysOnly, 5*time.Minute, igrmCfg.Batch)
+	}
+	// alert rules evaluated as builds finish, delivered to chat webhooks and email
+	var alertRules *alerts.Config
+	if cfg.AlertRulesFile != "" {
+		if alertRules, err = alerts.LoadConfig(cfg.AlertRulesFile); err != nil {
+			log.Fatalf("Alert rules invalid: %v", err)
+		}
+		poller.StartAlertEvaluator(database, alerts.NewEngine(alertRules), time.Minute, 200)
+	}
+	// emailed digests, e.g. a weekly Excel and HTML summary every Monday
+	var reportScheduler *reports.Scheduler
+	if cfg.ReportsFile != "" {
+		schedules, err := reports.LoadConfig(cfg.ReportsFile, reportRange)
+		if err != nil {
+			log.Fatalf("Report schedules invalid: %v", err)
+		}
+		reportScheduler = reports.NewScheduler(schedules, database, reportRange)
+		poller.StartReportScheduler(reportScheduler, time.Minute)
+	}
+	// Data retention, drops monthly partitions and builds past their env/folder policy
+	retentionCfg := config.DataRetentionConfig()
+	var policies *retention.Policies
+	if retentionCfg.Enabled {
+		policies, err = retention.LoadPolicies(retentionCfg.PoliciesFile)
+		if err != nil {
+			log.Fatalf("Retention policies invalid: %v", err)
+		}
+		// expiring builds are archived first when ARCHIVE_DIR or ARCHIVE_S3_BUCKET is set
+		store, err := archive.NewStore(config.BuildArchiveConfig())
+		if err != nil {
+			log.Fatalf("Archive config invalid: %v", err)
+		}
+		poller.StartRetention(database, policies, store, 3*time.Hour, retentionCfg)
+	} else {
+		log.Println("[Retention] Build retention disabled")
+	}
+	// keeps the daily rollups and project catalogue behind dashboards current
+	poller.StartRollupRefresher(database, policies, time.Minute, 500)
 
 	// Step 4: Setup Gin routes
-	handler := &api.Handler{DB: database}
+	handler := &api.Handler{
+		DB:           database,
+		Controllers:  jenkinsClients,
+		WebhookToken: cfg.WebhookToken,
+		Classifier:   classifier,
+		ParamColumns: cfg.ParamColumns,
+		Backfills:    backfill.NewRunner(database, backfill.DefaultTasks(envRules)...),
+		IGRMPolicy:   igrmPolicy,
+		AlertRules:   alertRules,
+		Reports:      reportScheduler,
+	}
 	r := gin.Default()
 
 	r.Use(gin.Logger())
@@ -67,6 +194,46 @@ func main() {
 	r.GET("/builds/export", handler.ExportBuildsToExcel)
 	r.GET("/builds/folder", handler.RenderBuildsByFolder)
 	r.GET("/builds/folder/*projectPath", handler.GetPipelineBuilds)
+	r.GET("/builds/running", handler.RenderRunningNow)
+	r.GET("/builds/:id", handler.RenderBuildDetail)
+	r.GET("/builds/:id/log", handler.DownloadBuildLog)
+	r.GET("/analytics/dora", handler.RenderDORA)
+	r.GET("/reports/failure-causes", handler.RenderFailureCauses)
+	r.GET("/reports/flaky-tests", handler.RenderFlakyTests)
+	r.GET("/reports/queue-times", handler.RenderQueueTimes)
+	r.GET("/reports/agents", handler.RenderAgents)
+	r.GET("/reports/trends", handler.RenderTrends)
+	r.GET("/reports/breaking-changes", handler.RenderBreakingChanges)
+	r.GET("/reports/igrm-compliance", handler.RenderIGRMCompliance)
+	r.GET("/reports/igrm-compliance/export", handler.ExportIGRMCompliance)
+	r.POST("/admin/failure-rules/reload", handler.ReloadFailureRules)
+	r.GET("/admin/job-cursors", handler.RenderJobCursors)
+	r.GET("/admin/backfills", handler.RenderBackfills)
+	r.POST("/admin/backfills/:name", handler.StartBackfill)
+	r.GET("/admin/alerts", handler.RenderAlerts)
+	r.GET("/admin/reports", handler.RenderReportRuns)
+	r.POST("/admin/reports/:name/send", handler.SendReport)
+	r.GET("/admin/reports/:name/preview", handler.PreviewReport)
+
+	// Jenkins Notification plugin / generic webhook receiver
+	r.POST("/webhooks/jenkins", handler.JenkinsWebhook)
+
+	// Versioned JSON API
+	v1 := r.Group("/api/v1")
+	v1.GET("/openapi.yaml", handler.OpenAPISpecV1)
+	v1.GET("/builds", handler.ListBuildsV1)
+	v1.GET("/builds/:id", handler.GetBuildV1)
+	v1.GET("/projects", handler.ListProjectsV1)
+	v1.GET("/folders/*path", handler.GetFolderV1)
+	v1.GET("/analytics/dora", handler.DORAReportV1)
+	v1.GET("/analytics/stages", handler.StageTrendV1)
+	v1.GET("/analytics/flaky-tests", handler.FlakyTestsV1)
+	v1.GET("/analytics/queue-times", handler.QueueTimesV1)
+	v1.GET("/analytics/agents", handler.AgentsV1)
+	v1.GET("/analytics/daily", handler.DailyRollupsV1)
+	v1.GET("/analytics/breaking-changes", handler.BreakingChangesV1)
+	v1.GET("/analytics/igrm-compliance", handler.IGRMComplianceV1)
+	v1.GET("/alerts", handler.ListAlertsV1)
 
 	// r.GET("/builds/folder/:folder/:app/:pipeline", handler.GetPipelineBuilds)
 
@@ -97,3 +264,9 @@ func main() {
 		log.Fatalf("Gin server failed: %v", err)
 	}
 }
+
+// reportRange resolves the named ranges of scheduled reports like the dashboard does.
+func reportRange(key string) (time.Time, time.Time, error) {
+	dr, err := api.GetDateRange(key)
+	return dr.From, dr.To, err
+}
@@ -8,6 +8,7 @@ require (
         github.com/jmoiron/sqlx v1.4.0
         github.com/lib/pq v1.10.9
         github.com/xuri/excelize/v2 v2.9.1
+        gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -41,5 +42,4 @@ require (
         golang.org/x/sys v0.33.0 // indirect
         golang.org/x/text v0.25.0 // indirect
         google.golang.org/protobuf v1.34.1 // indirect
-        gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,298 @@
+package alerts
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/mailer"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// fakeStore keeps builds and alert history in memory.
+type fakeStore struct {
+	pending  []models.Build
+	statuses map[string][]string // project path -> newest first
+	total    int
+	success  int
+	alerts   []models.Alert
+	checked  int
+}
+
+func (s *fakeStore) GetBuildsPendingAlerts(limit int) ([]models.Build, error) {
+	return s.pending, nil
+}
+
+func (s *fakeStore) MarkAlertsChecked(builds []models.Build) error {
+	s.checked += len(builds)
+	s.pending = nil
+	return nil
+}
+
+func (s *fakeStore) RecentStatuses(controller, projectPath string, before time.Time, limit int) ([]string, error) {
+	st := s.statuses[projectPath]
+	return st[:min(limit, len(st))], nil
+}
+
+func (s *fakeStore) FolderOutcomes(folder string, from, to time.Time) (int, int, error) {
+	return s.total, s.success, nil
+}
+
+func (s *fakeStore) LastAlertAt(key string) (*time.Time, error) {
+	var last *time.Time
+	for _, a := range s.alerts {
+		if a.DedupKey == key && a.State != models.AlertFailed {
+			t := a.FiredAt
+			last = &t
+		}
+	}
+	return last, nil
+}
+
+func (s *fakeStore) InsertAlert(a *models.Alert) error {
+	a.ID = int64(len(s.alerts) + 1)
+	s.alerts = append(s.alerts, *a)
+	return nil
+}
+
+// smtpSink accepts mail like a minimal SMTP server and hands over each message.
+func smtpSink(t *testing.T) (addr string, mails chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	mails = make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+				reply("220 sink")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+					case strings.HasPrefix(cmd, "DATA"):
+						reply("354 go ahead")
+						var body strings.Builder
+						for {
+							l, err := r.ReadString('\n')
+							if err != nil || l == ".\r\n" {
+								break
+							}
+							body.WriteString(l)
+						}
+						mails <- body.String()
+						reply("250 queued")
+					case strings.HasPrefix(cmd, "QUIT"):
+						reply("221 bye")
+						return
+					default:
+						reply("250 ok")
+					}
+				}
+			}(conn)
+		}
+	}()
+	return ln.Addr().String(), mails
+}
+
+const testConfig = `
+smtp:
+  host: 127.0.0.1
+  port: %PORT%
+  from: ci@example.com
+channels:
+  - name: ops
+    type: slack
+    url: %URL%/slack
+  - name: release
+    type: email
+    to: [release@example.com]
+silences:
+  - name: freeze
+    rules: [deploy-without-igrm]
+    start: 2025-03-04T00:00:00Z
+    end: 2025-03-05T00:00:00Z
+rules:
+  - name: prod-deploy-failed
+    type: build_failed
+    env: PROD_AND_DR
+    deploys_only: true
+    severity: critical
+    channels: [ops, release]
+  - name: pipeline-failing
+    type: consecutive_failures
+    count: 3
+    channels: [ops]
+  - name: payments-success-rate
+    type: success_rate
+    folder: PROD_AND_DR/payments
+    threshold: 80
+    channels: [ops]
+  - name: deploy-without-igrm
+    type: missing_igrm
+    channels: [ops]
+`
+
+func TestEngine(t *testing.T) {
+	var posted []map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg map[string]string
+		json.NewDecoder(r.Body).Decode(&msg)
+		posted = append(posted, msg)
+	}))
+	defer srv.Close()
+	addr, mails := smtpSink(t)
+	_, port, _ := net.SplitHostPort(addr)
+
+	doc := strings.NewReplacer("%URL%", srv.URL, "%PORT%", port).Replace(testConfig)
+	cfg, err := ParseConfig([]byte(doc))
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+	now := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+	e := NewEngine(cfg)
+	e.Now = func() time.Time { return now }
+
+	deploy := models.Build{ID: 1, Controller: "prod", ProjectPath: "PROD_AND_DR/payments/deploy", BuildNumber: 7,
+		Env: "PROD_AND_DR", DeployEnv: "prod", Status: "FAILURE", Timestamp: now.Add(-10 * time.Minute)}
+	s := &fakeStore{
+		pending: []models.Build{
+			deploy,
+			{ID: 2, ProjectPath: "DEV/api/build", Status: "SUCCESS", Timestamp: now.Add(-48 * time.Hour)},
+		},
+		statuses: map[string][]string{deploy.ProjectPath: {"FAILURE", "FAILURE", "FAILURE", "SUCCESS"}},
+		total:    10,
+		success:  9,
+	}
+
+	n, err := e.evaluatePending(s, 100)
+	if err != nil {
+		t.Fatalf("evaluatePending failed: %v", err)
+	}
+	// build_failed, consecutive_failures and missing_igrm; success rate is 90%
+	if n != 3 || s.checked != 2 {
+		t.Fatalf("fired %d alerts, checked %d builds: %+v", n, s.checked, s.alerts)
+	}
+	for _, a := range s.alerts {
+		if a.State != models.AlertSent {
+			t.Errorf("alert %s: state %s, error %s", a.Rule, a.State, a.Error)
+		}
+	}
+	if len(posted) != 3 || !strings.Contains(posted[0]["text"], "[CRITICAL] PROD_AND_DR/payments/deploy #7 FAILURE") {
+		t.Errorf("unexpected slack messages: %v", posted)
+	}
+	select {
+	case mail := <-mails:
+		if !strings.Contains(mail, "Subject: [CRITICAL] PROD_AND_DR/payments/deploy #7 FAILURE") {
+			t.Errorf("unexpected mail:\n%s", mail)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail reached the SMTP sink")
+	}
+
+	// the same build seen again is deduplicated; the success rate drops to 70%
+	s.pending = []models.Build{deploy}
+	s.success = 7
+	if n, err = e.evaluatePending(s, 100); err != nil || n != 1 {
+		t.Fatalf("second run fired %d alerts (err %v)", n, err)
+	}
+	if a := s.alerts[len(s.alerts)-1]; a.Rule != "payments-success-rate" || a.DedupKey != "payments-success-rate|folder|PROD_AND_DR/payments|2025-03-03" {
+		t.Errorf("unexpected success rate alert: %+v", a)
+	}
+
+	// silenced alerts are recorded but not delivered
+	now = time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC)
+	delivered := len(posted)
+	s.pending = []models.Build{{ID: 3, ProjectPath: "PROD_AND_DR/cards/deploy", DeployEnv: "prod", Status: "SUCCESS", Timestamp: now}}
+	if n, err = e.evaluatePending(s, 100); err != nil || n != 1 {
+		t.Fatalf("third run fired %d alerts (err %v)", n, err)
+	}
+	if a := s.alerts[len(s.alerts)-1]; a.State != models.AlertSilenced || len(posted) != delivered {
+		t.Errorf("missing IGRM alert should be silenced: %+v", a)
+	}
+
+	// an unreachable channel marks the alert failed
+	srv.Close()
+	s.pending = []models.Build{{ID: 4, ProjectPath: "QA/web/deploy", DeployEnv: "qa", Status: "SUCCESS", Timestamp: now.Add(48 * time.Hour)}}
+	now = now.Add(48 * time.Hour)
+	if _, err = e.evaluatePending(s, 100); err != nil {
+		t.Fatal(err)
+	}
+	if a := s.alerts[len(s.alerts)-1]; a.State != models.AlertFailed || !strings.HasPrefix(a.Error, "ops: ") {
+		t.Errorf("expected a failed delivery: %+v", a)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+timezone: Europe/Berlin
+channels: [{name: hook, type: webhook, url: http://localhost:9/x}]
+silences:
+  - name: night
+    days: [sat, sun]
+    from: "22:00"
+    to: "06:00"
+rules:
+  - {name: r, type: build_failed, channels: [hook]}
+`))
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+	if r := cfg.Rules[0]; r.Statuses[0] != "FAILURE" || r.Dedup != time.Hour || r.Severity != "warning" {
+		t.Errorf("unexpected defaults: %+v", r)
+	}
+	// 2025-03-08 is a Saturday; 21:30 UTC is 22:30 in Berlin
+	if !cfg.Silenced("r", time.Date(2025, 3, 8, 21, 30, 0, 0, time.UTC)) {
+		t.Error("saturday night should be silenced")
+	}
+	if cfg.Silenced("r", time.Date(2025, 3, 7, 21, 30, 0, 0, time.UTC)) {
+		t.Error("friday night should not be silenced")
+	}
+
+	for _, bad := range []string{
+		`rules: [{name: r, type: build_failed, channels: [nope]}]`,
+		`channels: [{name: c, type: slack}]`,
+		`channels: [{name: c, type: email, to: [a@b]}]`,
+		"channels: [{name: c, type: webhook, url: x}]\nrules: [{name: r, type: success_rate, channels: [c]}]",
+		"channels: [{name: c, type: webhook, url: x}]\nrules: [{name: r, type: sometimes, channels: [c]}]",
+		`silences: [{name: s}]`,
+	} {
+		if _, err := ParseConfig([]byte(bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestEmailSubjectEncoded(t *testing.T) {
+	n := &emailNotifier{smtp: mailer.Config{From: "ci@example.com"}, to: []string{"ops@example.com"}}
+	title := "Déploiement échoué: PROD/zahlung/übersicht #12\r\nBcc: x@example.com"
+	m, err := mail.ReadMessage(bytes.NewReader(n.message(&models.Alert{Severity: "critical", Title: title, Message: "failed"})))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	if m.Header.Get("Bcc") != "" {
+		t.Error("title injected a header")
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil || subject != "[CRITICAL] "+title {
+		t.Errorf("subject = %q, %v", subject, err)
+	}
+}
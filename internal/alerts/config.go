@@ -0,0 +1,237 @@
+// Package alerts evaluates alert rules against builds as they are ingested and
+// delivers the alerts to chat webhooks and email.
+package alerts
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/compliance"
+	"github.com/gauravkr19/jenkins-analytics/internal/mailer"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule types.
+const (
+	BuildFailed         = "build_failed"         // a matching build finished with one of statuses
+	ConsecutiveFailures = "consecutive_failures" // a job failed count times in a row
+	SuccessRate         = "success_rate"         // a folder's success rate today dropped below threshold
+	MissingIGRM         = "missing_igrm"         // a deployment carried no IGRM number
+)
+
+// Channel is a delivery target: a Slack or Teams incoming webhook, a generic
+// JSON webhook or a list of email recipients.
+type Channel struct {
+	Name    string            `yaml:"name"`
+	Type    string            `yaml:"type"` // slack, teams, webhook or email
+	URL     string            `yaml:"url"`
+	URLEnv  string            `yaml:"url_env"` // read the URL from this env var instead
+	Headers map[string]string `yaml:"headers"` // extra headers of generic webhooks
+	To      []string          `yaml:"to"`      // email recipients
+}
+
+// Silence suppresses delivery of some or all rules, either in a recurring
+// window (days, from, to in the config's timezone) or between start and end.
+// Silenced alerts are still recorded.
+type Silence struct {
+	compliance.Window `yaml:",inline"`
+	Rules             []string  `yaml:"rules"` // empty silences every rule
+	Start             time.Time `yaml:"start"`
+	End               time.Time `yaml:"end"`
+}
+
+// Rule is one alert condition with the filters that select its builds.
+type Rule struct {
+	Name         string   `yaml:"name"`
+	Type         string   `yaml:"type"`
+	Severity     string   `yaml:"severity"`     // default warning
+	Env          string   `yaml:"env"`          // canonical env, e.g. PROD_AND_DR
+	Folder       string   `yaml:"folder"`       // project path prefix, required by success_rate
+	Project      string   `yaml:"project"`      // regex on the project path
+	DeploysOnly  bool     `yaml:"deploys_only"` // only builds with a deploy_env parameter
+	Statuses     []string `yaml:"statuses"`     // build_failed, default FAILURE
+	Count        int      `yaml:"count"`        // consecutive_failures, default 3
+	Threshold    float64  `yaml:"threshold"`    // success_rate in percent, default 80
+	MinBuilds    int      `yaml:"min_builds"`   // success_rate, default 5
+	Channels     []string `yaml:"channels"`
+	DedupMinutes int      `yaml:"dedup_minutes"` // same alert is not repeated within, default 60
+
+	project *regexp.Regexp
+	Dedup   time.Duration `yaml:"-"`
+}
+
+// Config is a parsed alert rules document.
+type Config struct {
+	Timezone    string        `yaml:"timezone"`      // zone of silences and "today"
+	MaxAgeHours int           `yaml:"max_age_hours"` // older builds are not alerted on, default 24
+	SMTP        mailer.Config `yaml:"smtp"`
+	Channels    []Channel     `yaml:"channels"`
+	Silences    []Silence     `yaml:"silences"`
+	Rules       []Rule        `yaml:"rules"`
+
+	Location *time.Location `yaml:"-"`
+	MaxAge   time.Duration  `yaml:"-"`
+}
+
+// ParseConfig decodes and validates an alert rules document.
+func ParseConfig(data []byte) (*Config, error) {
+	cfg := &Config{Timezone: "UTC", MaxAgeHours: 24}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid alert rules file: %w", err)
+	}
+	var err error
+	if cfg.Location, err = time.LoadLocation(cfg.Timezone); err != nil {
+		return nil, fmt.Errorf("alert rules timezone: %w", err)
+	}
+	cfg.MaxAge = time.Duration(cfg.MaxAgeHours) * time.Hour
+	cfg.SMTP.Resolve()
+
+	channels := make(map[string]bool)
+	for i := range cfg.Channels {
+		ch := &cfg.Channels[i]
+		if ch.Name == "" {
+			return nil, fmt.Errorf("alert channel %d: name is required", i+1)
+		}
+		if channels[ch.Name] {
+			return nil, fmt.Errorf("alert channel %q is listed twice", ch.Name)
+		}
+		channels[ch.Name] = true
+		if ch.URLEnv != "" {
+			ch.URL = os.Getenv(ch.URLEnv)
+		}
+		switch ch.Type {
+		case "slack", "teams", "webhook":
+			if ch.URL == "" {
+				return nil, fmt.Errorf("alert channel %q: url (or url_env) is required", ch.Name)
+			}
+		case "email":
+			if len(ch.To) == 0 {
+				return nil, fmt.Errorf("alert channel %q: to is required", ch.Name)
+			}
+			if cfg.SMTP.Host == "" || cfg.SMTP.From == "" {
+				return nil, fmt.Errorf("alert channel %q: smtp host and from are required", ch.Name)
+			}
+		default:
+			return nil, fmt.Errorf("alert channel %q: unknown type %q", ch.Name, ch.Type)
+		}
+	}
+
+	for i := range cfg.Silences {
+		s := &cfg.Silences[i]
+		if s.Name == "" {
+			s.Name = fmt.Sprintf("silence-%d", i+1)
+		}
+		switch {
+		case s.From != "" || s.To != "":
+			if err := s.Parse(); err != nil {
+				return nil, fmt.Errorf("alert silence: %w", err)
+			}
+		case s.Start.IsZero() || !s.End.After(s.Start):
+			return nil, fmt.Errorf("alert silence %s: from/to or a start before end is required", s.Name)
+		}
+	}
+
+	names := make(map[string]bool)
+	for i := range cfg.Rules {
+		r := &cfg.Rules[i]
+		if r.Name == "" {
+			return nil, fmt.Errorf("alert rule %d: name is required", i+1)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("alert rule %q is listed twice", r.Name)
+		}
+		names[r.Name] = true
+		if err := r.validate(channels); err != nil {
+			return nil, fmt.Errorf("alert rule %q: %w", r.Name, err)
+		}
+	}
+	return cfg, nil
+}
+
+// LoadConfig reads an alert rules document from disk.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read alert rules file: %w", err)
+	}
+	return ParseConfig(data)
+}
+
+func (r *Rule) validate(channels map[string]bool) error {
+	switch r.Type {
+	case BuildFailed:
+		if len(r.Statuses) == 0 {
+			r.Statuses = []string{"FAILURE"}
+		}
+		for i, s := range r.Statuses {
+			r.Statuses[i] = strings.ToUpper(s)
+		}
+	case ConsecutiveFailures:
+		if r.Count == 0 {
+			r.Count = 3
+		}
+		if r.Count < 2 {
+			return fmt.Errorf("count must be at least 2")
+		}
+	case SuccessRate:
+		if r.Folder == "" {
+			return fmt.Errorf("folder is required")
+		}
+		if r.Threshold == 0 {
+			r.Threshold = 80
+		}
+		if r.MinBuilds == 0 {
+			r.MinBuilds = 5
+		}
+	case MissingIGRM:
+	default:
+		return fmt.Errorf("unknown type %q", r.Type)
+	}
+
+	if r.Project != "" {
+		var err error
+		if r.project, err = regexp.Compile(r.Project); err != nil {
+			return fmt.Errorf("project: %w", err)
+		}
+	}
+	r.Folder = strings.Trim(r.Folder, "/")
+	if r.Severity == "" {
+		r.Severity = "warning"
+	}
+	if r.DedupMinutes == 0 {
+		r.DedupMinutes = 60
+	}
+	r.Dedup = time.Duration(r.DedupMinutes) * time.Minute
+
+	if len(r.Channels) == 0 {
+		return fmt.Errorf("channels are required")
+	}
+	for _, ch := range r.Channels {
+		if !channels[ch] {
+			return fmt.Errorf("unknown channel %q", ch)
+		}
+	}
+	return nil
+}
+
+// Silenced reports whether delivery of the rule is suppressed at t.
+func (cfg *Config) Silenced(rule string, t time.Time) bool {
+	local := t.In(cfg.Location)
+	for _, s := range cfg.Silences {
+		if len(s.Rules) > 0 && !slices.Contains(s.Rules, rule) {
+			continue
+		}
+		if s.From != "" {
+			if s.Contains(local) {
+				return true
+			}
+		} else if !t.Before(s.Start) && t.Before(s.End) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package alerts
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// store is the part of *db.DB the engine reads and writes.
+type store interface {
+	GetBuildsPendingAlerts(limit int) ([]models.Build, error)
+	MarkAlertsChecked(builds []models.Build) error
+	RecentStatuses(controller, projectPath string, before time.Time, limit int) ([]string, error)
+	FolderOutcomes(folder string, from, to time.Time) (total, succeeded int, err error)
+	LastAlertAt(dedupKey string) (*time.Time, error)
+	InsertAlert(a *models.Alert) error
+}
+
+var _ store = (*db.DB)(nil)
+
+// Engine evaluates the rules of a Config and delivers what they fire.
+type Engine struct {
+	Config    *Config
+	Notifiers map[string]Notifier // by channel name
+	Now       func() time.Time
+}
+
+// NewEngine creates the notifiers of every channel in cfg.
+func NewEngine(cfg *Config) *Engine {
+	e := &Engine{Config: cfg, Notifiers: make(map[string]Notifier), Now: time.Now}
+	for _, ch := range cfg.Channels {
+		e.Notifiers[ch.Name] = NewNotifier(ch, cfg.SMTP)
+	}
+	return e
+}
+
+// EvaluatePending runs the rules over up to batch newly finished builds and
+// returns the number of alerts fired.
+func (e *Engine) EvaluatePending(database *db.DB, batch int) (int, error) {
+	return e.evaluatePending(database, batch)
+}
+
+func (e *Engine) evaluatePending(s store, batch int) (int, error) {
+	builds, err := s.GetBuildsPendingAlerts(batch)
+	if err != nil {
+		return 0, err
+	}
+
+	fired := 0
+	now := e.Now()
+	for i := range builds {
+		b := &builds[i]
+		// a backfill or an outage catching up is not news any more
+		if now.Sub(b.Timestamp) > e.Config.MaxAge {
+			continue
+		}
+		for j := range e.Config.Rules {
+			r := &e.Config.Rules[j]
+			a, err := r.evaluate(s, b, e.Config.Location)
+			if err != nil {
+				// builds stay pending; alerts fired so far are deduplicated on the retry
+				return fired, fmt.Errorf("rule %s on build ID %d: %w", r.Name, b.ID, err)
+			}
+			if a == nil {
+				continue
+			}
+			ok, err := e.fire(s, r, a)
+			if err != nil {
+				return fired, err
+			}
+			if ok {
+				fired++
+			}
+		}
+	}
+	return fired, s.MarkAlertsChecked(builds)
+}
+
+// fire records and delivers an alert unless the same alert was sent within
+// the rule's dedup window.
+func (e *Engine) fire(s store, r *Rule, a *models.Alert) (bool, error) {
+	now := e.Now()
+	last, err := s.LastAlertAt(a.DedupKey)
+	if err != nil {
+		return false, err
+	}
+	if last != nil && now.Sub(*last) < r.Dedup {
+		return false, nil
+	}
+
+	a.FiredAt = now
+	a.Channels = r.Channels
+	if e.Config.Silenced(r.Name, now) {
+		a.State = models.AlertSilenced
+	} else {
+		a.State = models.AlertSent
+		var errs []string
+		for _, name := range r.Channels {
+			if err := e.Notifiers[name].Notify(a); err != nil {
+				log.Printf("[Alerts] %s: delivery to %s failed: %v", r.Name, name, err)
+				errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			}
+		}
+		if len(errs) > 0 {
+			a.State = models.AlertFailed
+			a.Error = strings.Join(errs, "; ")
+		}
+	}
+	if err := s.InsertAlert(a); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Send delivers a test alert to one channel, e.g. to check a webhook URL.
+func (e *Engine) Send(channel string, a *models.Alert) error {
+	n, ok := e.Notifiers[channel]
+	if !ok {
+		return fmt.Errorf("unknown channel %q", channel)
+	}
+	return n.Notify(a)
+}
+
+// matches applies the rule's env, folder, project and deploy filters.
+func (r *Rule) matches(b *models.Build) bool {
+	if r.Env != "" && !strings.EqualFold(b.Env, r.Env) {
+		return false
+	}
+	if r.Folder != "" && b.ProjectPath != r.Folder && !strings.HasPrefix(b.ProjectPath, r.Folder+"/") {
+		return false
+	}
+	if r.project != nil && !r.project.MatchString(b.ProjectPath) {
+		return false
+	}
+	if r.DeploysOnly && b.DeployEnv == "" {
+		return false
+	}
+	return true
+}
+
+// evaluate returns the alert b fires under the rule, or nil.
+func (r *Rule) evaluate(s store, b *models.Build, loc *time.Location) (*models.Alert, error) {
+	if !r.matches(b) {
+		return nil, nil
+	}
+	status := strings.ToUpper(b.Status)
+	a := &models.Alert{
+		Rule:        r.Name,
+		Severity:    r.Severity,
+		BuildID:     b.ID,
+		Controller:  b.Controller,
+		ProjectPath: b.ProjectPath,
+		URL:         b.JobURL,
+	}
+
+	switch r.Type {
+	case BuildFailed:
+		if !slices.Contains(r.Statuses, status) {
+			return nil, nil
+		}
+		a.DedupKey = fmt.Sprintf("%s|build|%d", r.Name, b.ID)
+		a.Title = fmt.Sprintf("%s #%d %s", b.ProjectPath, b.BuildNumber, status)
+		a.Message = fmt.Sprintf("%s #%d on %s finished %s after %s.", b.ProjectPath, b.BuildNumber, b.Controller, status, b.FormattedDuration())
+		if b.DeployEnv != "" {
+			a.Message += fmt.Sprintf(" Deploy to %s (IGRM %s) by %s.", b.DeployEnv, orDash(b.IGRMNo), orDash(b.UserID))
+		}
+		if b.FailureCategory != "" {
+			a.Message += " Failure cause: " + b.FailureCategory + "."
+		}
+
+	case ConsecutiveFailures:
+		if status != "FAILURE" {
+			return nil, nil
+		}
+		statuses, err := s.RecentStatuses(b.Controller, b.ProjectPath, b.Timestamp, r.Count+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(statuses) < r.Count {
+			return nil, nil
+		}
+		for _, st := range statuses[:r.Count] {
+			if !strings.EqualFold(st, "FAILURE") {
+				return nil, nil
+			}
+		}
+		// fire once, when the streak reaches count
+		if len(statuses) > r.Count && strings.EqualFold(statuses[r.Count], "FAILURE") {
+			return nil, nil
+		}
+		a.DedupKey = fmt.Sprintf("%s|job|%s|%s", r.Name, b.Controller, b.ProjectPath)
+		a.Title = fmt.Sprintf("%s failed %d times in a row", b.ProjectPath, r.Count)
+		a.Message = fmt.Sprintf("The last %d builds of %s on %s failed, latest #%d.", r.Count, b.ProjectPath, b.Controller, b.BuildNumber)
+
+	case SuccessRate:
+		local := b.Timestamp.In(loc)
+		day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+		total, succeeded, err := s.FolderOutcomes(r.Folder, day, day.AddDate(0, 0, 1))
+		if err != nil {
+			return nil, err
+		}
+		if total < r.MinBuilds {
+			return nil, nil
+		}
+		rate := float64(succeeded) / float64(total) * 100
+		if rate >= r.Threshold {
+			return nil, nil
+		}
+		a.BuildID, a.ProjectPath, a.URL = 0, r.Folder, ""
+		a.DedupKey = fmt.Sprintf("%s|folder|%s|%s", r.Name, r.Folder, day.Format("2006-01-02"))
+		a.Title = fmt.Sprintf("%s success rate %.0f%% on %s", r.Folder, rate, day.Format("Jan 02"))
+		a.Message = fmt.Sprintf("%d of %d builds under %s succeeded on %s, below %.0f%%.", succeeded, total, r.Folder, day.Format("2006-01-02"), r.Threshold)
+
+	case MissingIGRM:
+		if b.DeployEnv == "" || strings.TrimSpace(b.IGRMNo) != "" {
+			return nil, nil
+		}
+		a.DedupKey = fmt.Sprintf("%s|build|%d", r.Name, b.ID)
+		a.Title = fmt.Sprintf("%s #%d deployed without IGRM", b.ProjectPath, b.BuildNumber)
+		a.Message = fmt.Sprintf("%s #%d deployed to %s (%s) by %s without an IGRM number.", b.ProjectPath, b.BuildNumber, b.DeployEnv, status, orDash(b.UserID))
+	}
+	return a, nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "–"
+	}
+	return s
+}
@@ -0,0 +1,122 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/mailer"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// Notifier delivers an alert to one channel.
+type Notifier interface {
+	Notify(a *models.Alert) error
+}
+
+// NewNotifier returns the notifier of a validated channel.
+func NewNotifier(ch Channel, cfg mailer.Config) Notifier {
+	client := &http.Client{Timeout: 10 * time.Second}
+	switch ch.Type {
+	case "slack":
+		return &webhookNotifier{url: ch.URL, client: client, payload: slackPayload}
+	case "teams":
+		return &webhookNotifier{url: ch.URL, client: client, payload: teamsPayload}
+	case "email":
+		return &emailNotifier{smtp: cfg, to: ch.To}
+	default:
+		return &webhookNotifier{url: ch.URL, headers: ch.Headers, client: client, payload: func(a *models.Alert) interface{} { return a }}
+	}
+}
+
+// webhookNotifier POSTs a JSON payload built from the alert.
+type webhookNotifier struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	payload func(a *models.Alert) interface{}
+}
+
+func (n *webhookNotifier) Notify(a *models.Alert) error {
+	body, err := json.Marshal(n.payload(a))
+	if err != nil {
+		return fmt.Errorf("encode alert: %w", err)
+	}
+	req, err := http.NewRequest("POST", n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// slackPayload is a Slack incoming-webhook message in mrkdwn.
+func slackPayload(a *models.Alert) interface{} {
+	text := fmt.Sprintf("*[%s] %s*\n%s", strings.ToUpper(a.Severity), a.Title, a.Message)
+	if a.URL != "" {
+		text += fmt.Sprintf("\n<%s|Open in Jenkins>", a.URL)
+	}
+	return map[string]string{"text": text}
+}
+
+// teamsPayload is a Microsoft Teams incoming-webhook MessageCard.
+func teamsPayload(a *models.Alert) interface{} {
+	color := "FFA500"
+	if a.Severity == "critical" {
+		color = "D70000"
+	}
+	card := map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    a.Title,
+		"themeColor": color,
+		"title":      fmt.Sprintf("[%s] %s", strings.ToUpper(a.Severity), a.Title),
+		"text":       a.Message,
+	}
+	if a.URL != "" {
+		card["potentialAction"] = []map[string]interface{}{{
+			"@type":   "OpenUri",
+			"name":    "Open in Jenkins",
+			"targets": []map[string]string{{"os": "default", "uri": a.URL}},
+		}}
+	}
+	return card
+}
+
+// emailNotifier sends a plain-text mail through the configured SMTP server.
+type emailNotifier struct {
+	smtp mailer.Config
+	to   []string
+}
+
+func (n *emailNotifier) Notify(a *models.Alert) error {
+	return n.smtp.Send(n.to, n.message(a))
+}
+
+// message is the plain-text mail of an alert.
+func (n *emailNotifier) message(a *models.Alert) []byte {
+	var msg strings.Builder
+	n.smtp.WriteHeader(&msg, n.to, fmt.Sprintf("[%s] %s", strings.ToUpper(a.Severity), a.Title))
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(a.Message + "\r\n")
+	if a.URL != "" {
+		msg.WriteString("\r\n" + a.URL + "\r\n")
+	}
+	fmt.Fprintf(&msg, "\r\nRule: %s\r\n", a.Rule)
+	return []byte(msg.String())
+}
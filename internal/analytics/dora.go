@@ -0,0 +1,218 @@
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// Deployment is a build that targeted a deploy environment (deploy_env is set).
+type Deployment struct {
+	ProjectPath string
+	Env         string // folder env: DEV / NON_PROD / PROD_AND_DR
+	DeployEnv   string
+	CommitSHA   string
+	Status      string
+	Timestamp   time.Time
+}
+
+// DORAMetrics holds the four DORA key metrics for one group of deployments.
+type DORAMetrics struct {
+	Deployments           int     `json:"deployments"`
+	SuccessfulDeployments int     `json:"successful_deployments"`
+	FailedDeployments     int     `json:"failed_deployments"`
+	DeploymentsPerDay     float64 `json:"deployment_frequency_per_day"`
+	LeadTimeHours         float64 `json:"lead_time_hours_median"`
+	ChangeFailureRate     float64 `json:"change_failure_rate"`
+	TimeToRestoreHours    float64 `json:"time_to_restore_hours_median"`
+	Restores              int     `json:"restores"`
+}
+
+// DORAGroup is a DORAMetrics rollup for one env, folder or project path.
+type DORAGroup struct {
+	Key string `json:"key"`
+	DORAMetrics
+}
+
+// DORAReport is the full DORA breakdown for a date range.
+type DORAReport struct {
+	From      time.Time   `json:"from"`
+	To        time.Time   `json:"to"`
+	Overall   DORAMetrics `json:"overall"`
+	ByEnv     []DORAGroup `json:"by_env"`
+	ByFolder  []DORAGroup `json:"by_folder"`
+	ByProject []DORAGroup `json:"by_project"`
+}
+
+// LoadDORA reads deployments in [from, to] and computes the DORA report.
+func LoadDORA(database *db.DB, from, to time.Time) (*DORAReport, error) {
+	builds, err := database.GetDeployments(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("load deployments: %w", err)
+	}
+
+	deps := make([]Deployment, 0, len(builds))
+	shaSet := make(map[string]struct{})
+	for _, b := range builds {
+		deps = append(deps, deploymentFromBuild(b))
+		if b.CommitSHA != "" {
+			shaSet[b.CommitSHA] = struct{}{}
+		}
+	}
+
+	shas := make([]string, 0, len(shaSet))
+	for sha := range shaSet {
+		shas = append(shas, sha)
+	}
+	firstSeen, err := database.GetCommitFirstSeen(shas)
+	if err != nil {
+		return nil, fmt.Errorf("load commit first-seen times: %w", err)
+	}
+
+	report := ComputeDORA(deps, firstSeen, from, to)
+	return &report, nil
+}
+
+func deploymentFromBuild(b models.Build) Deployment {
+	return Deployment{
+		ProjectPath: b.ProjectPath,
+		Env:         b.Env,
+		DeployEnv:   b.DeployEnv,
+		CommitSHA:   b.CommitSHA,
+		Status:      strings.ToUpper(b.Status),
+		Timestamp:   b.Timestamp,
+	}
+}
+
+// ComputeDORA computes overall, per-env, per-folder and per-project metrics.
+// firstSeen maps a commit SHA to the earliest build that contained it and
+// is used as the start of the lead time for changes.
+func ComputeDORA(deps []Deployment, firstSeen map[string]time.Time, from, to time.Time) DORAReport {
+	days := to.Sub(from).Hours() / 24
+	if days < 1 {
+		days = 1
+	}
+
+	byEnv := make(map[string][]Deployment)
+	byFolder := make(map[string][]Deployment)
+	byProject := make(map[string][]Deployment)
+	for _, d := range deps {
+		byEnv[d.Env] = append(byEnv[d.Env], d)
+		byFolder[folderOf(d.ProjectPath)] = append(byFolder[folderOf(d.ProjectPath)], d)
+		byProject[d.ProjectPath] = append(byProject[d.ProjectPath], d)
+	}
+
+	return DORAReport{
+		From:      from,
+		To:        to,
+		Overall:   computeMetrics(deps, firstSeen, days),
+		ByEnv:     computeGroups(byEnv, firstSeen, days),
+		ByFolder:  computeGroups(byFolder, firstSeen, days),
+		ByProject: computeGroups(byProject, firstSeen, days),
+	}
+}
+
+func computeGroups(groups map[string][]Deployment, firstSeen map[string]time.Time, days float64) []DORAGroup {
+	out := make([]DORAGroup, 0, len(groups))
+	for key, deps := range groups {
+		out = append(out, DORAGroup{Key: key, DORAMetrics: computeMetrics(deps, firstSeen, days)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+func computeMetrics(deps []Deployment, firstSeen map[string]time.Time, days float64) DORAMetrics {
+	var m DORAMetrics
+	var leadTimes []float64
+
+	for _, d := range deps {
+		switch {
+		case d.Status == "SUCCESS":
+			m.SuccessfulDeployments++
+			if start, ok := firstSeen[d.CommitSHA]; ok && d.CommitSHA != "" {
+				lead := d.Timestamp.Sub(start).Hours()
+				if lead < 0 {
+					lead = 0
+				}
+				leadTimes = append(leadTimes, lead)
+			}
+		case isFailure(d.Status):
+			m.FailedDeployments++
+		default:
+			continue // running or aborted deployments are not counted
+		}
+		m.Deployments++
+	}
+
+	m.DeploymentsPerDay = float64(m.SuccessfulDeployments) / days
+	if m.Deployments > 0 {
+		m.ChangeFailureRate = float64(m.FailedDeployments) / float64(m.Deployments)
+	}
+	m.LeadTimeHours = median(leadTimes)
+
+	restores := timesToRestore(deps)
+	m.Restores = len(restores)
+	m.TimeToRestoreHours = median(restores)
+
+	return m
+}
+
+// timesToRestore walks each project/deploy_env stream in time order and
+// measures the hours from the first failed deployment to the next success.
+func timesToRestore(deps []Deployment) []float64 {
+	streams := make(map[string][]Deployment)
+	for _, d := range deps {
+		key := d.ProjectPath + "|" + d.DeployEnv
+		streams[key] = append(streams[key], d)
+	}
+
+	var out []float64
+	for _, stream := range streams {
+		sort.Slice(stream, func(i, j int) bool { return stream[i].Timestamp.Before(stream[j].Timestamp) })
+
+		var failedAt time.Time
+		for _, d := range stream {
+			switch {
+			case isFailure(d.Status):
+				if failedAt.IsZero() {
+					failedAt = d.Timestamp
+				}
+			case d.Status == "SUCCESS":
+				if !failedAt.IsZero() {
+					out = append(out, d.Timestamp.Sub(failedAt).Hours())
+					failedAt = time.Time{}
+				}
+			}
+		}
+	}
+	return out
+}
+
+func isFailure(status string) bool {
+	return status == "FAILURE" || status == "UNSTABLE"
+}
+
+// folderOf returns the parent folder of a project path ("DEV/app/deploy" -> "DEV/app").
+func folderOf(projectPath string) string {
+	if i := strings.LastIndex(projectPath, "/"); i > 0 {
+		return projectPath[:i]
+	}
+	return projectPath
+}
+
+func median(vals []float64) float64 {
+	if len(vals) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), vals...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
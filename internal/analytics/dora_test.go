@@ -0,0 +1,56 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestComputeDORA(t *testing.T) {
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 10)
+	at := func(h int) time.Time { return from.Add(time.Duration(h) * time.Hour) }
+
+	deps := []Deployment{
+		{ProjectPath: "PROD_AND_DR/app/deploy", Env: "PROD_AND_DR", DeployEnv: "prod", CommitSHA: "a1", Status: "SUCCESS", Timestamp: at(10)},
+		{ProjectPath: "PROD_AND_DR/app/deploy", Env: "PROD_AND_DR", DeployEnv: "prod", CommitSHA: "b2", Status: "FAILURE", Timestamp: at(20)},
+		{ProjectPath: "PROD_AND_DR/app/deploy", Env: "PROD_AND_DR", DeployEnv: "prod", CommitSHA: "b2", Status: "FAILURE", Timestamp: at(22)},
+		{ProjectPath: "PROD_AND_DR/app/deploy", Env: "PROD_AND_DR", DeployEnv: "prod", CommitSHA: "c3", Status: "SUCCESS", Timestamp: at(26)},
+		{ProjectPath: "DEV/app/deploy", Env: "DEV", DeployEnv: "sit", CommitSHA: "c3", Status: "SUCCESS", Timestamp: at(24)},
+		{ProjectPath: "DEV/app/deploy", Env: "DEV", DeployEnv: "sit", Status: "ABORTED", Timestamp: at(25)},
+	}
+	firstSeen := map[string]time.Time{
+		"a1": at(8),
+		"c3": at(23),
+	}
+
+	r := ComputeDORA(deps, firstSeen, from, to)
+
+	if r.Overall.Deployments != 5 || r.Overall.FailedDeployments != 2 {
+		t.Fatalf("unexpected counts: %+v", r.Overall)
+	}
+	if got := r.Overall.ChangeFailureRate; math.Abs(got-0.4) > 1e-9 {
+		t.Errorf("change failure rate = %v, want 0.4", got)
+	}
+	if got := r.Overall.DeploymentsPerDay; math.Abs(got-0.3) > 1e-9 {
+		t.Errorf("deployments per day = %v, want 0.3", got)
+	}
+	// lead times: a1=2h, c3 (prod)=3h, c3 (dev)=1h -> median 2h
+	if got := r.Overall.LeadTimeHours; got != 2 {
+		t.Errorf("lead time = %v, want 2", got)
+	}
+	// restore: first failure at 20h, next success at 26h
+	if r.Overall.Restores != 1 || r.Overall.TimeToRestoreHours != 6 {
+		t.Errorf("time to restore = %v over %d restores, want 6 over 1", r.Overall.TimeToRestoreHours, r.Overall.Restores)
+	}
+
+	if len(r.ByEnv) != 2 || r.ByEnv[0].Key != "DEV" || r.ByEnv[1].Key != "PROD_AND_DR" {
+		t.Fatalf("unexpected env groups: %+v", r.ByEnv)
+	}
+	if r.ByEnv[0].Deployments != 1 || r.ByEnv[1].Deployments != 4 {
+		t.Errorf("unexpected env deployment counts: %+v", r.ByEnv)
+	}
+	if len(r.ByFolder) != 2 || r.ByFolder[0].Key != "DEV/app" {
+		t.Errorf("unexpected folder groups: %+v", r.ByFolder)
+	}
+}
@@ -0,0 +1,164 @@
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// DefaultFlakyWindow is the number of consecutive builds inspected for flips.
+const DefaultFlakyWindow = 5
+
+// FlakyTest is a test case that flipped between pass and fail without a code change
+// or repeatedly within a short run of builds of the same project path.
+type FlakyTest struct {
+	Folder       string    `json:"folder"`
+	ProjectPath  string    `json:"project_path"`
+	ClassName    string    `json:"class_name"`
+	Name         string    `json:"name"`
+	Runs         int       `json:"runs"`
+	Failures     int       `json:"failures"`
+	Flips        int       `json:"flips"`        // pass<->fail changes between consecutive runs
+	CommitFlips  int       `json:"commit_flips"` // commits on which the test both passed and failed
+	WindowFlips  int       `json:"window_flips"` // most flips within any window of consecutive runs
+	FlipRate     float64   `json:"flip_rate"`    // flips / (runs - 1)
+	LastFailedAt time.Time `json:"last_failed_at"`
+	LastBuildID  int       `json:"last_failed_build_id"`
+}
+
+// FlakyFolder holds the ranked flaky tests of one folder.
+type FlakyFolder struct {
+	Folder string      `json:"folder"`
+	Tests  []FlakyTest `json:"tests"`
+}
+
+// LoadFlakyTests runs the detector over test results of builds in [from, to]
+// below folder (all folders when empty), keeping the top limit tests per folder.
+func LoadFlakyTests(database *db.DB, from, to time.Time, folder string, window, limit int) ([]FlakyFolder, error) {
+	outcomes, err := database.GetTestOutcomes(from, to, folder)
+	if err != nil {
+		return nil, fmt.Errorf("load test outcomes: %w", err)
+	}
+	return GroupFlakyByFolder(DetectFlaky(outcomes, window), limit), nil
+}
+
+// DetectFlaky flags tests that passed and failed on the same commit, or flipped
+// at least twice within window consecutive runs of a project path. outcomes must
+// be ordered by project path, test and time, as returned by GetTestOutcomes.
+// The result is ranked flakiest first.
+func DetectFlaky(outcomes []models.TestOutcome, window int) []FlakyTest {
+	if window < 3 {
+		window = 3
+	}
+
+	var flaky []FlakyTest
+	for start := 0; start < len(outcomes); {
+		end := start + 1
+		for end < len(outcomes) && sameTest(outcomes[start], outcomes[end]) {
+			end++
+		}
+		if t, ok := scoreTest(outcomes[start:end], window); ok {
+			flaky = append(flaky, t)
+		}
+		start = end
+	}
+
+	sort.SliceStable(flaky, func(i, j int) bool {
+		a, b := flaky[i], flaky[j]
+		if a.Flips != b.Flips {
+			return a.Flips > b.Flips
+		}
+		if a.CommitFlips != b.CommitFlips {
+			return a.CommitFlips > b.CommitFlips
+		}
+		return a.FlipRate > b.FlipRate
+	})
+	return flaky
+}
+
+func sameTest(a, b models.TestOutcome) bool {
+	return a.ProjectPath == b.ProjectPath && a.ClassName == b.ClassName && a.Name == b.Name
+}
+
+// scoreTest computes flip statistics for the time-ordered runs of one test.
+func scoreTest(runs []models.TestOutcome, window int) (FlakyTest, bool) {
+	first := runs[0]
+	t := FlakyTest{
+		Folder:      folderOf(first.ProjectPath),
+		ProjectPath: first.ProjectPath,
+		ClassName:   first.ClassName,
+		Name:        first.Name,
+		Runs:        len(runs),
+	}
+
+	// flipAt[i] is 1 when run i changed outcome compared to run i-1
+	flipAt := make([]int, len(runs))
+	byCommit := make(map[string][2]bool) // sha -> {passed, failed}
+	for i, r := range runs {
+		failed := r.Status == "FAILED"
+		if failed {
+			t.Failures++
+			t.LastFailedAt = r.Timestamp
+			t.LastBuildID = r.BuildID
+		}
+		if i > 0 && r.Status != runs[i-1].Status {
+			flipAt[i] = 1
+			t.Flips++
+		}
+		if r.CommitSHA != "" {
+			seen := byCommit[r.CommitSHA]
+			if failed {
+				seen[1] = true
+			} else {
+				seen[0] = true
+			}
+			byCommit[r.CommitSHA] = seen
+		}
+	}
+	for _, seen := range byCommit {
+		if seen[0] && seen[1] {
+			t.CommitFlips++
+		}
+	}
+
+	// a window of n runs spans n-1 transitions
+	span := window - 1
+	sum := 0
+	for i := 1; i < len(flipAt); i++ {
+		sum += flipAt[i]
+		if i > span {
+			sum -= flipAt[i-span]
+		}
+		if sum > t.WindowFlips {
+			t.WindowFlips = sum
+		}
+	}
+
+	if len(runs) > 1 {
+		t.FlipRate = float64(t.Flips) / float64(len(runs)-1)
+	}
+	return t, t.CommitFlips > 0 || t.WindowFlips >= 2
+}
+
+// GroupFlakyByFolder splits a ranked list by folder, keeping at most limit tests
+// per folder (all when limit <= 0). Folders are sorted by name.
+func GroupFlakyByFolder(flaky []FlakyTest, limit int) []FlakyFolder {
+	idx := make(map[string]int)
+	var out []FlakyFolder
+	for _, t := range flaky {
+		i, ok := idx[t.Folder]
+		if !ok {
+			i = len(out)
+			idx[t.Folder] = i
+			out = append(out, FlakyFolder{Folder: t.Folder})
+		}
+		if limit <= 0 || len(out[i].Tests) < limit {
+			out[i].Tests = append(out[i].Tests, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Folder < out[j].Folder })
+	return out
+}
@@ -0,0 +1,53 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+func TestDetectFlaky(t *testing.T) {
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	var outcomes []models.TestOutcome
+	add := func(path, name, sha string, statuses ...string) {
+		for i, s := range statuses {
+			outcomes = append(outcomes, models.TestOutcome{
+				BuildID:     len(outcomes) + 1,
+				ProjectPath: path,
+				CommitSHA:   sha,
+				ClassName:   "com.acme.AppTest",
+				Name:        name,
+				Status:      s,
+				Timestamp:   base.Add(time.Duration(i) * time.Hour),
+			})
+		}
+	}
+	// flips P->F->P->F on one commit
+	add("DEV/app/build", "testRetry", "a1", "PASSED", "FAILED", "PASSED", "FAILED")
+	// broke once and stayed broken: a regression, not flaky
+	add("DEV/app/build", "testRegression", "", "PASSED", "PASSED", "FAILED", "FAILED", "FAILED")
+	// failed then passed on the same commit (a rerun fixed it)
+	add("NON_PROD/svc/build", "testRerun", "b2", "FAILED", "PASSED")
+	// two flips but spread over more than the window
+	add("NON_PROD/svc/build", "testSlow", "", "PASSED", "FAILED", "FAILED", "FAILED", "FAILED", "FAILED", "PASSED")
+
+	flaky := DetectFlaky(outcomes, 5)
+	if len(flaky) != 2 {
+		t.Fatalf("got %d flaky tests, want 2: %+v", len(flaky), flaky)
+	}
+	if flaky[0].Name != "testRetry" || flaky[0].Flips != 3 || flaky[0].CommitFlips != 1 || flaky[0].WindowFlips != 3 {
+		t.Errorf("unexpected top flaky test: %+v", flaky[0])
+	}
+	if flaky[1].Name != "testRerun" || flaky[1].CommitFlips != 1 {
+		t.Errorf("unexpected second flaky test: %+v", flaky[1])
+	}
+	if flaky[0].LastBuildID != 4 {
+		t.Errorf("last failed build = %d, want 4", flaky[0].LastBuildID)
+	}
+
+	groups := GroupFlakyByFolder(flaky, 10)
+	if len(groups) != 2 || groups[0].Folder != "DEV/app" || groups[1].Folder != "NON_PROD/svc" {
+		t.Errorf("unexpected folder groups: %+v", groups)
+	}
+}
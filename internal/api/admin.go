@@ -0,0 +1,113 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/backfill"
+	"github.com/gin-gonic/gin"
+)
+
+// GET /admin/job-cursors - sync position, last poll and last error of every job
+func (h *Handler) RenderJobCursors(c *gin.Context) {
+	cursors, err := h.DB.ListJobCursors()
+	if err != nil {
+		log.Printf("ListJobCursors error: %v", err)
+		c.String(http.StatusInternalServerError, "Error: %v", err)
+		return
+	}
+
+	failing := 0
+	for _, cur := range cursors {
+		if cur.LastError != "" {
+			failing++
+		}
+	}
+
+	data := gin.H{
+		"JobCursors": true,
+		"Cursors":    cursors,
+		"Failing":    failing,
+	}
+
+	if c.GetHeader("HX-Request") == "true" {
+		c.HTML(http.StatusOK, "job_cursors", data)
+	} else {
+		c.HTML(http.StatusOK, "base", data)
+	}
+}
+
+// GET /admin/backfills - derived column backfills with their progress
+func (h *Handler) RenderBackfills(c *gin.Context) {
+	h.renderBackfills(c, "")
+}
+
+// POST /admin/backfills/:name?dry_run=&restart=&batch=&pause_ms= - start a backfill in the background
+func (h *Handler) StartBackfill(c *gin.Context) {
+	if h.Backfills == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "backfills are not configured"})
+		return
+	}
+
+	opts := backfill.Options{
+		DryRun:  c.Query("dry_run") == "true",
+		Restart: c.Query("restart") == "true",
+		Pause:   100 * time.Millisecond,
+	}
+	if n, err := strconv.Atoi(c.Query("batch")); err == nil && n > 0 {
+		opts.BatchSize = n
+	}
+	if ms, err := strconv.Atoi(c.Query("pause_ms")); err == nil && ms >= 0 {
+		opts.Pause = time.Duration(ms) * time.Millisecond
+	}
+
+	name := c.Param("name")
+	if err := h.Backfills.Start(name, opts); err != nil {
+		if c.GetHeader("HX-Request") == "true" {
+			h.renderBackfills(c, err.Error())
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if c.GetHeader("HX-Request") == "true" {
+		h.renderBackfills(c, "")
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"status": "started", "task": name})
+}
+
+func (h *Handler) renderBackfills(c *gin.Context, message string) {
+	if h.Backfills == nil {
+		c.String(http.StatusServiceUnavailable, "Backfills are not configured")
+		return
+	}
+	status, err := h.Backfills.Status()
+	if err != nil {
+		log.Printf("Backfill status error: %v", err)
+		c.String(http.StatusInternalServerError, "Error: %v", err)
+		return
+	}
+
+	running := false
+	for _, p := range status {
+		running = running || p.Running
+	}
+
+	data := gin.H{
+		"Backfills": true,
+		"Tasks":     h.Backfills.Tasks(),
+		"Progress":  status,
+		"DryRuns":   h.Backfills.DryRuns(),
+		"Running":   running,
+		"Message":   message,
+	}
+
+	if c.GetHeader("HX-Request") == "true" {
+		c.HTML(http.StatusOK, "backfills", data)
+	} else {
+		c.HTML(http.StatusOK, "base", data)
+	}
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/gin-gonic/gin"
+)
+
+// agentReport bundles the utilisation reports shared by the page and the API.
+type agentReport struct {
+	Agents    []models.AgentUsage        `json:"agents"`
+	Labels    []models.LabelUsage        `json:"labels"`
+	Hours     []models.HourlyUtilisation `json:"busiest_hours"`
+	Incidents []models.OfflineIncident   `json:"offline_incidents"`
+	Folders   []models.FolderPoolUsage   `json:"folder_pools"`
+}
+
+func (h *Handler) loadAgentReport(from, to time.Time) (*agentReport, error) {
+	var (
+		r   agentReport
+		err error
+	)
+	if r.Agents, err = h.DB.AgentUsageReport(from, to); err != nil {
+		return nil, err
+	}
+	if r.Labels, err = h.DB.LabelUsageReport(from, to); err != nil {
+		return nil, err
+	}
+	if r.Hours, err = h.DB.BusiestHours(from, to); err != nil {
+		return nil, err
+	}
+	if r.Incidents, err = h.DB.OfflineIncidents(from, to); err != nil {
+		return nil, err
+	}
+	if r.Folders, err = h.DB.FolderPoolReport(from, to); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GET /reports/agents - executor time per agent and label, busiest hours, offline incidents
+func (h *Handler) RenderAgents(c *gin.Context) {
+	dr, rangeKey, err := resolveDateRange(c, "this_month")
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := h.loadAgentReport(dr.From, dr.To)
+	if err != nil {
+		log.Printf("Agent report error: %v", err)
+		c.String(http.StatusInternalServerError, "Error: %v", err)
+		return
+	}
+
+	data := gin.H{
+		"Agents": true,
+		"Report": report,
+		"Range":  rangeKey,
+		"From":   dr.From,
+		"To":     dr.To,
+	}
+
+	if c.GetHeader("HX-Request") == "true" {
+		c.HTML(http.StatusOK, "agents_report", data)
+	} else {
+		c.HTML(http.StatusOK, "base", data)
+	}
+}
+
+// GET /api/v1/analytics/agents
+func (h *Handler) AgentsV1(c *gin.Context) {
+	dr, _, err := resolveDateRange(c, "this_month")
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_range", err.Error())
+		return
+	}
+
+	report, err := h.loadAgentReport(dr.From, dr.To)
+	if err != nil {
+		log.Printf("[API] Agent report error: %v", err)
+		apiError(c, http.StatusInternalServerError, "internal", "failed to load agent utilisation")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
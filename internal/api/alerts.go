@@ -0,0 +1,67 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GET /admin/alerts?rule=&state= - configured alert rules and the history of fired alerts
+func (h *Handler) RenderAlerts(c *gin.Context) {
+	filter := db.AlertFilter{Rule: c.Query("rule"), State: c.Query("state"), Limit: 200}
+	history, err := h.DB.ListAlerts(filter)
+	if err != nil {
+		log.Printf("ListAlerts error: %v", err)
+		c.String(http.StatusInternalServerError, "Error: %v", err)
+		return
+	}
+
+	data := gin.H{
+		"AlertHistory": true,
+		"Alerts":       history,
+		"AlertRules":   h.AlertRules,
+		"Rule":         filter.Rule,
+		"State":        filter.State,
+		"States":       []string{models.AlertSent, models.AlertFailed, models.AlertSilenced},
+	}
+
+	if c.GetHeader("HX-Request") == "true" {
+		c.HTML(http.StatusOK, "alert_history", data)
+	} else {
+		c.HTML(http.StatusOK, "base", data)
+	}
+}
+
+// GET /api/v1/alerts?rule=&state=&range= - fired alerts, newest first
+func (h *Handler) ListAlertsV1(c *gin.Context) {
+	dr, _, err := resolveDateRange(c, "this_month")
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_range", err.Error())
+		return
+	}
+	limit, err := pageLimit(c)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_limit", err.Error())
+		return
+	}
+
+	history, err := h.DB.ListAlerts(db.AlertFilter{
+		Rule:  c.Query("rule"),
+		State: c.Query("state"),
+		From:  dr.From,
+		To:    dr.To,
+		Limit: limit,
+	})
+	if err != nil {
+		log.Printf("[API] ListAlerts error: %v", err)
+		apiError(c, http.StatusInternalServerError, "internal", "failed to fetch alerts")
+		return
+	}
+	if history == nil {
+		history = []models.Alert{}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": history})
+}
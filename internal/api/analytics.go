@@ -0,0 +1,84 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/analytics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resolveDateRange reads ?from=&to= (YYYY-MM-DD) or ?range= (any GetDateRange key),
+// falling back to defaultKey when neither is given.
+func resolveDateRange(c *gin.Context, defaultKey string) (DateRange, string, error) {
+	fromStr, toStr := c.Query("from"), c.Query("to")
+	if fromStr != "" && toStr != "" {
+		from, err := time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			return DateRange{}, "", fmt.Errorf("invalid from date")
+		}
+		to, err := time.Parse("2006-01-02", toStr)
+		if err != nil {
+			return DateRange{}, "", fmt.Errorf("invalid to date")
+		}
+		return DateRange{From: from, To: to.AddDate(0, 0, 1).Add(-time.Nanosecond)}, "", nil
+	}
+
+	key := c.DefaultQuery("range", defaultKey)
+	dr, err := GetDateRange(key)
+	if err != nil {
+		return DateRange{}, "", fmt.Errorf("invalid time range")
+	}
+	return dr, key, nil
+}
+
+// GET /analytics/dora - DORA dashboard
+func (h *Handler) RenderDORA(c *gin.Context) {
+	dr, rangeKey, err := resolveDateRange(c, "this_month")
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := analytics.LoadDORA(h.DB, dr.From, dr.To)
+	if err != nil {
+		log.Printf("[DORA] LoadDORA error from=%s to=%s: %v", dr.From, dr.To, err)
+		c.String(http.StatusInternalServerError, "Failed to compute DORA metrics: %v", err)
+		return
+	}
+
+	data := gin.H{
+		"DORA":     true,
+		"Report":   report,
+		"Range":    rangeKey,
+		"FromDate": c.Query("from"),
+		"ToDate":   c.Query("to"),
+	}
+
+	if c.GetHeader("HX-Request") == "true" {
+		c.HTML(http.StatusOK, "dora_report", data)
+	} else {
+		c.HTML(http.StatusOK, "base", data)
+	}
+}
+
+// GET /api/v1/analytics/dora
+func (h *Handler) DORAReportV1(c *gin.Context) {
+	dr, _, err := resolveDateRange(c, "this_month")
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_range", err.Error())
+		return
+	}
+
+	report, err := analytics.LoadDORA(h.DB, dr.From, dr.To)
+	if err != nil {
+		log.Printf("[API] LoadDORA error: %v", err)
+		apiError(c, http.StatusInternalServerError, "internal", "failed to compute DORA metrics")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /builds/:id - build detail page with captured console log
+func (h *Handler) RenderBuildDetail(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid build ID")
+		return
+	}
+
+	build, err := h.DB.GetBuildByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.String(http.StatusNotFound, "build %d not found", id)
+		return
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error: %v", err)
+		return
+	}
+
+	data := gin.H{
+		"BuildDetail": true,
+		"Build":       build,
+	}
+
+	buildLog, err := h.DB.GetBuildLog(id, false)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// not captured yet
+	case err != nil:
+		log.Printf("GetBuildLog(%d) error: %v", id, err)
+	default:
+		data["Log"] = buildLog
+	}
+
+	report, err := h.DB.GetTestReport(id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// no JUnit results published or not collected yet
+	case err != nil:
+		log.Printf("GetTestReport(%d) error: %v", id, err)
+	default:
+		data["Tests"] = report
+		if report.Failed > 0 {
+			failed, err := h.DB.GetFailedTestCases(id)
+			if err != nil {
+				log.Printf("GetFailedTestCases(%d) error: %v", id, err)
+			}
+			data["FailedTests"] = failed
+		}
+	}
+
+	changes, err := h.DB.GetBuildChanges(id)
+	if err != nil {
+		log.Printf("GetBuildChanges(%d) error: %v", id, err)
+	}
+	data["Changes"] = changes
+
+	if c.GetHeader("HX-Request") == "true" {
+		c.HTML(http.StatusOK, "build_detail", data)
+	} else {
+		c.HTML(http.StatusOK, "base", data)
+	}
+}
+
+// GET /builds/:id/log - full console log as plain text
+func (h *Handler) DownloadBuildLog(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid build ID")
+		return
+	}
+
+	buildLog, err := h.DB.GetBuildLog(id, true)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && len(buildLog.ConsoleLogGz) == 0) {
+		c.String(http.StatusNotFound, "no console log captured for build %d", id)
+		return
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error: %v", err)
+		return
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(buildLog.ConsoleLogGz))
+	if err != nil {
+		c.String(http.StatusInternalServerError, "corrupt console log: %v", err)
+		return
+	}
+	defer zr.Close()
+
+	filename := fmt.Sprintf("%s_%d_console.txt", buildLog.ProjectName, buildLog.BuildNumber)
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	if _, err := io.Copy(c.Writer, zr); err != nil {
+		log.Printf("Error streaming console log for build %d: %v", id, err)
+	}
+}
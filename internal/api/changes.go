@@ -0,0 +1,89 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/gin-gonic/gin"
+)
+
+// breakingChangesLimit caps the commits listed by the breaking changes report.
+const breakingChangesLimit = 200
+
+// breakingReport bundles the breaking changes reports shared by the page and the API.
+type breakingReport struct {
+	Authors []models.BreakingAuthor `json:"authors"`
+	Changes []models.BreakingChange `json:"changes"`
+}
+
+func (h *Handler) loadBreakingReport(folder string, from, to time.Time) (*breakingReport, error) {
+	var (
+		r   breakingReport
+		err error
+	)
+	if r.Authors, err = h.DB.BreakingAuthors(folder, from, to); err != nil {
+		return nil, err
+	}
+	if r.Changes, err = h.DB.BreakingChanges(folder, from, to, breakingChangesLimit); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GET /reports/breaking-changes - authors of the commits that turned a job from green to red
+func (h *Handler) RenderBreakingChanges(c *gin.Context) {
+	dr, rangeKey, err := resolveDateRange(c, "this_month")
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	folder := strings.Trim(c.Query("folder"), "/")
+
+	report, err := h.loadBreakingReport(folder, dr.From, dr.To)
+	if err != nil {
+		log.Printf("Breaking changes report error: %v", err)
+		c.String(http.StatusInternalServerError, "Error: %v", err)
+		return
+	}
+
+	data := gin.H{
+		"BreakingChanges": true,
+		"Report":          report,
+		"Folder":          folder,
+		"Range":           rangeKey,
+		"From":            dr.From,
+		"To":              dr.To,
+	}
+
+	if c.GetHeader("HX-Request") == "true" {
+		c.HTML(http.StatusOK, "breaking_changes", data)
+	} else {
+		c.HTML(http.StatusOK, "base", data)
+	}
+}
+
+// GET /api/v1/analytics/breaking-changes
+func (h *Handler) BreakingChangesV1(c *gin.Context) {
+	dr, _, err := resolveDateRange(c, "this_month")
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_range", err.Error())
+		return
+	}
+
+	report, err := h.loadBreakingReport(strings.Trim(c.Query("folder"), "/"), dr.From, dr.To)
+	if err != nil {
+		log.Printf("[API] Breaking changes report error: %v", err)
+		apiError(c, http.StatusInternalServerError, "internal", "failed to load breaking changes")
+		return
+	}
+	if report.Authors == nil {
+		report.Authors = []models.BreakingAuthor{}
+	}
+	if report.Changes == nil {
+		report.Changes = []models.BreakingChange{}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/compliance"
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// loadIGRMReport resolves the report range and checks its deployments.
+func (h *Handler) loadIGRMReport(c *gin.Context) (*compliance.Report, string, int, error) {
+	if h.IGRMPolicy == nil {
+		return nil, "", http.StatusServiceUnavailable, fmt.Errorf("IGRM compliance is not configured, see IGRM_POLICY_FILE")
+	}
+	dr, rangeKey, err := resolveDateRange(c, "previous_month")
+	if err != nil {
+		return nil, "", http.StatusBadRequest, err
+	}
+	report, err := compliance.Load(h.DB, h.IGRMPolicy, dr.From, dr.To)
+	if err != nil {
+		log.Printf("[IGRM] compliance report error from=%s to=%s: %v", dr.From, dr.To, err)
+		return nil, "", http.StatusInternalServerError, fmt.Errorf("failed to load IGRM compliance")
+	}
+	return report, rangeKey, http.StatusOK, nil
+}
+
+// GET /reports/igrm-compliance - production deployments breaking change-ticket rules
+func (h *Handler) RenderIGRMCompliance(c *gin.Context) {
+	report, rangeKey, status, err := h.loadIGRMReport(c)
+	if err != nil {
+		c.String(status, err.Error())
+		return
+	}
+
+	data := gin.H{
+		"IGRMCompliance": true,
+		"Report":         report,
+		"Policy":         h.IGRMPolicy,
+		"Range":          rangeKey,
+		"FromDate":       report.From.Format("2006-01-02"),
+		"ToDate":         report.To.Format("2006-01-02"),
+	}
+
+	if c.GetHeader("HX-Request") == "true" {
+		c.HTML(http.StatusOK, "igrm_compliance", data)
+	} else {
+		c.HTML(http.StatusOK, "base", data)
+	}
+}
+
+// GET /reports/igrm-compliance/export - findings and monthly summary as Excel
+func (h *Handler) ExportIGRMCompliance(c *gin.Context) {
+	report, _, status, err := h.loadIGRMReport(c)
+	if err != nil {
+		c.String(status, err.Error())
+		return
+	}
+
+	f := excelize.NewFile()
+	f.SetSheetName("Sheet1", "Findings")
+
+	header := []interface{}{"#", "Controller", "Project", "Build#", "Status", "Time", "User", "DeployEnv", "IGRM#", "Issues", "Reused by", "Ticket check"}
+	f.SetSheetRow("Findings", "A1", &header)
+	for i, fd := range report.Findings {
+		row := []interface{}{
+			i + 1,
+			fd.Controller,
+			fd.ProjectPath,
+			fd.BuildNumber,
+			fd.Status,
+			fd.Timestamp.In(h.IGRMPolicy.Location).Format("2006-01-02 15:04"),
+			fd.UserID,
+			fd.DeployEnv,
+			fd.IGRMNo,
+			strings.Join(fd.Issues(), ", "),
+			strings.Join(fd.ReusedBy, ", "),
+			fd.TicketDetail,
+		}
+		f.SetSheetRow("Findings", fmt.Sprintf("A%d", i+2), &row)
+	}
+
+	f.NewSheet("Monthly")
+	header = []interface{}{"Month", "Folder", "Deployments", "Compliant", "Missing", "Malformed", "Reused", "Outside window", "Ticket rejected", "Compliance %"}
+	f.SetSheetRow("Monthly", "A1", &header)
+	for i, m := range report.Monthly {
+		row := []interface{}{
+			m.Month.Format("2006-01"),
+			m.Folder,
+			m.Deployments,
+			m.Compliant,
+			m.Missing,
+			m.Malformed,
+			m.Reused,
+			m.OutsideWindow,
+			m.Rejected,
+			fmt.Sprintf("%.1f", m.ComplianceRate()*100),
+		}
+		f.SetSheetRow("Monthly", fmt.Sprintf("A%d", i+2), &row)
+	}
+
+	filename := fmt.Sprintf("igrm_compliance_%s_to_%s_%s.xlsx",
+		report.From.Format("2006-01-02"), report.To.Format("2006-01-02"), time.Now().Format("2006-01-02_1504"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Transfer-Encoding", "binary")
+	c.Header("Expires", "0")
+
+	if err := f.Write(c.Writer); err != nil {
+		log.Printf("Error writing Excel: %v", err)
+		c.String(http.StatusInternalServerError, "Failed to write Excel file")
+	}
+}
+
+// GET /api/v1/analytics/igrm-compliance
+func (h *Handler) IGRMComplianceV1(c *gin.Context) {
+	report, _, status, err := h.loadIGRMReport(c)
+	if err != nil {
+		code := "internal"
+		switch status {
+		case http.StatusBadRequest:
+			code = "invalid_range"
+		case http.StatusServiceUnavailable:
+			code = "not_configured"
+		}
+		apiError(c, status, code, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
@@ -3,14 +3,19 @@ package api
 import (
 	"fmt"
 	"log"
-	"math"
 	"net/http"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gauravkr19/jenkins-analytics/internal/alerts"
+	"github.com/gauravkr19/jenkins-analytics/internal/backfill"
+	"github.com/gauravkr19/jenkins-analytics/internal/classify"
+	"github.com/gauravkr19/jenkins-analytics/internal/compliance"
 	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/internal/jenkins"
+	"github.com/gauravkr19/jenkins-analytics/internal/reports"
 	"github.com/gauravkr19/jenkins-analytics/models"
 
 	"github.com/gin-gonic/gin"
@@ -18,7 +23,17 @@ import (
 )
 
 type Handler struct {
-	DB *db.DB
+	DB           *db.DB
+	Controllers  []*jenkins.JenkinsClient // configured Jenkins controllers, used by the webhook
+	WebhookToken string                 // shared secret for /webhooks/jenkins, empty disables it
+	Classifier   *classify.Classifier   // failure classification rules, may be nil
+	ParamColumns []string               // build parameters shown as dashboard and export columns
+	Backfills    *backfill.Runner       // derived column backfills started from the admin page
+	IGRMPolicy   *compliance.Policy     // change-ticket compliance rules, may be nil
+	AlertRules   *alerts.Config         // alert rules shown on the admin page, nil when alerting is off
+	Reports      *reports.Scheduler     // scheduled digests, nil when none are configured
+
+	builds buildLister // overrides DB for ListBuildsV1 in tests
 }
 
 func (h *Handler) GetRecentBuilds(c *gin.Context) {
@@ -69,89 +84,144 @@ func (h *Handler) GetBuild(c *gin.Context) {
 	c.JSON(http.StatusOK, build)
 }
 
-func (h *Handler) FilterBuildsByTime(c *gin.Context) {
+// searchByParam is the search_by value of the parameter filter syntax.
+const searchByParam = "param"
+
+// searchByCommit is the search_by value of builds that built or shipped a commit.
+const searchByCommit = "commit"
+
+// searchContext returns the search_by/search_term pair that reproduces q's
+// search in pagination, sort and export links.
+func searchContext(q db.BuildQuery) (string, string) {
+    if len(q.Params) > 0 {
+        return searchByParam, db.FormatParamFilter(q.Params)
+    }
+    if q.Commit != "" {
+        return searchByCommit, q.Commit
+    }
+    return strings.Join(q.SearchFields, ","), q.SearchTerm
+}
+
+// buildQueryFromRequest maps the shared /builds/filter and /builds/export
+// query parameters onto a db.BuildQuery. mode is one of "search_only",
+// "date_range", "named_range" or "project"; an empty mode means no usable filter.
+func buildQueryFromRequest(c *gin.Context) (q db.BuildQuery, mode string, err error) {
     rangeKey := c.Query("range")
     fromStr := c.Query("from")
     toStr := c.Query("to")
-	sortBy := c.DefaultQuery("sort_by", "timestamp") // faillback to timestamp if sort_by is missing
-	order := c.DefaultQuery("order", "desc")
-    searchBy  := strings.ToLower(c.DefaultQuery("search_by", ""))
-    searchTerm:= strings.TrimSpace(strings.ToLower(c.DefaultQuery("search_term", "")))	
+    project := c.Query("project")
+    searchBy := strings.ToLower(c.DefaultQuery("search_by", ""))
+    rawTerm := strings.TrimSpace(c.DefaultQuery("search_term", ""))
+    searchTerm := strings.ToLower(rawTerm)
+
+    q = db.BuildQuery{
+        SortBy: c.DefaultQuery("sort_by", "timestamp"), // faillback to timestamp if sort_by is missing
+        Order:  c.DefaultQuery("order", "desc"),
+        Status: c.Query("status"),
+        Env:    c.Query("env"),
+        UserID: c.Query("user"),
+        Controller: c.Query("controller"),
+    }
+    // parameter filters keep their case: search_term=param.VERSION=1.4.2 or ?param.VERSION=1.4.2
+    if searchBy == searchByParam || (searchBy == "" && strings.HasPrefix(rawTerm, db.ParamFilterPrefix)) {
+        q.Params, err = db.ParseParamFilter(rawTerm)
+        if err != nil {
+            return q, "", err
+        }
+    }
+    for key, vals := range c.Request.URL.Query() {
+        if name, ok := strings.CutPrefix(key, db.ParamFilterPrefix); ok && name != "" && len(vals) > 0 {
+            if q.Params == nil {
+                q.Params = make(map[string]string)
+            }
+            q.Params[name] = vals[0]
+        }
+    }
 
-    var from, to time.Time
-    var err error
-    var mode string
+    // builds that built or shipped a commit: search_by=commit or ?commit=<sha prefix>
+    if searchBy == searchByCommit {
+        q.Commit = searchTerm
+    } else if commit := strings.TrimSpace(c.Query("commit")); commit != "" {
+        q.Commit = strings.ToLower(commit)
+    }
 
-    switch {
-    // —— NEW: search without date/range —— 
-    case searchBy != "" && searchTerm != "":
-        // full‐history search only when user provided both parts
-        from = time.Unix(0, 0)           // epoch start
-        to   = time.Now().Add(time.Second) // just beyond now
-        mode = "search_only"
+    if searchBy != "" && searchBy != searchByParam && searchBy != searchByCommit && searchTerm != "" {
+        if !db.IsSearchable(searchBy) {
+            return q, "", fmt.Errorf("invalid search field %q", searchBy)
+        }
+        q.SearchFields = []string{searchBy}
+        q.SearchTerm = searchTerm
+    }
+
+    // the project narrows every time filter, not only the project-only mode
+    q.ProjectPath = project
 
+    switch {
     case fromStr != "" && toStr != "":
-        // existing custom date‐range
-        from, err = time.Parse("2006-01-02", fromStr)
-        if err != nil { c.String(http.StatusBadRequest, "Invalid from date"); return }
-        to, err = time.Parse("2006-01-02", toStr)
-        if err != nil { c.String(http.StatusBadRequest, "Invalid to date"); return }
-        to = to.Add(24*time.Hour).Add(-time.Nanosecond)
+        q.From, err = time.Parse("2006-01-02", fromStr)
+        if err != nil {
+            return q, "", fmt.Errorf("invalid from date")
+        }
+        q.To, err = time.Parse("2006-01-02", toStr)
+        if err != nil {
+            return q, "", fmt.Errorf("invalid to date")
+        }
+        // Include entire 'to' day
+        q.To = q.To.AddDate(0, 0, 1).Add(-time.Nanosecond)
         mode = "date_range"
 
     case rangeKey != "":
-        // existing named range
         dr, err := GetDateRange(rangeKey)
-        if err != nil { c.String(http.StatusBadRequest, "Invalid time range"); return }
-        from, to = dr.From, dr.To
+        if err != nil {
+            return q, "", fmt.Errorf("invalid time range")
+        }
+        q.From, q.To = dr.From, dr.To
         mode = "named_range"
 
-    default:
+    case project != "":
+        mode = "project"
+
+    case q.SearchTerm != "" || len(q.Params) > 0 || q.Commit != "":
+        // full-history search, no time bounds
+        mode = "search_only"
+    }
+
+    return q, mode, nil
+}
+
+func (h *Handler) FilterBuildsByTime(c *gin.Context) {
+    q, mode, err := buildQueryFromRequest(c)
+    if err != nil {
+        c.String(http.StatusBadRequest, err.Error())
+        return
+    }
+    if mode == "" || mode == "project" {
         c.String(http.StatusBadRequest, "Missing parameters")
         return
     }
 
-    // Fetch **all** builds in this date/range, ignoring pagination
-    allBuilds, err := h.DB.GetBuildsByTime(from, to, math.MaxInt32, 0, sortBy, order)
+    page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+    limit, _ := strconv.Atoi(c.DefaultQuery("limit", "35"))
+    if page < 1 { page = 1 }
+    if limit < 1 { limit = 35 }
+    q.Limit = limit
+    q.Offset = (page - 1) * limit
+
+    totalCount, err := h.DB.CountBuildsMatching(q)
     if err != nil {
-        log.Printf("GetBuildsByTime error sortBy=%s order=%s from=%s to=%s: %v",
-            sortBy, order, from, to, err)
-        c.String(500, fmt.Sprintf("Failed to fetch builds: %v", err))
+        log.Printf("CountBuildsMatching error query=%+v: %v", q, err)
+        c.String(500, fmt.Sprintf("Failed to count builds: %v", err))
         return
     }
 
-    // --- New: apply search filter ---
-    if searchBy != "" && searchTerm != "" {
-        filtered := allBuilds[:0]
-        for _, b := range allBuilds {
-            var field string
-            switch searchBy {
-            case "env":
-                field = b.Env
-            case "project_path":
-                field = b.ProjectPath
-            case "user_id":
-                field = b.UserID
-            default:
-                continue
-            }
-            if strings.Contains(strings.ToLower(field), searchTerm) {
-                filtered = append(filtered, b)
-            }
-        }
-        allBuilds = filtered
+    builds, err := h.DB.QueryBuilds(q)
+    if err != nil {
+        log.Printf("QueryBuilds error query=%+v: %v", q, err)
+        c.String(500, fmt.Sprintf("Failed to fetch builds: %v", err))
+        return
     }
-
-    // --- Recount & paginate on filtered+sorted slice ---
-    totalCount := len(allBuilds)
-    page, _   := strconv.Atoi(c.DefaultQuery("page", "1"))
-    limit, _  := strconv.Atoi(c.DefaultQuery("limit", "35"))
-    if page < 1 { page = 1 }
     totalPages := (totalCount + limit - 1) / limit
-    offset     := (page - 1) * limit
-    end        := offset + limit
-    if end > totalCount { end = totalCount }
-    builds     := allBuilds[offset:end]
+    searchBy, searchTerm := searchContext(q)
 
     // Prepare template data
     data := gin.H{
@@ -159,28 +229,28 @@ func (h *Handler) FilterBuildsByTime(c *gin.Context) {
         "CurrentPage":   page,
         "TotalPages":    totalPages,
         "Limit":         limit,
-        "CurrentSortBy": sortBy,
-        "CurrentOrder":  order,
+        "CurrentSortBy": q.SortBy,
+        "CurrentOrder":  q.Order,
         "SearchBy":      searchBy,
         "SearchTerm":    searchTerm,
+        "Controller":    q.Controller,
+        "ParamColumns":  h.ParamColumns,
     }
-	data["TotalPages"] = totalPages
 
 	log.Printf("Fetching page=%d limit=%d, total builds=%d, totalPages=%d", page, limit, totalCount, totalPages)
 
 	if mode == "date_range" {
-		data["FromDate"] = fromStr
-		data["ToDate"] = toStr
+		data["FromDate"] = c.Query("from")
+		data["ToDate"] = c.Query("to")
 	} else {
-		data["Range"] = rangeKey
+		data["Range"] = c.Query("range")
 	}
 
     // Choose partial or full
+    log.Printf("Rendering builds_table count=%d sortBy=%s order=%s", len(builds), q.SortBy, q.Order)
     if c.GetHeader("HX-Request") != "" {
-        log.Printf("Rendering builds_table count=%d sortBy=%s order=%s", len(allBuilds), sortBy, order)
         c.HTML(http.StatusOK, "builds/partial_response", data)
     } else {
-        log.Printf("Rendering builds_table count=%d sortBy=%s order=%s", len(allBuilds), sortBy, order)
         c.HTML(http.StatusOK, "base", data)
     }
 }
@@ -197,85 +267,36 @@ func (h *Handler) RenderHome(c *gin.Context) {
 
 // ExportBuildsToExcel handles exporting builds to an Excel file.
 func (h *Handler) ExportBuildsToExcel(c *gin.Context) {
-    rangeKey := c.Query("range")
-    fromStr := c.Query("from")
-    toStr := c.Query("to")
-    project := c.Query("project")
-	sortBy := c.DefaultQuery("sort_by", "timestamp") // faillback to timestamp if sort_by is missing
-	order := c.DefaultQuery("order", "desc")	
-
-    searchBy   := strings.ToLower(c.DefaultQuery("search_by", ""))
-    searchTerm := strings.TrimSpace(strings.ToLower(c.DefaultQuery("search_term", "")))
+    q, mode, err := buildQueryFromRequest(c)
+    if err != nil {
+        c.String(http.StatusBadRequest, err.Error())
+        return
+    }
 
-	// Resolve date range or full‑history for search-only:
-    var from, to time.Time
-    var err error
     var label string
-    var builds []models.Build
-
-    // Determine filter mode
-    switch {
-    case searchBy != "" && searchTerm != "":
-        from = time.Unix(0, 0)
-        to   = time.Now()
-		builds, err = h.DB.GetBuildsByTime(from, to, math.MaxInt32, 0, sortBy, order)
-        if err != nil {
-            log.Printf("GetBuildsByTime error sortBy=%s order=%s from=%s to=%s: %v",
-                sortBy, order, from, to, err)
-            c.String(500, fmt.Sprintf("Failed to fetch builds: %v", err))
-            return
-        }
-    case fromStr != "" && toStr != "":
-        // Manual date range
-        from, err = time.Parse("2006-01-02", fromStr)
-        if err != nil {
-            c.String(http.StatusBadRequest, "Invalid 'from' date")
-            return
-        }
-        to, err = time.Parse("2006-01-02", toStr)
-        if err != nil {
-            c.String(http.StatusBadRequest, "Invalid 'to' date")
-            return
-        }		
-        // Include entire 'to' day
-        to = to.AddDate(0, 0, 1).Add(-time.Nanosecond)
-        label = fmt.Sprintf("%s_to_%s", fromStr, toStr)
-        builds, err = h.DB.GetBuildsByTime(from, to, math.MaxInt32, 0, sortBy, order)
-        if err != nil {
-            log.Printf("GetBuildsByTime error sortBy=%s order=%s from=%s to=%s: %v",
-                sortBy, order, from, to, err)
-            c.String(500, fmt.Sprintf("Failed to fetch builds: %v", err))
-            return
-        }
-    case rangeKey != "":
-        // Named range
-        dr, err := GetDateRange(rangeKey)
-        if err != nil {
-            c.String(http.StatusBadRequest, "Invalid range key")
-            return
-        }
-        from, to = dr.From, dr.To
-        label = rangeKey
-        builds, err = h.DB.GetBuildsByTime(from, to, math.MaxInt32, 0, sortBy, order)
-        if err != nil {
-            log.Printf("GetBuildsByTime error sortBy=%s order=%s from=%s to=%s: %v",
-                sortBy, order, from, to, err)
-            c.String(500, fmt.Sprintf("Failed to fetch builds: %v", err))
-            return
-        }
-    case project != "":
-        label = strings.ReplaceAll(project, "/", "_")
-
-        builds, err = h.DB.GetBuildsByProjectPath(project)
-        if err != nil {
-            c.String(http.StatusInternalServerError, "Failed to fetch builds")
-            return
-        }		
-
+    switch mode {
+    case "search_only":
+        label = "search"
+    case "date_range":
+        label = fmt.Sprintf("%s_to_%s", c.Query("from"), c.Query("to"))
+    case "named_range":
+        label = c.Query("range")
+    case "project":
+        label = strings.ReplaceAll(q.ProjectPath, "/", "_")
 	default:
 		c.String(http.StatusBadRequest, "Missing filter parameters")
 		return
 	}
+    if q.Controller != "" {
+        label = q.Controller + "_" + label
+    }
+
+    builds, err := h.DB.QueryBuilds(q)
+    if err != nil {
+        log.Printf("QueryBuilds error query=%+v: %v", q, err)
+        c.String(500, fmt.Sprintf("Failed to fetch builds: %v", err))
+        return
+    }
 
 	// Create the Excel file
 	f := excelize.NewFile()
@@ -291,16 +312,22 @@ func (h *Handler) ExportBuildsToExcel(c *gin.Context) {
 	f.DeleteSheet("Sheet1")	
 
 	// Header
-	f.SetSheetRow(sheet, "A1", &[]interface{}{"#", "Build#", "Env", "DeployEnv", "IGRM#", "Project", "Status", "User", "Time", "DurationMS", "JobURL", "Trigger", "GitRepoURL", "GitBranch", "CommitID"})
+	header := []interface{}{"#", "Controller", "Build#", "Env", "DeployEnv", "IGRM#", "IGRM check", "Project", "Status", "User", "Time", "DurationMS", "JobURL", "Trigger", "GitRepoURL", "GitBranch", "CommitID"}
+	for _, p := range h.ParamColumns {
+		header = append(header, p)
+	}
+	f.SetSheetRow(sheet, "A1", &header)
 
 	// Data rows
 	for i, b := range builds {
 		row := []interface{}{
 			i + 1,
+			b.Controller,
 			b.BuildNumber,
 			b.Env,
             b.DeployEnv,
             b.IGRMNo,
+			b.IGRMStatus,
 			b.ProjectName,
 			b.Status,
 			b.UserID,
@@ -312,6 +339,9 @@ func (h *Handler) ExportBuildsToExcel(c *gin.Context) {
 			b.Branch,
 			b.CommitSHA,			
 		}
+		for _, p := range h.ParamColumns {
+			row = append(row, b.Parameters[p])
+		}
 		cell := fmt.Sprintf("A%d", i+2)
 		f.SetSheetRow(sheet, cell, &row)
 	}
@@ -337,6 +367,7 @@ func (h *Handler) ExportBuildsToExcel(c *gin.Context) {
 func (h *Handler) GetPipelineBuilds(c *gin.Context) {
     rawPath := c.Param("projectPath")
     fullPath := strings.TrimPrefix(rawPath, "/")
+    controller := c.Query("controller")
 
     page, _  := strconv.Atoi(c.DefaultQuery("page", "1"))
     limit, _ := strconv.Atoi(c.DefaultQuery("limit", "30"))
@@ -344,7 +375,7 @@ func (h *Handler) GetPipelineBuilds(c *gin.Context) {
     offset := (page - 1) * limit
 
     // fetch all, then sort in Go
-    allBuilds, err := h.DB.GetBuildsByProjectPath(fullPath)
+    allBuilds, err := h.DB.GetBuildsByProjectPath(controller, fullPath)
     if err != nil {
         c.String(http.StatusInternalServerError, "Error: %v", err)
         return
@@ -384,13 +415,16 @@ func (h *Handler) GetPipelineBuilds(c *gin.Context) {
 
     data := gin.H{
         "ProjectPath":   fullPath,
+        "Controller":    controller,
         "Builds":        paged,
         "CurrentPage":   page,
         "TotalPages":    totalPages,
         "Limit":         limit,
         "CurrentSortBy": sortBy,
         "CurrentOrder":  order,	
+        "ParamColumns":  h.ParamColumns,
     }
+    h.addStageData(data, fullPath, paged)
 
     if c.GetHeader("HX-Request") == "true" {
         c.HTML(http.StatusOK, "pipeline_partial", data)
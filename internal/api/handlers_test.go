@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBuildQueryParamFilterWithSpaces(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, target := range []string{
+		// the link on a build's parameters
+		"/builds/filter?page=1&limit=35&param.MESSAGE=fix+login+page",
+		// the search box and pagination links
+		"/builds/filter?page=1&limit=35&search_by=param&search_term=param.MESSAGE%3D%22fix+login+page%22",
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+
+		q, mode, err := buildQueryFromRequest(c)
+		if err != nil {
+			t.Errorf("%s: %v", target, err)
+			continue
+		}
+		if mode != "search_only" || q.Params["MESSAGE"] != "fix login page" {
+			t.Errorf("%s: got mode %q and params %v", target, mode, q.Params)
+		}
+		if by, term := searchContext(q); by != searchByParam || term != `param.MESSAGE="fix login page"` {
+			t.Errorf("%s: search context %q %q", target, by, term)
+		}
+	}
+}
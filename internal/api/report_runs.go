@@ -0,0 +1,106 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /admin/reports - scheduled digests, their next run and the delivery history
+func (h *Handler) RenderReportRuns(c *gin.Context) {
+	h.renderReportRuns(c, "")
+}
+
+func (h *Handler) renderReportRuns(c *gin.Context, message string) {
+	runs, err := h.DB.ListReportRuns(c.Query("report"), 100)
+	if err != nil {
+		log.Printf("ListReportRuns error: %v", err)
+		c.String(http.StatusInternalServerError, "Error: %v", err)
+		return
+	}
+
+	type scheduled struct {
+		Name, Schedule, Range, Env, Folder string
+		Recipients                         []string
+		NextRun                            time.Time
+	}
+	var schedules []scheduled
+	if h.Reports != nil {
+		now := time.Now()
+		for i := range h.Reports.Config.Reports {
+			r := &h.Reports.Config.Reports[i]
+			schedules = append(schedules, scheduled{
+				Name: r.Name, Schedule: r.Schedule, Range: r.Range, Env: r.Env, Folder: r.Folder,
+				Recipients: r.Recipients, NextRun: h.Reports.NextRun(r, now),
+			})
+		}
+	}
+
+	data := gin.H{
+		"ReportRuns": true,
+		"Runs":       runs,
+		"Schedules":  schedules,
+		"Enabled":    h.Reports != nil,
+		"Message":    message,
+	}
+
+	if c.GetHeader("HX-Request") == "true" {
+		c.HTML(http.StatusOK, "report_runs", data)
+	} else {
+		c.HTML(http.StatusOK, "base", data)
+	}
+}
+
+// POST /admin/reports/:name/send - deliver a report now, outside its schedule
+func (h *Handler) SendReport(c *gin.Context) {
+	if h.Reports == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "scheduled reports are not configured"})
+		return
+	}
+
+	name := c.Param("name")
+	run, err := h.Reports.SendNow(name)
+	message := ""
+	switch {
+	case err != nil:
+		log.Printf("[Reports] send %s error: %v", name, err)
+		message = fmt.Sprintf("%s could not be sent: %v", name, err)
+	case run.Error != "":
+		message = fmt.Sprintf("%s failed: %s", name, run.Error)
+	default:
+		message = fmt.Sprintf("%s sent to %d recipients.", name, len(run.Recipients))
+	}
+
+	if c.GetHeader("HX-Request") == "true" {
+		h.renderReportRuns(c, message)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": run})
+}
+
+// GET /admin/reports/:name/preview - the HTML digest as it would be mailed now
+func (h *Handler) PreviewReport(c *gin.Context) {
+	if h.Reports == nil {
+		c.String(http.StatusServiceUnavailable, "scheduled reports are not configured, see REPORT_SCHEDULES_FILE")
+		return
+	}
+	d, err := h.Reports.Digest(c.Param("name"))
+	if err != nil {
+		log.Printf("[Reports] preview %s error: %v", c.Param("name"), err)
+		c.String(http.StatusInternalServerError, "Error: %v", err)
+		return
+	}
+	html, err := d.HTML()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error: %v", err)
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", html)
+}
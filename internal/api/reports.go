@@ -0,0 +1,144 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/analytics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// categoryTotal is a per-category sum shown above the weekly breakdown.
+type categoryTotal struct {
+	Category string
+	Count    int
+}
+
+// GET /reports/failure-causes - classified failures by category, folder and week
+func (h *Handler) RenderFailureCauses(c *gin.Context) {
+	dr, rangeKey, err := resolveDateRange(c, "this_month")
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rows, err := h.DB.FailureCauseReport(dr.From, dr.To)
+	if err != nil {
+		log.Printf("FailureCauseReport error: %v", err)
+		c.String(http.StatusInternalServerError, "Error: %v", err)
+		return
+	}
+
+	sums := make(map[string]int)
+	total := 0
+	for _, r := range rows {
+		sums[r.Category] += r.Count
+		total += r.Count
+	}
+	totals := make([]categoryTotal, 0, len(sums))
+	for cat, n := range sums {
+		totals = append(totals, categoryTotal{Category: cat, Count: n})
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Count > totals[j].Count })
+
+	data := gin.H{
+		"FailureCauses": true,
+		"Rows":          rows,
+		"Totals":        totals,
+		"Total":         total,
+		"Range":         rangeKey,
+		"From":          dr.From,
+		"To":            dr.To,
+	}
+
+	if c.GetHeader("HX-Request") == "true" {
+		c.HTML(http.StatusOK, "failure_causes", data)
+	} else {
+		c.HTML(http.StatusOK, "base", data)
+	}
+}
+
+// POST /admin/failure-rules/reload - re-read the failure rules file now
+func (h *Handler) ReloadFailureRules(c *gin.Context) {
+	if h.Classifier == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "failure classification is not configured"})
+		return
+	}
+	if err := h.Classifier.Reload(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "reloaded", "rules": len(h.Classifier.Rules())})
+}
+
+// flakyParams reads ?folder=&window=&limit= shared by the flaky test page and API.
+func flakyParams(c *gin.Context) (folder string, window, limit int) {
+	folder = strings.Trim(c.Query("folder"), "/")
+	window, err := strconv.Atoi(c.Query("window"))
+	if err != nil || window <= 0 {
+		window = analytics.DefaultFlakyWindow
+	}
+	limit, err = strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	return folder, window, limit
+}
+
+// GET /reports/flaky-tests - flakiest tests per folder
+func (h *Handler) RenderFlakyTests(c *gin.Context) {
+	dr, rangeKey, err := resolveDateRange(c, "this_month")
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	folder, window, limit := flakyParams(c)
+
+	groups, err := analytics.LoadFlakyTests(h.DB, dr.From, dr.To, folder, window, limit)
+	if err != nil {
+		log.Printf("LoadFlakyTests error: %v", err)
+		c.String(http.StatusInternalServerError, "Error: %v", err)
+		return
+	}
+
+	data := gin.H{
+		"FlakyTests": true,
+		"Groups":     groups,
+		"Folder":     folder,
+		"Window":     window,
+		"Range":      rangeKey,
+		"From":       dr.From,
+		"To":         dr.To,
+	}
+
+	if c.GetHeader("HX-Request") == "true" {
+		c.HTML(http.StatusOK, "flaky_tests", data)
+	} else {
+		c.HTML(http.StatusOK, "base", data)
+	}
+}
+
+// GET /api/v1/analytics/flaky-tests
+func (h *Handler) FlakyTestsV1(c *gin.Context) {
+	dr, _, err := resolveDateRange(c, "this_month")
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_range", err.Error())
+		return
+	}
+	folder, window, limit := flakyParams(c)
+
+	groups, err := analytics.LoadFlakyTests(h.DB, dr.From, dr.To, folder, window, limit)
+	if err != nil {
+		log.Printf("[API] LoadFlakyTests error: %v", err)
+		apiError(c, http.StatusInternalServerError, "internal", "failed to detect flaky tests")
+		return
+	}
+	if groups == nil {
+		groups = []analytics.FlakyFolder{}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": groups})
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GET /builds/running - builds running now and items waiting in the queue
+func (h *Handler) RenderRunningNow(c *gin.Context) {
+	running, err := h.DB.GetRunningBuilds(c.Query("controller"))
+	if err != nil {
+		log.Printf("GetRunningBuilds error: %v", err)
+		c.String(http.StatusInternalServerError, "Error: %v", err)
+		return
+	}
+	queued, err := h.DB.GetQueuedItems()
+	if err != nil {
+		log.Printf("GetQueuedItems error: %v", err)
+		c.String(http.StatusInternalServerError, "Error: %v", err)
+		return
+	}
+
+	data := gin.H{
+		"RunningNow": true,
+		"Running":    running,
+		"Queued":     queued,
+	}
+
+	if c.GetHeader("HX-Request") == "true" {
+		c.HTML(http.StatusOK, "running_now", data)
+	} else {
+		c.HTML(http.StatusOK, "base", data)
+	}
+}
+
+// GET /reports/queue-times - weekly queue wait per folder
+func (h *Handler) RenderQueueTimes(c *gin.Context) {
+	dr, rangeKey, err := resolveDateRange(c, "this_month")
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	folder := strings.Trim(c.Query("folder"), "/")
+
+	rows, err := h.DB.QueueWaitReport(dr.From, dr.To, folder)
+	if err != nil {
+		log.Printf("QueueWaitReport error: %v", err)
+		c.String(http.StatusInternalServerError, "Error: %v", err)
+		return
+	}
+
+	data := gin.H{
+		"QueueTimes": true,
+		"Rows":       rows,
+		"Folder":     folder,
+		"Range":      rangeKey,
+		"From":       dr.From,
+		"To":         dr.To,
+	}
+
+	if c.GetHeader("HX-Request") == "true" {
+		c.HTML(http.StatusOK, "queue_times", data)
+	} else {
+		c.HTML(http.StatusOK, "base", data)
+	}
+}
+
+// GET /api/v1/analytics/queue-times
+func (h *Handler) QueueTimesV1(c *gin.Context) {
+	dr, _, err := resolveDateRange(c, "this_month")
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_range", err.Error())
+		return
+	}
+
+	rows, err := h.DB.QueueWaitReport(dr.From, dr.To, strings.Trim(c.Query("folder"), "/"))
+	if err != nil {
+		log.Printf("[API] QueueWaitReport error: %v", err)
+		apiError(c, http.StatusInternalServerError, "internal", "failed to load queue times")
+		return
+	}
+	if rows == nil {
+		rows = []models.QueueWaitTrend{}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": rows})
+}
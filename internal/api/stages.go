@@ -0,0 +1,116 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stageTrendWeeks is how far back the pipeline view's stage trend looks.
+const stageTrendWeeks = 8
+
+// stageSegment is one stage drawn on a build's timeline bar.
+type stageSegment struct {
+	models.BuildStage
+	Width float64 // share of the summed stage durations, 0..100
+	Class string  // bootstrap background class for the stage status
+}
+
+// stageTimeline pairs a build with the segments of its stage bar.
+type stageTimeline struct {
+	Build    models.Build
+	Segments []stageSegment
+}
+
+// buildStageTimelines lays out the stage bars of builds that have stage data.
+func buildStageTimelines(builds []models.Build, stages map[int][]models.BuildStage) []stageTimeline {
+	var out []stageTimeline
+	for _, b := range builds {
+		ss := stages[b.ID]
+		if len(ss) == 0 {
+			continue
+		}
+		var total int64
+		for _, s := range ss {
+			total += s.DurationMS
+		}
+		t := stageTimeline{Build: b}
+		for _, s := range ss {
+			width := 0.0
+			if total > 0 {
+				width = float64(s.DurationMS) * 100 / float64(total)
+			}
+			t.Segments = append(t.Segments, stageSegment{BuildStage: s, Width: width, Class: stageClass(s.Status)})
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+func stageClass(status string) string {
+	switch strings.ToUpper(status) {
+	case "SUCCESS":
+		return "bg-success"
+	case "FAILED", "FAILURE":
+		return "bg-danger"
+	case "UNSTABLE":
+		return "bg-warning"
+	case "PAUSED_PENDING_INPUT", "IN_PROGRESS":
+		return "bg-info"
+	default:
+		return "bg-secondary"
+	}
+}
+
+// addStageData puts the stage timeline of the listed builds and the project's
+// stage trend into a pipeline view. Failures only hide those sections.
+func (h *Handler) addStageData(data gin.H, projectPath string, builds []models.Build) {
+	ids := make([]int, 0, len(builds))
+	for _, b := range builds {
+		ids = append(ids, b.ID)
+	}
+	stages, err := h.DB.GetStagesForBuilds(ids)
+	if err != nil {
+		log.Printf("GetStagesForBuilds error for %s: %v", projectPath, err)
+		return
+	}
+	data["StageTimelines"] = buildStageTimelines(builds, stages)
+
+	to := time.Now()
+	trend, err := h.DB.StageDurationTrend(projectPath, to.AddDate(0, 0, -7*stageTrendWeeks), to)
+	if err != nil {
+		log.Printf("StageDurationTrend error for %s: %v", projectPath, err)
+		return
+	}
+	data["StageTrend"] = trend
+}
+
+// GET /api/v1/analytics/stages?project=<path> - weekly stage durations of a project
+func (h *Handler) StageTrendV1(c *gin.Context) {
+	project := c.Query("project")
+	if project == "" {
+		apiError(c, http.StatusBadRequest, "invalid_project", "project is required")
+		return
+	}
+	dr, _, err := resolveDateRange(c, "this_month")
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_range", err.Error())
+		return
+	}
+
+	trend, err := h.DB.StageDurationTrend(project, dr.From, dr.To)
+	if err != nil {
+		log.Printf("[API] StageDurationTrend error: %v", err)
+		apiError(c, http.StatusInternalServerError, "internal", "failed to load stage trend")
+		return
+	}
+	if trend == nil {
+		trend = []models.StageTrend{}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": trend})
+}
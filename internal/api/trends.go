@@ -0,0 +1,113 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/gin-gonic/gin"
+)
+
+// trendReport bundles the rollup reports shared by the page and the API.
+type trendReport struct {
+	Days    []models.DailyRollup  `json:"days"`
+	Folders []models.FolderRollup `json:"folders"`
+}
+
+// Share is the number of builds relative to the busiest day, 0..1.
+func (r *trendReport) Share(builds int) float64 {
+	max := 0
+	for _, d := range r.Days {
+		if d.Builds > max {
+			max = d.Builds
+		}
+	}
+	if max == 0 {
+		return 0
+	}
+	return float64(builds) / float64(max)
+}
+
+// rollupFilter reads the folder, env and controller query parameters.
+func rollupFilter(c *gin.Context, dr DateRange) db.RollupFilter {
+	return db.RollupFilter{
+		From:       dr.From,
+		To:         dr.To,
+		Folder:     strings.Trim(c.Query("folder"), "/"),
+		Env:        c.Query("env"),
+		Controller: c.Query("controller"),
+	}
+}
+
+func (h *Handler) loadTrendReport(f db.RollupFilter) (*trendReport, error) {
+	var (
+		r   trendReport
+		err error
+	)
+	if r.Days, err = h.DB.DailyRollups(f); err != nil {
+		return nil, err
+	}
+	if r.Folders, err = h.DB.FolderRollups(f); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GET /reports/trends - daily builds, failures and durations from the rollups
+func (h *Handler) RenderTrends(c *gin.Context) {
+	dr, rangeKey, err := resolveDateRange(c, "this_month")
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	f := rollupFilter(c, dr)
+
+	report, err := h.loadTrendReport(f)
+	if err != nil {
+		log.Printf("Trend report error: %v", err)
+		c.String(http.StatusInternalServerError, "Error: %v", err)
+		return
+	}
+
+	data := gin.H{
+		"Trends":     true,
+		"Report":     report,
+		"Folder":     f.Folder,
+		"Env":        f.Env,
+		"Controller": f.Controller,
+		"Range":      rangeKey,
+		"From":       dr.From,
+		"To":         dr.To,
+	}
+
+	if c.GetHeader("HX-Request") == "true" {
+		c.HTML(http.StatusOK, "build_trends", data)
+	} else {
+		c.HTML(http.StatusOK, "base", data)
+	}
+}
+
+// GET /api/v1/analytics/daily
+func (h *Handler) DailyRollupsV1(c *gin.Context) {
+	dr, _, err := resolveDateRange(c, "this_month")
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_range", err.Error())
+		return
+	}
+
+	report, err := h.loadTrendReport(rollupFilter(c, dr))
+	if err != nil {
+		log.Printf("[API] Trend report error: %v", err)
+		apiError(c, http.StatusInternalServerError, "internal", "failed to load daily rollups")
+		return
+	}
+	if report.Days == nil {
+		report.Days = []models.DailyRollup{}
+	}
+	if report.Folders == nil {
+		report.Folders = []models.FolderRollup{}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
@@ -0,0 +1,336 @@
+package api
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPISpec documents every /api/v1 route; keep it in sync with the handlers below.
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// buildLister is the part of *db.DB ListBuildsV1 reads; tests swap it out.
+type buildLister interface {
+	QueryBuilds(q db.BuildQuery) ([]models.Build, error)
+}
+
+func (h *Handler) buildSource() buildLister {
+	if h.builds != nil {
+		return h.builds
+	}
+	return h.DB
+}
+
+// errorEnvelope is the body of every non-2xx /api/v1 response.
+type errorEnvelope struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// page wraps list responses; NextCursor is empty on the last page.
+type page struct {
+	Data       interface{} `json:"data"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Limit      int         `json:"limit"`
+}
+
+// buildResource is the stable JSON representation of models.Build.
+type buildResource struct {
+	ID          int                `json:"id"`
+	Controller  string             `json:"controller"`
+	BuildNumber int                `json:"build_number"`
+	ProjectName string             `json:"project_name"`
+	ProjectPath string             `json:"project_path"`
+	UserID      string             `json:"user_id"`
+	Status      string             `json:"status"`
+	Timestamp   time.Time          `json:"timestamp"`
+	DurationMS  int64              `json:"duration_ms"`
+	JobURL      string             `json:"job_url"`
+	Branch      string             `json:"branch"`
+	GitRepo     string             `json:"git_url"`
+	CommitSHA   string             `json:"commit_sha"`
+	DeployEnv   string             `json:"deploy_env"`
+	TriggerType string             `json:"trigger_type"`
+	Env         string             `json:"env"`
+	IGRMNo      string             `json:"igrm_no"`
+	IGRMStatus  string             `json:"igrm_status,omitempty"`
+	IGRMDetail  string             `json:"igrm_detail,omitempty"`
+	Building    bool               `json:"building"`
+	QueueWaitMS int64              `json:"queue_wait_ms"`
+	BuiltOn     string             `json:"built_on"`
+	Parameters  models.BuildParams `json:"parameters"`
+	Causes      models.BuildCauses `json:"causes"`
+
+	// single build only
+	Changes []models.BuildChange `json:"changes,omitempty"`
+}
+
+type projectResource struct {
+	ProjectPath string    `json:"project_path"`
+	ProjectName string    `json:"project_name"`
+	BuildCount  int       `json:"build_count"`
+	LastBuildAt time.Time `json:"last_build_at"`
+}
+
+type folderResource struct {
+	Name       string           `json:"name"`
+	FullPath   string           `json:"full_path"`
+	Controller string           `json:"controller,omitempty"`
+	IsLeaf     bool             `json:"is_leaf"`
+	Children   []folderResource `json:"children"`
+}
+
+func newBuildResource(b models.Build) buildResource {
+	return buildResource{
+		ID:          b.ID,
+		Controller:  b.Controller,
+		BuildNumber: b.BuildNumber,
+		ProjectName: b.ProjectName,
+		ProjectPath: b.ProjectPath,
+		UserID:      b.UserID,
+		Status:      b.Status,
+		Timestamp:   b.Timestamp,
+		DurationMS:  b.DurationMS,
+		JobURL:      b.JobURL,
+		Branch:      b.Branch,
+		GitRepo:     b.GitRepo,
+		CommitSHA:   b.CommitSHA,
+		DeployEnv:   b.DeployEnv,
+		TriggerType: b.TriggerType,
+		Env:         b.Env,
+		IGRMNo:      b.IGRMNo,
+		IGRMStatus:  b.IGRMStatus,
+		IGRMDetail:  b.IGRMDetail,
+		Building:    b.Building,
+		QueueWaitMS: b.QueueWaitMS,
+		BuiltOn:     b.BuiltOn,
+		Parameters:  b.Parameters,
+		Causes:      b.Causes,
+	}
+}
+
+func newFolderResource(n *models.FolderNode) folderResource {
+	res := folderResource{
+		Name:       n.Name,
+		FullPath:   n.FullPath,
+		Controller: n.Controller,
+		IsLeaf:     n.IsLeaf,
+		Children:   []folderResource{},
+	}
+	names := make([]string, 0, len(n.Children))
+	for name := range n.Children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		res.Children = append(res.Children, newFolderResource(n.Children[name]))
+	}
+	return res
+}
+
+func apiError(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, errorEnvelope{Error: errorBody{Code: code, Message: message}})
+}
+
+// pageLimit reads ?limit=, clamped to [1, maxPageLimit].
+func pageLimit(c *gin.Context) (int, error) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("limit must be a positive integer")
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	return limit, nil
+}
+
+// encodeBuildCursor packs the keyset position of b into an opaque token.
+func encodeBuildCursor(b models.Build) string {
+	raw := fmt.Sprintf("%d:%d", b.Timestamp.UnixNano(), b.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeBuildCursor(cursor string) (time.Time, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return time.Time{}, 0, fmt.Errorf("malformed cursor")
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return time.Unix(0, nanos).UTC(), id, nil
+}
+
+// GET /api/v1/openapi.yaml
+func (h *Handler) OpenAPISpecV1(c *gin.Context) {
+	c.Data(http.StatusOK, "application/yaml", openAPISpec)
+}
+
+// GET /api/v1/builds - same filters as /builds/filter, paged by cursor in timestamp order.
+func (h *Handler) ListBuildsV1(c *gin.Context) {
+	q, _, err := buildQueryFromRequest(c)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_filter", err.Error())
+		return
+	}
+	limit, err := pageLimit(c)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_limit", err.Error())
+		return
+	}
+	q.SortBy = "timestamp"
+	q.Limit = limit + 1 // one extra row tells us whether another page exists
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		q.AfterTime, q.AfterID, err = decodeBuildCursor(cursor)
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "invalid_cursor", "cursor is malformed")
+			return
+		}
+	}
+
+	builds, err := h.buildSource().QueryBuilds(q)
+	if err != nil {
+		log.Printf("[API] QueryBuilds error query=%+v: %v", q, err)
+		apiError(c, http.StatusInternalServerError, "internal", "failed to fetch builds")
+		return
+	}
+
+	resp := page{Data: []buildResource{}, Limit: limit}
+	if len(builds) > limit {
+		builds = builds[:limit]
+		resp.NextCursor = encodeBuildCursor(builds[len(builds)-1])
+	}
+	data := make([]buildResource, 0, len(builds))
+	for _, b := range builds {
+		data = append(data, newBuildResource(b))
+	}
+	resp.Data = data
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GET /api/v1/builds/:id
+func (h *Handler) GetBuildV1(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_id", "build id must be an integer")
+		return
+	}
+
+	build, err := h.DB.GetBuildByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		apiError(c, http.StatusNotFound, "not_found", fmt.Sprintf("build %d not found", id))
+		return
+	}
+	if err != nil {
+		log.Printf("[API] GetBuildByID(%d) error: %v", id, err)
+		apiError(c, http.StatusInternalServerError, "internal", "failed to fetch build")
+		return
+	}
+
+	res := newBuildResource(*build)
+	if res.Changes, err = h.DB.GetBuildChanges(id); err != nil {
+		log.Printf("[API] GetBuildChanges(%d) error: %v", id, err)
+	}
+	c.JSON(http.StatusOK, gin.H{"data": res})
+}
+
+// GET /api/v1/projects - paged by cursor in project_path order.
+func (h *Handler) ListProjectsV1(c *gin.Context) {
+	limit, err := pageLimit(c)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_limit", err.Error())
+		return
+	}
+
+	after := ""
+	if cursor := c.Query("cursor"); cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "invalid_cursor", "cursor is malformed")
+			return
+		}
+		after = string(raw)
+	}
+
+	projects, err := h.DB.ListProjects(after, limit+1)
+	if err != nil {
+		log.Printf("[API] ListProjects error: %v", err)
+		apiError(c, http.StatusInternalServerError, "internal", "failed to fetch projects")
+		return
+	}
+
+	resp := page{Limit: limit}
+	if len(projects) > limit {
+		projects = projects[:limit]
+		last := projects[len(projects)-1].ProjectPath
+		resp.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(last))
+	}
+	data := make([]projectResource, 0, len(projects))
+	for _, p := range projects {
+		data = append(data, projectResource(p))
+	}
+	resp.Data = data
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GET /api/v1/folders/*path - the GetBuildTree hierarchy rooted at path.
+func (h *Handler) GetFolderV1(c *gin.Context) {
+	path := strings.Trim(c.Param("path"), "/")
+
+	tree, err := h.DB.GetBuildTree()
+	if err != nil {
+		log.Printf("[API] GetBuildTree error: %v", err)
+		apiError(c, http.StatusInternalServerError, "internal", "failed to build folder tree")
+		return
+	}
+
+	node := tree
+	if path != "" {
+		for _, part := range strings.Split(path, "/") {
+			child, ok := node.Children[part]
+			if !ok {
+				apiError(c, http.StatusNotFound, "not_found", fmt.Sprintf("folder %q not found", path))
+				return
+			}
+			node = child
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": newFolderResource(node)})
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeBuilds serves QueryBuilds from memory, newest first, honouring the keyset cursor.
+type fakeBuilds struct {
+	builds []models.Build
+	err    error
+	last   db.BuildQuery
+}
+
+func (f *fakeBuilds) QueryBuilds(q db.BuildQuery) ([]models.Build, error) {
+	f.last = q
+	if f.err != nil {
+		return nil, f.err
+	}
+	var out []models.Build
+	for _, b := range f.builds {
+		if q.AfterID > 0 && !(b.Timestamp.Before(q.AfterTime) || b.Timestamp.Equal(q.AfterTime) && b.ID < q.AfterID) {
+			continue
+		}
+		if len(out) < q.Limit {
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+func getV1(t *testing.T, h *Handler, target string) (int, map[string]json.RawMessage) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/v1/builds", h.ListBuildsV1)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s: invalid JSON %q: %v", target, w.Body.String(), err)
+	}
+	return w.Code, body
+}
+
+func TestBuildCursor(t *testing.T) {
+	b := models.Build{ID: 42, Timestamp: time.Date(2025, 3, 5, 10, 0, 0, 123456789, time.UTC)}
+	at, id, err := decodeBuildCursor(encodeBuildCursor(b))
+	if err != nil || id != 42 || !at.Equal(b.Timestamp) {
+		t.Errorf("round trip: got %s, %d, %v", at, id, err)
+	}
+	for _, bad := range []string{"!!", "bm9jb2xvbg", "MTIzOmFiYw", "YWJjOjE"} { // not base64, "nocolon", "123:abc", "abc:1"
+		if _, _, err := decodeBuildCursor(bad); err == nil {
+			t.Errorf("decodeBuildCursor(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestListBuildsV1(t *testing.T) {
+	start := time.Date(2025, 3, 5, 10, 0, 0, 0, time.UTC)
+	fake := &fakeBuilds{}
+	for id := 5; id >= 1; id-- {
+		fake.builds = append(fake.builds, models.Build{ID: id, ProjectPath: "team/app", Timestamp: start.Add(time.Duration(id) * time.Minute)})
+	}
+	h := &Handler{builds: fake}
+
+	var ids []int
+	target := "/api/v1/builds?limit=2"
+	for pages := 0; target != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("paging did not end")
+		}
+		code, body := getV1(t, h, target)
+		if code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", target, code)
+		}
+		var data []buildResource
+		var next string
+		var limit int
+		json.Unmarshal(body["data"], &data)
+		json.Unmarshal(body["limit"], &limit)
+		json.Unmarshal(body["next_cursor"], &next)
+		if limit != 2 || fake.last.Limit != 3 || fake.last.SortBy != "timestamp" {
+			t.Errorf("%s: limit %d, queried %d rows sorted by %q", target, limit, fake.last.Limit, fake.last.SortBy)
+		}
+		for _, b := range data {
+			ids = append(ids, b.ID)
+		}
+		target = ""
+		if next != "" {
+			target = "/api/v1/builds?limit=2&cursor=" + next
+		}
+	}
+	if len(ids) != 5 || ids[0] != 5 || ids[4] != 1 {
+		t.Errorf("expected builds 5..1 across pages, got %v", ids)
+	}
+
+	getV1(t, h, "/api/v1/builds")
+	if fake.last.Limit != defaultPageLimit+1 {
+		t.Errorf("default limit: queried %d rows", fake.last.Limit)
+	}
+	getV1(t, h, "/api/v1/builds?limit=100000")
+	if fake.last.Limit != maxPageLimit+1 {
+		t.Errorf("limit above the maximum: queried %d rows", fake.last.Limit)
+	}
+	getV1(t, h, "/api/v1/builds?range=this_week&project=team/app")
+	if fake.last.ProjectPath != "team/app" || fake.last.From.IsZero() {
+		t.Errorf("range and project must both filter, got %+v", fake.last)
+	}
+}
+
+func TestListBuildsV1Errors(t *testing.T) {
+	cases := []struct {
+		target string
+		err    error
+		status int
+		code   string
+	}{
+		{"/api/v1/builds?limit=0", nil, http.StatusBadRequest, "invalid_limit"},
+		{"/api/v1/builds?limit=ten", nil, http.StatusBadRequest, "invalid_limit"},
+		{"/api/v1/builds?cursor=%21%21", nil, http.StatusBadRequest, "invalid_cursor"},
+		{"/api/v1/builds?search_by=param&search_term=VERSION", nil, http.StatusBadRequest, "invalid_filter"},
+		{"/api/v1/builds", errors.New("connection refused"), http.StatusInternalServerError, "internal"},
+	}
+	for _, tc := range cases {
+		status, body := getV1(t, &Handler{builds: &fakeBuilds{err: tc.err}}, tc.target)
+		var env errorBody
+		if raw, ok := body["error"]; ok {
+			json.Unmarshal(raw, &env)
+		}
+		if status != tc.status || env.Code != tc.code || env.Message == "" {
+			t.Errorf("%s: got %d %+v, want %d %s", tc.target, status, env, tc.status, tc.code)
+		}
+		if _, leaked := body["data"]; leaked {
+			t.Errorf("%s: error responses must not carry data", tc.target)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"crypto/subtle"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/jenkins"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBody caps the accepted payload size (Jenkins build JSON with many params stays well below).
+const maxWebhookBody = 1 << 20
+
+// webhookToken extracts the shared secret from the request: X-Webhook-Token or
+// "Authorization: Bearer <token>". It is never read from the query string, which
+// ends up in access and proxy logs.
+func webhookToken(c *gin.Context) string {
+	if t := c.GetHeader("X-Webhook-Token"); t != "" {
+		return t
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
+
+// POST /webhooks/jenkins - build start/completion events from Jenkins
+func (h *Handler) JenkinsWebhook(c *gin.Context) {
+	if h.WebhookToken == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "webhook endpoint is disabled, set WEBHOOK_TOKEN"})
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(webhookToken(c)), []byte(h.WebhookToken)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid webhook token"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "could not read body"})
+		return
+	}
+
+	// Payloads may carry only a relative build URL, so the controller is taken from
+	// ?controller= when several are configured, else matched on the absolute URL.
+	name := c.Query("controller")
+	controller := jenkins.ControllerFor(h.Controllers, name, "")
+	if name != "" && controller == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown controller " + name})
+		return
+	}
+	var baseURL string
+	if controller != nil {
+		baseURL = controller.BaseURL
+	} else if len(h.Controllers) == 1 {
+		baseURL = h.Controllers[0].BaseURL
+	}
+
+	build, phase, err := jenkins.ParseWebhookPayload(body, baseURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if controller == nil {
+		controller = jenkins.ControllerFor(h.Controllers, "", build.URL)
+	}
+	if controller == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "build url is not on a configured controller"})
+		return
+	}
+	if !controller.Owns(build.URL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "build url is not on controller " + controller.Name})
+		return
+	}
+
+	// QUEUED events carry no build number worth storing yet
+	if phase == "QUEUED" {
+		c.JSON(http.StatusAccepted, gin.H{"status": "ignored", "phase": phase})
+		return
+	}
+
+	stored, err := jenkins.StoreWebhookBuild(h.DB, controller, build)
+	if err != nil {
+		log.Printf("[Webhook] store failed for %s #%d: %v", build.URL, build.Number, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store build"})
+		return
+	}
+
+	log.Printf("[Webhook] %s %s #%d status=%q", phase, stored.ProjectPath, stored.BuildNumber, stored.Status)
+	c.JSON(http.StatusOK, gin.H{"status": "stored", "phase": phase, "id": stored.ID})
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/jenkins"
+	"github.com/gin-gonic/gin"
+)
+
+func TestJenkinsWebhookAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller := &jenkins.JenkinsClient{Name: "main", BaseURL: "http://jenkins.local"}
+
+	foreign := `{"number": 1, "url": "http://attacker.test/job/app/1/", "result": "SUCCESS"}`
+	cases := []struct {
+		name    string
+		token   string // configured secret
+		target  string
+		headers map[string]string
+		body    string
+		want    int
+	}{
+		{"disabled", "", "/webhooks/jenkins", nil, foreign, http.StatusServiceUnavailable},
+		{"missing token", "s3cret", "/webhooks/jenkins", nil, foreign, http.StatusUnauthorized},
+		{"wrong token", "s3cret", "/webhooks/jenkins", map[string]string{"X-Webhook-Token": "guess"}, foreign, http.StatusUnauthorized},
+		{"query token", "s3cret", "/webhooks/jenkins?token=s3cret", nil, foreign, http.StatusUnauthorized},
+		{"header token", "s3cret", "/webhooks/jenkins", map[string]string{"X-Webhook-Token": "s3cret"}, `{}`, http.StatusBadRequest},
+		{"bearer token", "s3cret", "/webhooks/jenkins", map[string]string{"Authorization": "Bearer s3cret"}, `{}`, http.StatusBadRequest},
+		{"foreign url", "s3cret", "/webhooks/jenkins", map[string]string{"X-Webhook-Token": "s3cret"}, foreign, http.StatusBadRequest},
+		{"foreign url by name", "s3cret", "/webhooks/jenkins?controller=main", map[string]string{"X-Webhook-Token": "s3cret"}, foreign, http.StatusBadRequest},
+		{"unknown controller", "s3cret", "/webhooks/jenkins?controller=other", map[string]string{"X-Webhook-Token": "s3cret"}, foreign, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		h := &Handler{WebhookToken: tc.token, Controllers: []*jenkins.JenkinsClient{controller}}
+		r := gin.New()
+		r.POST("/webhooks/jenkins", h.JenkinsWebhook)
+
+		req := httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(tc.body))
+		for k, v := range tc.headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d (%s)", tc.name, tc.want, w.Code, w.Body.String())
+		}
+	}
+}
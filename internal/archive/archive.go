@@ -0,0 +1,238 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+)
+
+// Format of archive files: one row of the manifest's table per line as a JSON
+// object, gzipped. Builds and each of their child tables get a file of their own.
+const Format = "ndjson.gz"
+
+// Manifest describes one archive file; it is stored next to it as
+// <name>.manifest.json.
+type Manifest struct {
+	File      string    `json:"file"`
+	Format    string    `json:"format"`
+	Table     string    `json:"table"`  // builds or one of db.ArchivedChildTables
+	Scope     string    `json:"scope"`  // partition or retention policy the rows expired under
+	Reason    string    `json:"reason"` // e.g. "drop partition"
+	From      time.Time `json:"from,omitempty"`
+	To        time.Time `json:"to"` // rows started before To
+	Rows      int       `json:"rows"`
+	Bytes     int64     `json:"bytes"`
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ManifestName is the manifest file of an archive file.
+func ManifestName(file string) string {
+	return strings.TrimSuffix(file, "."+Format) + ".manifest.json"
+}
+
+// ExportFunc streams rows to emit, e.g. db.ExportPartition.
+type ExportFunc func(emit func(id int, row []byte) error) error
+
+// Write exports rows into a date-stamped archive file plus its manifest and
+// returns the manifest and the ids of the archived builds. Nothing is stored
+// when export yields no rows.
+func Write(store Store, m Manifest, export ExportFunc) (*Manifest, []int, error) {
+	tmp, err := os.CreateTemp("", "build-archive-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	gz := gzip.NewWriter(io.MultiWriter(tmp, hash))
+	w := bufio.NewWriter(gz)
+
+	var ids []int
+	err = export(func(id int, row []byte) error {
+		ids = append(ids, id)
+		if _, err := w.Write(row); err != nil {
+			return err
+		}
+		return w.WriteByte('\n')
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(ids) == 0 {
+		return &m, nil, nil
+	}
+	if err := w.Flush(); err != nil {
+		return nil, nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	m.CreatedAt = time.Now().UTC()
+	m.Format = Format
+	if m.Table == "" {
+		m.Table = "builds"
+	}
+	m.File = fmt.Sprintf("%s/%s-%s.%s", m.CreatedAt.Format("2006/01/02"), m.Table, fileSafe(m.Scope)+"-"+m.CreatedAt.Format("20060102T150405Z"), Format)
+	m.Rows = len(ids)
+	m.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	if m.Bytes, err = tmp.Seek(0, io.SeekCurrent); err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	if err := store.Put(m.File, tmp, m.Bytes, m.SHA256); err != nil {
+		return nil, nil, err
+	}
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := store.Put(ManifestName(m.File), strings.NewReader(string(manifest)), int64(len(manifest)), sha256Hex(manifest)); err != nil {
+		return nil, nil, err
+	}
+	log.Printf("[Archive] %d %s rows of %s archived to %s/%s", m.Rows, m.Table, m.Scope, store, m.File)
+	return &m, ids, nil
+}
+
+// WriteBuildChildren archives the logs, stages, test results and changes of
+// the builds in ids, one file per table under the scope of m, and returns the
+// files written. Call it after the builds themselves are archived and before
+// they are deleted.
+func WriteBuildChildren(database *db.DB, store Store, m Manifest, ids []int) ([]string, error) {
+	var files []string
+	for _, table := range db.ArchivedChildTables {
+		m.Table = table
+		written, _, err := Write(store, m, func(emit func(int, []byte) error) error {
+			return database.ExportBuildChildren(table, ids, emit)
+		})
+		if err != nil {
+			return files, fmt.Errorf("archive %s: %w", table, err)
+		}
+		if written.File != "" {
+			files = append(files, written.File)
+		}
+	}
+	return files, nil
+}
+
+var unsafeChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+func fileSafe(s string) string {
+	return strings.Trim(unsafeChars.ReplaceAllString(s, "_"), "_")
+}
+
+// RestoreTable is the default scratch table of an archive file.
+func RestoreTable(file string) string {
+	name := file[strings.LastIndex(file, "/")+1:]
+	name = strings.ToLower(fileSafe(strings.TrimSuffix(name, "."+Format)))
+	name = strings.NewReplacer(".", "_", "-", "_").Replace(name)
+	name = "restore_" + name
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
+
+// tableOf is the table an archive file holds: the manifest's, else the
+// <table>- prefix of the file name, else builds.
+func tableOf(file string, m *Manifest) string {
+	if m != nil && m.Table != "" {
+		return m.Table
+	}
+	name := file[strings.LastIndex(file, "/")+1:]
+	prefix, _, _ := strings.Cut(name, "-")
+	for _, table := range db.ArchivedChildTables {
+		if prefix == table {
+			return table
+		}
+	}
+	return "builds"
+}
+
+const restoreBatch = 500
+
+// Restore loads an archive file into a new scratch table shaped like the table
+// it was exported from and returns the number of rows loaded. The checksum in
+// the manifest, when present, is verified.
+func Restore(database *db.DB, store Store, file, table string) (int, error) {
+	var m *Manifest
+	if r, err := store.Open(ManifestName(file)); err == nil {
+		m = &Manifest{}
+		err = json.NewDecoder(r).Decode(m)
+		r.Close()
+		if err != nil {
+			return 0, fmt.Errorf("invalid manifest of %s: %w", file, err)
+		}
+	} else {
+		log.Printf("[Archive] no manifest for %s, checksum not verified: %v", file, err)
+	}
+
+	r, err := store.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	hash := sha256.New()
+	gz, err := gzip.NewReader(io.TeeReader(r, hash))
+	if err != nil {
+		return 0, fmt.Errorf("read %s: %w", file, err)
+	}
+
+	if err := database.CreateRestoreTable(table, tableOf(file, m)); err != nil {
+		return 0, err
+	}
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 1<<20), 64<<20)
+	var batch [][]byte
+	restored := 0
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		batch = append(batch, append([]byte(nil), line...))
+		if len(batch) == restoreBatch {
+			if err := database.InsertRestoredRows(table, batch); err != nil {
+				return restored, err
+			}
+			restored += len(batch)
+			batch = batch[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return restored, fmt.Errorf("read %s: %w", file, err)
+	}
+	if err := database.InsertRestoredRows(table, batch); err != nil {
+		return restored, err
+	}
+	restored += len(batch)
+
+	// drain the trailer so the hash covers the whole file
+	if _, err := io.Copy(io.Discard, r); err != nil && !errors.Is(err, io.EOF) {
+		return restored, err
+	}
+	if m != nil && m.SHA256 != hex.EncodeToString(hash.Sum(nil)) {
+		return restored, fmt.Errorf("%s does not match the checksum in its manifest; rows in %s are unverified", file, table)
+	}
+	return restored, nil
+}
@@ -0,0 +1,152 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteToDir(t *testing.T) {
+	store := &DirStore{Root: t.TempDir()}
+	rows := []string{`{"id":1,"env":"PROD_AND_DR"}`, `{"id":2,"env":"PROD_AND_DR"}`}
+
+	m, ids, err := Write(store, Manifest{Scope: "prod-deploys", Reason: "retention policy", To: time.Now()},
+		func(emit func(int, []byte) error) error {
+			for i, r := range rows {
+				if err := emit(i+1, []byte(r)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || m.Rows != 2 {
+		t.Fatalf("archived ids %v, manifest rows %d; want 2", ids, m.Rows)
+	}
+	if !strings.HasSuffix(m.File, ".ndjson.gz") || !strings.Contains(m.File, "prod-deploys") {
+		t.Errorf("unexpected archive name %q", m.File)
+	}
+
+	f, err := store.Open(m.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(f)
+	f.Close()
+	sum := sha256.Sum256(raw)
+	if hex.EncodeToString(sum[:]) != m.SHA256 || int64(len(raw)) != m.Bytes {
+		t.Errorf("manifest checksum/size does not match the file")
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(gz)
+	if got := strings.TrimSpace(string(body)); got != strings.Join(rows, "\n") {
+		t.Errorf("archive content = %q", got)
+	}
+
+	mf, err := store.Open(ManifestName(m.File))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mf.Close()
+	var stored Manifest
+	if err := json.NewDecoder(mf).Decode(&stored); err != nil || stored.SHA256 != m.SHA256 {
+		t.Errorf("stored manifest = %+v, %v", stored, err)
+	}
+}
+
+func TestWriteNothing(t *testing.T) {
+	store := &DirStore{Root: t.TempDir()}
+	m, ids, err := Write(store, Manifest{Scope: "dev"}, func(func(int, []byte) error) error { return nil })
+	if err != nil || len(ids) != 0 || m.File != "" {
+		t.Errorf("empty export: manifest %+v, ids %v, err %v", m, ids, err)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	objects := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+			http.Error(w, "bad signature "+auth, http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			sum := sha256.Sum256(body)
+			if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+				http.Error(w, "payload hash mismatch", http.StatusBadRequest)
+				return
+			}
+			objects[r.URL.Path] = body
+		case http.MethodGet:
+			body, ok := objects[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(body)
+		}
+	}))
+	defer srv.Close()
+
+	store := &S3Store{Endpoint: srv.URL, Bucket: "audit", Prefix: "ci", Region: "eu-west-1", AccessKey: "AKID", SecretKey: "secret"}
+	data := []byte("hello")
+	if err := store.Put("2025/06/01/a b.json", bytes.NewReader(data), int64(len(data)), sha256Hex(data)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := objects["/audit/ci/2025/06/01/a b.json"]; !ok {
+		t.Fatalf("object stored under %v", objects)
+	}
+
+	r, err := store.Open("2025/06/01/a b.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if got, _ := io.ReadAll(r); string(got) != "hello" {
+		t.Errorf("read back %q", got)
+	}
+	if _, err := store.Open("missing"); err == nil {
+		t.Error("expected an error for a missing object")
+	}
+}
+
+func TestRestoreTable(t *testing.T) {
+	got := RestoreTable("2025/06/01/builds-prod-deploys-20250601T030000Z.ndjson.gz")
+	if got != "restore_builds_prod_deploys_20250601t030000z" {
+		t.Errorf("RestoreTable = %q", got)
+	}
+}
+
+func TestTableOf(t *testing.T) {
+	cases := []struct {
+		file string
+		m    *Manifest
+		want string
+	}{
+		{"2025/06/01/builds-prod-deploys-20250601T030000Z.ndjson.gz", nil, "builds"},
+		{"2025/06/01/build_logs-builds_2018_05-20250601T030000Z.ndjson.gz", nil, "build_logs"},
+		{"2025/06/01/test_cases-dev-20250601T030000Z.ndjson.gz", nil, "test_cases"},
+		{"elsewhere.ndjson.gz", &Manifest{Table: "build_changes"}, "build_changes"},
+		{"unknown-dev.ndjson.gz", nil, "builds"},
+	}
+	for _, c := range cases {
+		if got := tableOf(c.file, c.m); got != c.want {
+			t.Errorf("tableOf(%q) = %q, want %q", c.file, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/config"
+)
+
+// Store keeps archive files, on local disk or in an S3-compatible bucket.
+// Names are slash-separated paths relative to the store root.
+type Store interface {
+	// Put stores size bytes from body; sha256Hex is the hex SHA-256 of body.
+	Put(name string, body io.Reader, size int64, sha256Hex string) error
+	Open(name string) (io.ReadCloser, error)
+	String() string
+}
+
+// NewStore returns the store configured by ARCHIVE_*, or nil when archiving
+// is not configured.
+func NewStore(cfg config.ArchiveConfig) (Store, error) {
+	switch {
+	case cfg.S3Bucket != "":
+		if cfg.S3Endpoint == "" || cfg.S3AccessKey == "" || cfg.S3SecretKey == "" {
+			return nil, fmt.Errorf("archive bucket %s needs an endpoint, access key and secret key", cfg.S3Bucket)
+		}
+		return &S3Store{
+			Endpoint:  strings.TrimSuffix(cfg.S3Endpoint, "/"),
+			Bucket:    cfg.S3Bucket,
+			Prefix:    strings.Trim(cfg.S3Prefix, "/"),
+			Region:    cfg.S3Region,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+		}, nil
+	case cfg.Dir != "":
+		return &DirStore{Root: cfg.Dir}, nil
+	}
+	return nil, nil
+}
+
+// DirStore keeps archives below a local directory, e.g. a mounted volume.
+type DirStore struct {
+	Root string
+}
+
+func (s *DirStore) path(name string) string {
+	return filepath.Join(s.Root, filepath.FromSlash(name))
+}
+
+func (s *DirStore) Put(name string, body io.Reader, size int64, sha256Hex string) error {
+	dst := s.path(name)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	// write aside and rename, so a crash never leaves a partial archive
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+func (s *DirStore) Open(name string) (io.ReadCloser, error) {
+	return os.Open(s.path(name))
+}
+
+func (s *DirStore) String() string {
+	return s.Root
+}
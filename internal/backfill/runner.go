@@ -0,0 +1,318 @@
+package backfill
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// Task re-derives columns of stored builds. Bumping Version discards the
+// checkpoint of the previous version so every build is derived again.
+type Task struct {
+	Name        string
+	Version     int
+	Description string
+	Columns     []string // builds columns Derive produces, see db.UpdateBuildColumns
+	// Derive returns the new values of Columns for a build and whether they
+	// differ from what is stored.
+	Derive func(b models.Build) (values []string, changed bool)
+}
+
+// Options tune a single run.
+type Options struct {
+	BatchSize int           // builds per batch and per UPDATE
+	Pause     time.Duration // sleep between batches to keep load off the database
+	DryRun    bool          // count changes from the first build without writing or checkpointing
+	Restart   bool          // ignore the stored checkpoint and start from the first build
+	Since     time.Time     // only builds started on or after this; such a run is not checkpointed
+}
+
+// checkpointed reports whether a run covers every build and so keeps a checkpoint.
+func (o Options) checkpointed() bool {
+	return !o.DryRun && o.Since.IsZero()
+}
+
+// Progress is the state of a task, either live or from its checkpoint.
+type Progress struct {
+	Task      string    `json:"task"`
+	Version   int       `json:"version"`
+	AfterID   int       `json:"after_id"` // last build id processed
+	Scanned   int       `json:"scanned"`
+	Changed   int       `json:"changed"`
+	Done      bool      `json:"done"`
+	DryRun    bool      `json:"dry_run,omitempty"`
+	Running   bool      `json:"running,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+const defaultBatchSize = 1000
+
+// store is the part of *db.DB the runner reads and writes.
+type store interface {
+	GetBuildsAfter(afterID, limit int, since time.Time) ([]models.Build, error)
+	UpdateBuildColumns(columns []string, ids []int, values [][]string) error
+	GetSyncStatus(key string) (value string, ok bool, err error)
+	SetSyncStatus(key, value string) error
+}
+
+var _ store = (*db.DB)(nil)
+
+// Runner executes backfill tasks and tracks the progress of running ones.
+type Runner struct {
+	db    store
+	tasks map[string]Task
+
+	mu      sync.Mutex
+	running map[string]*Progress
+	last    map[string]Progress  // latest finished real run per task
+	dryRuns map[string]*Progress // latest finished dry run per task, kept apart from the checkpoint
+}
+
+// NewRunner registers tasks by name.
+func NewRunner(database *db.DB, tasks ...Task) *Runner {
+	return newRunner(database, tasks...)
+}
+
+func newRunner(database store, tasks ...Task) *Runner {
+	r := &Runner{db: database, tasks: make(map[string]Task), running: make(map[string]*Progress), last: make(map[string]Progress), dryRuns: make(map[string]*Progress)}
+	for _, t := range tasks {
+		r.tasks[t.Name] = t
+	}
+	return r
+}
+
+// Tasks lists the registered tasks by name.
+func (r *Runner) Tasks() []Task {
+	tasks := make([]Task, 0, len(r.tasks))
+	for _, t := range r.tasks {
+		tasks = append(tasks, t)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Name < tasks[j].Name })
+	return tasks
+}
+
+// checkpointKey is the sync_status key of a task version.
+func checkpointKey(t Task) string {
+	return fmt.Sprintf("backfill_task:%s:v%d", t.Name, t.Version)
+}
+
+// Checkpoint reads the stored progress of a task's current version; ok is
+// false when it has never run.
+func (r *Runner) Checkpoint(t Task) (p Progress, ok bool, err error) {
+	value, ok, err := r.db.GetSyncStatus(checkpointKey(t))
+	if err != nil || !ok {
+		return Progress{Task: t.Name, Version: t.Version}, false, err
+	}
+	if err := json.Unmarshal([]byte(value), &p); err != nil {
+		return Progress{Task: t.Name, Version: t.Version}, false, fmt.Errorf("invalid checkpoint of %s: %w", t.Name, err)
+	}
+	return p, true, nil
+}
+
+func (r *Runner) saveCheckpoint(t Task, p Progress) error {
+	value, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return r.db.SetSyncStatus(checkpointKey(t), string(value))
+}
+
+// Status returns the live progress of running tasks, the latest real run of
+// tasks finished since startup and the checkpoint of all others. Dry runs never
+// stand in for a checkpoint, see DryRuns.
+func (r *Runner) Status() ([]Progress, error) {
+	var all []Progress
+	for _, t := range r.Tasks() {
+		r.mu.Lock()
+		live, running := r.running[t.Name]
+		p, finished := r.last[t.Name]
+		if running {
+			p = *live
+		}
+		r.mu.Unlock()
+
+		if !running && !finished {
+			var err error
+			if p, _, err = r.Checkpoint(t); err != nil {
+				return nil, err
+			}
+		}
+		all = append(all, p)
+	}
+	return all, nil
+}
+
+// DryRuns returns the latest finished dry run of each task that had one.
+func (r *Runner) DryRuns() map[string]*Progress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	runs := make(map[string]*Progress, len(r.dryRuns))
+	for name, p := range r.dryRuns {
+		cp := *p
+		runs[name] = &cp
+	}
+	return runs
+}
+
+// Start runs a task in the background; only one run per task at a time.
+func (r *Runner) Start(name string, opts Options) error {
+	t, p, err := r.begin(name, opts)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := r.run(t, p, opts); err != nil {
+			log.Printf("[Backfill] %s v%d failed: %v", t.Name, t.Version, err)
+		}
+	}()
+	return nil
+}
+
+// Run executes a task to completion and returns its final progress.
+func (r *Runner) Run(name string, opts Options) (Progress, error) {
+	t, p, err := r.begin(name, opts)
+	if err != nil {
+		return Progress{}, err
+	}
+	err = r.run(t, p, opts)
+	return *p, err
+}
+
+// begin claims a task and loads the position it resumes from.
+func (r *Runner) begin(name string, opts Options) (Task, *Progress, error) {
+	t, ok := r.tasks[name]
+	if !ok {
+		return t, nil, fmt.Errorf("unknown backfill task %q", name)
+	}
+
+	p := &Progress{Task: t.Name, Version: t.Version}
+	if opts.checkpointed() && !opts.Restart {
+		cp, ok, err := r.Checkpoint(t)
+		if err != nil {
+			return t, nil, err
+		}
+		if ok {
+			p = &cp
+		}
+	}
+	if p.Done {
+		return t, nil, fmt.Errorf("backfill %s v%d is already done, restart it to run again", t.Name, t.Version)
+	}
+	p.DryRun = opts.DryRun
+	p.Running = true
+	p.Error = ""
+	p.StartedAt = time.Now().UTC()
+	p.UpdatedAt = p.StartedAt
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, busy := r.running[t.Name]; busy {
+		return t, nil, fmt.Errorf("backfill %s is already running", t.Name)
+	}
+	r.running[t.Name] = p
+	return t, p, nil
+}
+
+// run processes batches after p.AfterID, checkpointing after each one.
+func (r *Runner) run(t Task, p *Progress, opts Options) (err error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	defer func() {
+		r.mu.Lock()
+		p.Running = false
+		if err != nil {
+			p.Error = err.Error()
+		}
+		delete(r.running, t.Name)
+		if opts.DryRun {
+			cp := *p
+			r.dryRuns[t.Name] = &cp
+		} else if opts.checkpointed() {
+			r.last[t.Name] = *p
+		}
+		r.mu.Unlock()
+		if err != nil && opts.checkpointed() {
+			// keep the position so the next run resumes here
+			if cpErr := r.saveCheckpoint(t, *p); cpErr != nil {
+				log.Printf("[Backfill] %v", cpErr)
+			}
+		}
+	}()
+
+	mode := ""
+	if opts.DryRun {
+		mode = " (dry run)"
+	}
+	log.Printf("[Backfill] %s v%d starting after build id %d%s", t.Name, t.Version, p.AfterID, mode)
+
+	for {
+		builds, err := r.db.GetBuildsAfter(p.AfterID, batchSize, opts.Since)
+		if err != nil {
+			return err
+		}
+		if len(builds) == 0 {
+			break
+		}
+
+		var ids []int
+		var values [][]string
+		for _, b := range builds {
+			if vals, changed := t.Derive(b); changed {
+				ids = append(ids, b.ID)
+				values = append(values, vals)
+			}
+		}
+		if !opts.DryRun {
+			if err := r.db.UpdateBuildColumns(t.Columns, ids, values); err != nil {
+				return err
+			}
+		}
+
+		r.mu.Lock()
+		p.AfterID = builds[len(builds)-1].ID
+		p.Scanned += len(builds)
+		p.Changed += len(ids)
+		p.UpdatedAt = time.Now().UTC()
+		snapshot := *p
+		r.mu.Unlock()
+
+		if opts.checkpointed() {
+			if err := r.saveCheckpoint(t, snapshot); err != nil {
+				return err
+			}
+		}
+		log.Printf("[Backfill] %s v%d: scanned %d builds, %d changed (last id %d)%s", t.Name, t.Version, snapshot.Scanned, snapshot.Changed, snapshot.AfterID, mode)
+
+		if len(builds) < batchSize {
+			break
+		}
+		if opts.Pause > 0 {
+			time.Sleep(opts.Pause)
+		}
+	}
+
+	r.mu.Lock()
+	p.Done = true
+	p.Running = false
+	p.UpdatedAt = time.Now().UTC()
+	snapshot := *p
+	r.mu.Unlock()
+
+	if opts.checkpointed() {
+		if err := r.saveCheckpoint(t, snapshot); err != nil {
+			return err
+		}
+	}
+	log.Printf("[Backfill] %s v%d complete: %d of %d builds changed%s", t.Name, t.Version, snapshot.Changed, snapshot.Scanned, mode)
+	return nil
+}
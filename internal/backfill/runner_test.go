@@ -0,0 +1,143 @@
+package backfill
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// fakeStore keeps builds and sync_status in memory.
+type fakeStore struct {
+	builds   []models.Build // ordered by id
+	status   map[string]string
+	updated  map[int]string // build id -> new value
+	failFrom int            // UpdateBuildColumns fails for batches with ids from here on
+}
+
+func (s *fakeStore) GetBuildsAfter(afterID, limit int, since time.Time) ([]models.Build, error) {
+	var out []models.Build
+	for _, b := range s.builds {
+		if b.ID > afterID && !b.Timestamp.Before(since) && len(out) < limit {
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStore) UpdateBuildColumns(columns []string, ids []int, values [][]string) error {
+	for i, id := range ids {
+		if s.failFrom > 0 && id >= s.failFrom {
+			return errors.New("connection reset")
+		}
+		s.updated[id] = values[i][0]
+	}
+	return nil
+}
+
+func (s *fakeStore) GetSyncStatus(key string) (string, bool, error) {
+	v, ok := s.status[key]
+	return v, ok, nil
+}
+
+func (s *fakeStore) SetSyncStatus(key, value string) error {
+	s.status[key] = value
+	return nil
+}
+
+func TestRunnerCheckpoints(t *testing.T) {
+	s := &fakeStore{status: make(map[string]string), updated: make(map[int]string)}
+	for id := 1; id <= 10; id++ {
+		igrm := ""
+		if id%2 == 0 {
+			igrm = "IGRM1"
+		}
+		s.builds = append(s.builds, models.Build{ID: id, Parameters: map[string]string{"IGRM_NO": igrm}})
+	}
+	task := IGRMTask()
+	r := newRunner(s, task)
+
+	dry, err := r.Run(task.Name, Options{BatchSize: 3, DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if !dry.Done || dry.Scanned != 10 || dry.Changed != 5 {
+		t.Errorf("unexpected dry run progress %+v", dry)
+	}
+	if len(s.updated) != 0 || len(s.status) != 0 {
+		t.Fatalf("a dry run must not write, got updates %v and status %v", s.updated, s.status)
+	}
+
+	// the third batch (ids 7-9) fails, the checkpoint stays after id 6
+	s.failFrom = 7
+	p, err := r.Run(task.Name, Options{BatchSize: 3})
+	if err == nil || p.Error == "" {
+		t.Fatalf("expected the run to fail, got %+v", p)
+	}
+	cp, ok, err := r.Checkpoint(task)
+	if err != nil || !ok || cp.AfterID != 6 || cp.Done || cp.Changed != 3 {
+		t.Fatalf("unexpected checkpoint %+v (ok %v, %v)", cp, ok, err)
+	}
+
+	s.failFrom = 0
+	p, err = r.Run(task.Name, Options{BatchSize: 3})
+	if err != nil {
+		t.Fatalf("resumed run failed: %v", err)
+	}
+	if !p.Done || p.Scanned != 10 || p.Changed != 5 || len(s.updated) != 5 {
+		t.Errorf("expected the resumed run to finish the rest, got %+v with updates %v", p, s.updated)
+	}
+	if _, err := r.Run(task.Name, Options{BatchSize: 3}); err == nil || !strings.Contains(err.Error(), "already done") {
+		t.Errorf("expected a finished task to refuse another run, got %v", err)
+	}
+
+	p, err = r.Run(task.Name, Options{BatchSize: 3, Restart: true})
+	if err != nil || !p.Done || p.Scanned != 10 {
+		t.Errorf("expected a restart to scan every build again, got %+v (%v)", p, err)
+	}
+
+	task.Version++
+	if _, ok, _ := newRunner(s, task).Checkpoint(task); ok {
+		t.Error("a new task version must not reuse the previous checkpoint")
+	}
+}
+
+func TestRunnerResumeAfterDryRun(t *testing.T) {
+	s := &fakeStore{status: make(map[string]string), updated: make(map[int]string)}
+	for id := 1; id <= 6; id++ {
+		s.builds = append(s.builds, models.Build{ID: id, Parameters: map[string]string{"IGRM_NO": "IGRM1"}})
+	}
+	task := IGRMTask()
+	r := newRunner(s, task)
+
+	// a real run stops after the first batch
+	s.failFrom = 4
+	if _, err := r.Run(task.Name, Options{BatchSize: 3}); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+	if _, err := r.Run(task.Name, Options{BatchSize: 3, DryRun: true}); err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+
+	status, err := r.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := status[0]; p.DryRun || p.Done || p.AfterID != 3 {
+		t.Errorf("the dry run must not hide the checkpoint, got %+v", p)
+	}
+	if dry := r.DryRuns()[task.Name]; dry == nil || !dry.Done || dry.Scanned != 6 {
+		t.Errorf("expected the dry run to be kept apart, got %+v", dry)
+	}
+
+	s.failFrom = 0
+	p, err := r.Run(task.Name, Options{BatchSize: 3})
+	if err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	if !p.Done || p.Scanned != 6 || len(s.updated) != 6 {
+		t.Errorf("expected the run to resume after id 3, got %+v with updates %v", p, s.updated)
+	}
+}
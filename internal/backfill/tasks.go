@@ -0,0 +1,63 @@
+package backfill
+
+import (
+	"strings"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/envrules"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// DefaultTasks are the derived builds columns that can be backfilled.
+func DefaultTasks(rules *envrules.RuleSet) []Task {
+	return []Task{EnvTask(rules, nil), IGRMTask(), TriggerTypeTask()}
+}
+
+// EnvTask re-detects env and deploy_env with the environment rules, see
+// envrules.RelabelBuild. When report is set, every change is added to it.
+func EnvTask(rules *envrules.RuleSet, report *envrules.Report) Task {
+	return Task{
+		Name:        "env",
+		Version:     1,
+		Description: "env and deploy_env from the environment rules",
+		Columns:     []string{"env", "deploy_env"},
+		Derive: func(b models.Build) ([]string, bool) {
+			c, changed := envrules.RelabelBuild(rules, b)
+			if changed && report != nil {
+				report.Add(c)
+			}
+			return []string{c.NewEnv, c.NewDeployEnv}, changed
+		},
+	}
+}
+
+// IGRMTask fills igrm_no from the IGRM_NO parameter.
+func IGRMTask() Task {
+	return Task{
+		Name:        "igrm_no",
+		Version:     1,
+		Description: "igrm_no from the IGRM_NO build parameter",
+		Columns:     []string{"igrm_no"},
+		Derive: func(b models.Build) ([]string, bool) {
+			igrm := strings.TrimSpace(b.Parameters["IGRM_NO"])
+			return []string{igrm}, igrm != "" && igrm != b.IGRMNo
+		},
+	}
+}
+
+// TriggerTypeTask fills trigger_type from the first stored build cause.
+func TriggerTypeTask() Task {
+	return Task{
+		Name:        "trigger_type",
+		Version:     1,
+		Description: "trigger_type from the stored build causes",
+		Columns:     []string{"trigger_type"},
+		Derive: func(b models.Build) ([]string, bool) {
+			for _, c := range b.Causes {
+				if c.ShortDescription != "" {
+					return []string{c.ShortDescription}, c.ShortDescription != b.TriggerType
+				}
+			}
+			return nil, false
+		},
+	}
+}
@@ -0,0 +1,138 @@
+package classify
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Unclassified is the category of failed builds no rule matched.
+const Unclassified = "unclassified"
+
+// Rule maps a console log regex onto a failure category.
+type Rule struct {
+	Name     string `yaml:"name"`
+	Category string `yaml:"category"`
+	Pattern  string `yaml:"pattern"`
+
+	re *regexp.Regexp
+}
+
+type ruleFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Classifier holds an ordered rule set loaded from a YAML file and can
+// reload it at runtime; it is safe for concurrent use.
+type Classifier struct {
+	path string
+
+	mu      sync.RWMutex
+	rules   []Rule
+	modTime time.Time
+}
+
+// NewClassifier loads the rules in path.
+func NewClassifier(path string) (*Classifier, error) {
+	c := &Classifier{path: path}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ParseRules decodes and compiles a rules document.
+func ParseRules(data []byte) ([]Rule, error) {
+	var f ruleFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid rules file: %w", err)
+	}
+	for i := range f.Rules {
+		r := &f.Rules[i]
+		if r.Category == "" || r.Pattern == "" {
+			return nil, fmt.Errorf("rule %d (%s): category and pattern are required", i+1, r.Name)
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i+1, r.Name, err)
+		}
+		r.re = re
+		if r.Name == "" {
+			r.Name = r.Category
+		}
+	}
+	return f.Rules, nil
+}
+
+// Reload re-reads the rules file. On error the previous rules stay active.
+func (c *Classifier) Reload() error {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return fmt.Errorf("failure rules: %w", err)
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("failure rules: %w", err)
+	}
+	rules, err := ParseRules(data)
+	if err != nil {
+		return fmt.Errorf("failure rules %s: %w", c.path, err)
+	}
+
+	c.mu.Lock()
+	c.rules = rules
+	c.modTime = info.ModTime()
+	c.mu.Unlock()
+	return nil
+}
+
+// WatchFile reloads the rules whenever the file's modification time changes.
+func (c *Classifier) WatchFile(interval time.Duration) {
+	go func() {
+		for {
+			time.Sleep(interval)
+
+			info, err := os.Stat(c.path)
+			if err != nil {
+				continue
+			}
+			c.mu.RLock()
+			changed := !info.ModTime().Equal(c.modTime)
+			c.mu.RUnlock()
+			if !changed {
+				continue
+			}
+
+			if err := c.Reload(); err != nil {
+				log.Printf("[Classifier] reload failed, keeping previous rules: %v", err)
+				continue
+			}
+			log.Printf("[Classifier] reloaded %d rules from %s", len(c.Rules()), c.path)
+		}
+	}()
+}
+
+// Rules returns a copy of the active rules in evaluation order.
+func (c *Classifier) Rules() []Rule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Rule(nil), c.rules...)
+}
+
+// Classify returns the category and rule name of the first matching rule.
+func (c *Classifier) Classify(consoleText string) (category, rule string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, r := range c.rules {
+		if r.re.MatchString(consoleText) {
+			return r.Category, r.Name
+		}
+	}
+	return Unclassified, ""
+}
@@ -0,0 +1,45 @@
+package classify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassifyFirstMatchWins(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "config", "failure-rules.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := ParseRules(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Classifier{rules: rules}
+
+	cases := []struct {
+		log, category string
+	}{
+		{"[ERROR] Tests run: 12, Failures: 2\njava.lang.OutOfMemoryError: Java heap space", "oom"},
+		{"hudson.remoting.ChannelClosedException: Channel \"unknown\": Remote call on agent-3 failed", "agent_disconnected"},
+		{"ERROR: Could not find credentials entry with ID 'nexus-deploy'", "credentials_missing"},
+		{"npm ERR! code ETIMEDOUT", "artifact_repo_timeout"},
+		{"[ERROR] /src/App.java:[12,8] error: cannot find symbol", "compile_error"},
+		{"Tests run: 40, Failures: 3, Errors: 0, Skipped: 1", "test_failure"},
+		{"Finished: FAILURE", Unclassified},
+	}
+	for _, tc := range cases {
+		if got, _ := c.Classify(tc.log); got != tc.category {
+			t.Errorf("Classify(%q) = %q, want %q", tc.log, got, tc.category)
+		}
+	}
+}
+
+func TestParseRulesRejectsBadPattern(t *testing.T) {
+	if _, err := ParseRules([]byte("rules:\n  - category: x\n    pattern: '(unclosed'\n")); err == nil {
+		t.Error("expected error for invalid regex")
+	}
+	if _, err := ParseRules([]byte("rules:\n  - name: no-category\n    pattern: 'x'\n")); err == nil {
+		t.Error("expected error for missing category")
+	}
+}
@@ -0,0 +1,73 @@
+package classify
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"log"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// ClassifyPending assigns a category to failed builds that have a captured log
+// but no category yet. It returns the number of builds classified.
+func ClassifyPending(database *db.DB, c *Classifier, limit int) (int, error) {
+	logs, err := database.GetFailedBuildLogs(0, limit, true, time.Time{})
+	if err != nil {
+		return 0, err
+	}
+	return classifyAll(database, c, logs), nil
+}
+
+// Reclassify re-runs the current rules over every failed build since the given
+// time, in batches, overwriting earlier categories. Used after rules change.
+func Reclassify(database *db.DB, c *Classifier, since time.Time, batchSize int) (int, error) {
+	total, afterID := 0, 0
+	for {
+		logs, err := database.GetFailedBuildLogs(afterID, batchSize, false, since)
+		if err != nil {
+			return total, err
+		}
+		if len(logs) == 0 {
+			return total, nil
+		}
+
+		total += classifyAll(database, c, logs)
+		afterID = logs[len(logs)-1].BuildID
+		log.Printf("[Classifier] reclassified %d builds (last id %d)", total, afterID)
+	}
+}
+
+func classifyAll(database *db.DB, c *Classifier, logs []models.BuildLog) int {
+	n := 0
+	for _, l := range logs {
+		category, rule := c.Classify(logText(l))
+		if err := database.SetFailureCategory(l.BuildID, category, rule); err != nil {
+			log.Printf("[Classifier] update failed for build ID %d: %v", l.BuildID, err)
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+// logText prefers the full compressed log and falls back to head + tail. The
+// compressed copy of a truncated log stops early, so the tail (taken from the
+// real end of the log) is appended to it.
+func logText(l models.BuildLog) string {
+	if len(l.ConsoleLogGz) > 0 {
+		if zr, err := gzip.NewReader(bytes.NewReader(l.ConsoleLogGz)); err == nil {
+			full, err := io.ReadAll(zr)
+			zr.Close()
+			if err == nil && l.Truncated {
+				return string(full) + "\n" + l.ConsoleLogTail
+			}
+			if err == nil {
+				return string(full)
+			}
+		}
+	}
+	return l.ConsoleLogHead + "\n" + l.ConsoleLogTail
+}
@@ -0,0 +1,213 @@
+package compliance
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/internal/igrm"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// Finding is a deployment that breaks at least one IGRM rule.
+type Finding struct {
+	BuildID       int       `json:"build_id"`
+	Controller    string    `json:"controller"`
+	ProjectPath   string    `json:"project_path"`
+	BuildNumber   int       `json:"build_number"`
+	Status        string    `json:"status"`
+	Timestamp     time.Time `json:"timestamp"`
+	UserID        string    `json:"user_id"`
+	DeployEnv     string    `json:"deploy_env"`
+	IGRMNo        string    `json:"igrm_no"`
+	Missing       bool      `json:"missing"`
+	Malformed     bool      `json:"malformed"`
+	OutsideWindow bool      `json:"outside_window"`
+	ReusedBy      []string  `json:"reused_by,omitempty"`     // unrelated projects that used the same number
+	TicketStatus  string    `json:"ticket_status,omitempty"` // change-management check, empty until checked
+	TicketDetail  string    `json:"ticket_detail,omitempty"`
+}
+
+// TicketRejected reports whether the change-management system rejected the
+// deployment's ticket. Unchecked tickets and lookup errors are not findings.
+func (f Finding) TicketRejected() bool {
+	switch f.TicketStatus {
+	case igrm.StatusNotFound, igrm.StatusNotApproved, igrm.StatusOutsideWindow:
+		return true
+	}
+	return false
+}
+
+// Issues names the rules the deployment breaks.
+func (f Finding) Issues() []string {
+	var issues []string
+	if f.Missing {
+		issues = append(issues, "missing")
+	}
+	if f.Malformed {
+		issues = append(issues, "malformed")
+	}
+	if len(f.ReusedBy) > 0 {
+		issues = append(issues, "reused")
+	}
+	if f.OutsideWindow {
+		issues = append(issues, "outside window")
+	}
+	if f.TicketRejected() {
+		issues = append(issues, "ticket "+strings.ReplaceAll(f.TicketStatus, "_", " "))
+	}
+	return issues
+}
+
+// FolderMonth summarises the deployments of one folder in one month.
+type FolderMonth struct {
+	Month         time.Time `json:"month"`
+	Folder        string    `json:"folder"`
+	Deployments   int       `json:"deployments"`
+	Compliant     int       `json:"compliant"`
+	Missing       int       `json:"missing"`
+	Malformed     int       `json:"malformed"`
+	Reused        int       `json:"reused"`
+	OutsideWindow int       `json:"outside_window"`
+	Rejected      int       `json:"ticket_rejected"` // tickets rejected by the change-management system
+}
+
+// ComplianceRate is the share of compliant deployments, 0..1.
+func (m FolderMonth) ComplianceRate() float64 {
+	if m.Deployments == 0 {
+		return 1
+	}
+	return float64(m.Compliant) / float64(m.Deployments)
+}
+
+// Report is the IGRM compliance of the deployments in a date range.
+type Report struct {
+	From        time.Time     `json:"from"`
+	To          time.Time     `json:"to"`
+	Env         string        `json:"env"`
+	Deployments int           `json:"deployments"`
+	Compliant   int           `json:"compliant"`
+	Findings    []Finding     `json:"findings"`
+	Monthly     []FolderMonth `json:"monthly"`
+}
+
+// Load reads the deployments of the policy's env in [from, to] and checks them.
+func Load(database *db.DB, p *Policy, from, to time.Time) (*Report, error) {
+	builds, err := database.GetEnvDeployments(p.Env, p.DeploysOnly, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("load deployments: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var igrms []string
+	for _, b := range builds {
+		if n := Normalize(b.IGRMNo); n != "" && !seen[n] {
+			seen[n] = true
+			igrms = append(igrms, n)
+		}
+	}
+	usage, err := database.GetIGRMUsage(p.Env, igrms)
+	if err != nil {
+		return nil, fmt.Errorf("load igrm usage: %w", err)
+	}
+
+	report := Compute(builds, usage, p)
+	report.From, report.To = from, to
+	return &report, nil
+}
+
+// Compute checks each deployment against p. usage maps a normalized IGRM number
+// to every project path that used it, within and outside the report's range.
+// Build timestamps are read as stored, in the app's local wall clock.
+func Compute(builds []models.Build, usage map[string][]string, p *Policy) Report {
+	report := Report{Env: p.Env, Findings: []Finding{}, Monthly: []FolderMonth{}}
+	monthly := make(map[string]*FolderMonth)
+
+	for _, b := range builds {
+		number := Normalize(b.IGRMNo)
+		started := db.LocalWallClock(b.Timestamp)
+		f := Finding{
+			BuildID:       b.ID,
+			Controller:    b.Controller,
+			ProjectPath:   b.ProjectPath,
+			BuildNumber:   b.BuildNumber,
+			Status:        b.Status,
+			Timestamp:     started,
+			UserID:        b.UserID,
+			DeployEnv:     b.DeployEnv,
+			IGRMNo:        b.IGRMNo,
+			Missing:       number == "",
+			Malformed:     number != "" && !p.WellFormed(number),
+			OutsideWindow: !p.InWindow(started),
+			TicketStatus:  b.IGRMStatus,
+			TicketDetail:  b.IGRMDetail,
+		}
+		folder := folderOf(b.ProjectPath)
+		if number != "" {
+			for _, path := range usage[number] {
+				if folderOf(path) != folder {
+					f.ReusedBy = append(f.ReusedBy, path)
+				}
+			}
+		}
+
+		local := started.In(p.Location)
+		month := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, p.Location)
+		key := month.Format("2006-01") + "|" + folder
+		m, ok := monthly[key]
+		if !ok {
+			m = &FolderMonth{Month: month, Folder: folder}
+			monthly[key] = m
+		}
+		m.Deployments++
+		report.Deployments++
+
+		if len(f.Issues()) == 0 {
+			m.Compliant++
+			report.Compliant++
+			continue
+		}
+		if f.Missing {
+			m.Missing++
+		}
+		if f.Malformed {
+			m.Malformed++
+		}
+		if len(f.ReusedBy) > 0 {
+			m.Reused++
+		}
+		if f.OutsideWindow {
+			m.OutsideWindow++
+		}
+		if f.TicketRejected() {
+			m.Rejected++
+		}
+		report.Findings = append(report.Findings, f)
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return report.Findings[i].Timestamp.After(report.Findings[j].Timestamp)
+	})
+	for _, m := range monthly {
+		report.Monthly = append(report.Monthly, *m)
+	}
+	sort.Slice(report.Monthly, func(i, j int) bool {
+		a, b := report.Monthly[i], report.Monthly[j]
+		if !a.Month.Equal(b.Month) {
+			return a.Month.Before(b.Month)
+		}
+		return a.Folder < b.Folder
+	})
+	return report
+}
+
+// folderOf returns the parent folder of a project path; jobs in the same
+// folder are related and may share a change ticket.
+func folderOf(projectPath string) string {
+	if i := strings.LastIndex(projectPath, "/"); i > 0 {
+		return projectPath[:i]
+	}
+	return projectPath
+}
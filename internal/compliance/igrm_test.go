@@ -0,0 +1,130 @@
+package compliance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+const testPolicy = `
+format: '^IGRM[0-9]{6}$'
+timezone: UTC
+windows:
+  - name: evening
+    days: [tue, thu]
+    from: "19:00"
+    to: "23:00"
+  - name: overnight
+    days: [fri]
+    from: "22:00"
+    to: "04:00"
+`
+
+func TestInWindow(t *testing.T) {
+	p, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("ParsePolicy failed: %v", err)
+	}
+	if p.Env != "PROD_AND_DR" {
+		t.Errorf("default env = %q", p.Env)
+	}
+
+	// 2025-03-04 is a Tuesday
+	at := func(day, hour, min int) time.Time { return time.Date(2025, 3, day, hour, min, 0, 0, time.UTC) }
+	tests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"tuesday evening", at(4, 20, 30), true},
+		{"tuesday end is exclusive", at(4, 23, 0), false},
+		{"wednesday evening", at(5, 20, 30), false},
+		{"friday night", at(7, 23, 15), true},
+		{"saturday early morning", at(8, 3, 59), true},
+		{"saturday morning", at(8, 4, 0), false},
+		{"sunday early morning", at(9, 1, 0), false},
+	}
+	for _, tt := range tests {
+		if got := p.InWindow(tt.t); got != tt.want {
+			t.Errorf("%s: InWindow(%s) = %v, want %v", tt.name, tt.t, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{
+		`format: '('`,
+		`windows: [{from: "19:00", to: "23:00"}]`,
+		"format: x\nwindows: [{days: [funday], from: \"19:00\", to: \"23:00\"}]",
+		"format: x\nwindows: [{from: \"7pm\", to: \"23:00\"}]",
+	} {
+		if _, err := ParsePolicy([]byte(bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestCompute(t *testing.T) {
+	p, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("ParsePolicy failed: %v", err)
+	}
+	inWindow := time.Date(2025, 3, 4, 20, 0, 0, 0, time.UTC)
+	builds := []models.Build{
+		{ID: 1, ProjectPath: "PROD_AND_DR/pay/deploy", IGRMNo: "igrm123456 ", Timestamp: inWindow},
+		{ID: 2, ProjectPath: "PROD_AND_DR/pay/deploy-dr", IGRMNo: "IGRM123456", Timestamp: inWindow},
+		{ID: 3, ProjectPath: "PROD_AND_DR/pay/deploy", IGRMNo: "", Timestamp: inWindow},
+		{ID: 4, ProjectPath: "PROD_AND_DR/cards/deploy", IGRMNo: "IGRM-1", Timestamp: inWindow.AddDate(0, 1, 0)},
+		{ID: 5, ProjectPath: "PROD_AND_DR/cards/deploy", IGRMNo: "IGRM654321", Timestamp: inWindow.Add(-8 * time.Hour)},
+	}
+	usage := map[string][]string{
+		"IGRM123456": {"PROD_AND_DR/pay/deploy", "PROD_AND_DR/pay/deploy-dr"},
+		"IGRM654321": {"PROD_AND_DR/cards/deploy", "PROD_AND_DR/loans/deploy"},
+	}
+
+	r := Compute(builds, usage, p)
+	if r.Deployments != 5 || r.Compliant != 2 || len(r.Findings) != 3 {
+		t.Fatalf("unexpected totals: %d deployments, %d compliant, findings %+v", r.Deployments, r.Compliant, r.Findings)
+	}
+	byID := make(map[int]Finding)
+	for _, f := range r.Findings {
+		byID[f.BuildID] = f
+	}
+	if f := byID[3]; !f.Missing || f.Malformed {
+		t.Errorf("build 3 should only be missing: %+v", f)
+	}
+	if f := byID[4]; !f.Malformed || !f.OutsideWindow {
+		t.Errorf("build 4 should be malformed and outside the window: %+v", f)
+	}
+	if f := byID[5]; len(f.ReusedBy) != 1 || f.ReusedBy[0] != "PROD_AND_DR/loans/deploy" || !f.OutsideWindow {
+		t.Errorf("build 5 should be reused by loans and outside the window: %+v", f)
+	}
+	if r.Findings[0].BuildID != 4 {
+		t.Errorf("findings should be newest first, got %d", r.Findings[0].BuildID)
+	}
+
+	if len(r.Monthly) != 3 {
+		t.Fatalf("expected 3 month/folder groups, got %+v", r.Monthly)
+	}
+	if m := r.Monthly[1]; m.Folder != "PROD_AND_DR/pay" || m.Deployments != 3 || m.Compliant != 2 || m.Missing != 1 {
+		t.Errorf("unexpected pay summary: %+v", m)
+	}
+}
+
+func TestComputeLocalTimestamps(t *testing.T) {
+	p, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("ParsePolicy failed: %v", err)
+	}
+	defer func(loc *time.Location) { time.Local = loc }(time.Local)
+	time.Local = time.FixedZone("CET", 3600)
+
+	// stored as 19:30 app time, read back as 19:30 UTC, but started 18:30 UTC
+	stored := time.Date(2025, 3, 4, 19, 30, 0, 0, time.UTC)
+	r := Compute([]models.Build{{ID: 1, ProjectPath: "PROD_AND_DR/pay/deploy", IGRMNo: "IGRM123456", Timestamp: stored}}, nil, p)
+	if len(r.Findings) != 1 || !r.Findings[0].OutsideWindow {
+		t.Fatalf("deployment before the 19:00 UTC window should be outside it: %+v", r.Findings)
+	}
+	if got := r.Findings[0].Timestamp.UTC(); !got.Equal(time.Date(2025, 3, 4, 18, 30, 0, 0, time.UTC)) {
+		t.Errorf("finding timestamp = %v, want 18:30 UTC", got)
+	}
+}
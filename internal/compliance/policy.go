@@ -0,0 +1,149 @@
+package compliance
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Window is an approved change window, e.g. Tue and Thu 20:00-23:00. A window
+// whose end is before its start runs past midnight into the next day.
+type Window struct {
+	Name string   `yaml:"name"`
+	Days []string `yaml:"days"` // mon..sun, empty for every day
+	From string   `yaml:"from"` // HH:MM
+	To   string   `yaml:"to"`   // HH:MM, exclusive
+
+	days     [7]bool
+	from, to int // minutes after midnight
+}
+
+// Policy is a parsed IGRM compliance document.
+type Policy struct {
+	Env         string   `yaml:"env"`          // canonical env of the deployments checked
+	DeploysOnly bool     `yaml:"deploys_only"` // only builds with a deploy_env parameter
+	FormatText  string   `yaml:"format"`       // regex a well-formed IGRM number matches
+	Timezone    string   `yaml:"timezone"`     // zone the windows are written in
+	Windows     []Window `yaml:"windows"`      // empty disables the window check
+
+	Format   *regexp.Regexp `yaml:"-"`
+	Location *time.Location `yaml:"-"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParsePolicy decodes and validates an IGRM compliance document.
+func ParsePolicy(data []byte) (*Policy, error) {
+	p := &Policy{Env: "PROD_AND_DR", Timezone: "UTC"}
+	if err := yaml.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("invalid igrm policy file: %w", err)
+	}
+	if p.FormatText == "" {
+		return nil, fmt.Errorf("igrm policy: format is required")
+	}
+	var err error
+	if p.Format, err = regexp.Compile(p.FormatText); err != nil {
+		return nil, fmt.Errorf("igrm policy format: %w", err)
+	}
+	if p.Location, err = time.LoadLocation(p.Timezone); err != nil {
+		return nil, fmt.Errorf("igrm policy timezone: %w", err)
+	}
+
+	for i := range p.Windows {
+		w := &p.Windows[i]
+		if w.Name == "" {
+			w.Name = fmt.Sprintf("window-%d", i+1)
+		}
+		if err := w.Parse(); err != nil {
+			return nil, fmt.Errorf("igrm %w", err)
+		}
+	}
+	return p, nil
+}
+
+// LoadPolicy reads an IGRM compliance document from disk.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read igrm policy file: %w", err)
+	}
+	return ParsePolicy(data)
+}
+
+// Parse validates the days and times of the window.
+func (w *Window) Parse() error {
+	var err error
+	if w.from, err = parseClock(w.From); err != nil {
+		return fmt.Errorf("window %s from: %w", w.Name, err)
+	}
+	if w.to, err = parseClock(w.To); err != nil {
+		return fmt.Errorf("window %s to: %w", w.Name, err)
+	}
+	if w.from == w.to {
+		return fmt.Errorf("window %s is empty", w.Name)
+	}
+	w.days = [7]bool{}
+	if len(w.Days) == 0 {
+		w.days = [7]bool{true, true, true, true, true, true, true}
+	}
+	for _, d := range w.Days {
+		wd, ok := weekdays[strings.ToLower(d)[:min(3, len(d))]]
+		if !ok {
+			return fmt.Errorf("window %s: unknown day %q", w.Name, d)
+		}
+		w.days[wd] = true
+	}
+	return nil
+}
+
+// Contains reports whether local, a time in the window's zone, falls in the
+// window. An overnight window belongs to the day it starts on.
+func (w Window) Contains(local time.Time) bool {
+	day := local.Weekday()
+	prev := (day + 6) % 7
+	m := local.Hour()*60 + local.Minute()
+	if w.from < w.to {
+		return w.days[day] && m >= w.from && m < w.to
+	}
+	return (w.days[day] && m >= w.from) || (w.days[prev] && m < w.to)
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Normalize is the comparable form of an IGRM number.
+func Normalize(igrm string) string {
+	return strings.ToUpper(strings.TrimSpace(igrm))
+}
+
+// WellFormed reports whether igrm matches the configured format.
+func (p *Policy) WellFormed(igrm string) bool {
+	return p.Format.MatchString(Normalize(igrm))
+}
+
+// InWindow reports whether t falls in an approved change window. Without
+// windows every time is allowed.
+func (p *Policy) InWindow(t time.Time) bool {
+	if len(p.Windows) == 0 {
+		return true
+	}
+	local := t.In(p.Location)
+	for _, w := range p.Windows {
+		if w.Contains(local) {
+			return true
+		}
+	}
+	return false
+}
@@ -5,13 +5,51 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// RetentionConfig controls age-based retention of builds.
 type RetentionConfig struct {
-    MaxRecords     int  // e.g. 280000
-    DeleteMultiple int  // e.g. 50
-    CleanupEnabled  bool
+	Enabled         bool
+	PoliciesFile    string // per env/folder retention ages
+	PartitionAction string // "drop" or "detach" monthly partitions past every policy
+	DeleteBatch     int    // builds deleted per statement under shorter policies
+	MonthsAhead     int    // monthly partitions created in advance
 }
+// LogConfig controls console log capture.
+type LogConfig struct {
+	Enabled   bool
+	HeadLines int           // lines kept uncompressed from the start of the log
+	TailLines int           // lines kept uncompressed from the end of the log
+	MaxBytes  int64         // full copy is cut off beyond this size
+	BatchSize int           // builds fetched per collector run
+	Timeout   time.Duration // per console download, large logs take longer than API calls
+}
+
+// ArchiveConfig selects where builds are archived before retention removes
+// them: a local directory or an S3-compatible bucket. Empty disables archiving.
+type ArchiveConfig struct {
+	Dir         string
+	S3Endpoint  string
+	S3Bucket    string
+	S3Prefix    string
+	S3Region    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+// IGRMValidatorConfig selects the change-management system IGRM numbers are
+// checked against: an HTTP/JSON API or a CSV export. Empty disables the checks.
+type IGRMValidatorConfig struct {
+	URL      string // ServiceNow-style table API base URL
+	User     string
+	Token    string
+	CSVFile  string        // offline export: number,state,approval,start,end
+	CacheTTL time.Duration // how long a looked up ticket is reused
+	Batch    int           // builds checked per run
+}
+
 type EnvConfig struct {
 	DBUser       string
 	DBPass       string
@@ -24,6 +62,16 @@ type EnvConfig struct {
 	JenkinsToken string
 	JenkinsTLS 	 bool
 	DSN          string
+	WebhookToken string        // shared secret for /webhooks/jenkins
+	PollInterval time.Duration // reconciliation poll, webhooks deliver builds in between
+	FailureRulesFile string    // ordered regex rules for failure classification
+	ControllersFile  string    // YAML list of Jenkins controllers, replaces JENKINS_URL/USER/TOKEN
+	ParamColumns     []string  // build parameters promoted to dashboard/export columns
+	EnvRulesFile     string    // declarative env detection rules
+	AutoMigrate      bool      // apply pending schema migrations on startup
+	IGRMPolicyFile   string    // change-ticket format and approved change windows
+	AlertRulesFile   string    // alert rules and channels, empty disables alerting
+	ReportsFile      string    // emailed digests and their cron schedules, empty disables them
 }
 
 func LoadEnvConfig() *EnvConfig {
@@ -32,12 +80,26 @@ func LoadEnvConfig() *EnvConfig {
 		DBPass:       getOrExit("DB_PASS"),
 		DBName:       getOrExit("DB_NAME"),
 		DBHost:       getOrExit("DB_HOST"),
-		JenkinsURL:   getOrExit("JENKINS_URL"),
-		JenkinsUser:  getOrExit("JENKINS_USER"),
-		JenkinsToken: getOrExit("JENKINS_TOKEN"),
 		SSLMode:      getOrDefault("SSL_MODE_DB", "disable"),
 		JenkinsTLS:   os.Getenv("JENKINS_TLS_INSECURE") != "true",
-		DBPort:       getIntOrDefault("DB_PORT", 5432),		
+		DBPort:       getIntOrDefault("DB_PORT", 5432),
+		WebhookToken: os.Getenv("WEBHOOK_TOKEN"),
+		PollInterval: time.Duration(getIntOrDefault("POLL_INTERVAL_MINUTES", 30)) * time.Minute,
+		FailureRulesFile: getOrDefault("FAILURE_RULES_FILE", "config/failure-rules.yaml"),
+		ControllersFile:  os.Getenv("JENKINS_CONTROLLERS_FILE"),
+		ParamColumns:     getListOrDefault("PARAM_COLUMNS", nil),
+		EnvRulesFile:     getOrDefault("ENV_RULES_FILE", "config/env-rules.yaml"),
+		AutoMigrate:      os.Getenv("DB_AUTO_MIGRATE") != "false",
+		IGRMPolicyFile:   getOrDefault("IGRM_POLICY_FILE", "config/igrm-compliance.yaml"),
+		AlertRulesFile:   os.Getenv("ALERT_RULES_FILE"),
+		ReportsFile:      os.Getenv("REPORT_SCHEDULES_FILE"),
+	}
+
+	// A controllers file carries its own URLs and credentials
+	if cfg.ControllersFile == "" {
+		cfg.JenkinsURL = getOrExit("JENKINS_URL")
+		cfg.JenkinsUser = getOrExit("JENKINS_USER")
+		cfg.JenkinsToken = getOrExit("JENKINS_TOKEN")
 	}
 
 	cfg.DSN = fmt.Sprintf(
@@ -48,16 +110,52 @@ func LoadEnvConfig() *EnvConfig {
 	return cfg
 }
 
-// DataRetentionConfig reads env vars or falls back to defaults.
+// DataRetentionConfig reads RETENTION_* env vars or falls back to defaults.
 func DataRetentionConfig() RetentionConfig {
-    maxRecs := getIntOrDefault("MAX_BUILD_RECORDS", 350000)
-    multiple := getIntOrDefault("DELETE_MULTIPLE", 50)
-
-    return RetentionConfig{
-        MaxRecords:     maxRecs,
-        DeleteMultiple: multiple,
-        CleanupEnabled: true,
-    }
+	return RetentionConfig{
+		Enabled:         os.Getenv("RETENTION_ENABLED") != "false",
+		PoliciesFile:    getOrDefault("RETENTION_POLICIES_FILE", "config/retention.yaml"),
+		PartitionAction: getOrDefault("RETENTION_PARTITION_ACTION", "drop"),
+		DeleteBatch:     getIntOrDefault("RETENTION_DELETE_BATCH", 1000),
+		MonthsAhead:     getIntOrDefault("PARTITION_MONTHS_AHEAD", 3),
+	}
+}
+
+// BuildArchiveConfig reads ARCHIVE_* env vars.
+func BuildArchiveConfig() ArchiveConfig {
+	return ArchiveConfig{
+		Dir:         os.Getenv("ARCHIVE_DIR"),
+		S3Endpoint:  os.Getenv("ARCHIVE_S3_ENDPOINT"),
+		S3Bucket:    os.Getenv("ARCHIVE_S3_BUCKET"),
+		S3Prefix:    getOrDefault("ARCHIVE_S3_PREFIX", "jenkins-analytics"),
+		S3Region:    getOrDefault("ARCHIVE_S3_REGION", "us-east-1"),
+		S3AccessKey: getOrDefault("ARCHIVE_S3_ACCESS_KEY", os.Getenv("AWS_ACCESS_KEY_ID")),
+		S3SecretKey: getOrDefault("ARCHIVE_S3_SECRET_KEY", os.Getenv("AWS_SECRET_ACCESS_KEY")),
+	}
+}
+
+// IGRMValidatorSettings reads IGRM_VALIDATOR_* env vars or falls back to defaults.
+func IGRMValidatorSettings() IGRMValidatorConfig {
+	return IGRMValidatorConfig{
+		URL:      os.Getenv("IGRM_VALIDATOR_URL"),
+		User:     os.Getenv("IGRM_VALIDATOR_USER"),
+		Token:    os.Getenv("IGRM_VALIDATOR_TOKEN"),
+		CSVFile:  os.Getenv("IGRM_VALIDATOR_CSV"),
+		CacheTTL: time.Duration(getIntOrDefault("IGRM_VALIDATOR_CACHE_MINUTES", 60)) * time.Minute,
+		Batch:    getIntOrDefault("IGRM_VALIDATOR_BATCH", 200),
+	}
+}
+
+// ConsoleLogConfig reads LOG_* env vars or falls back to defaults.
+func ConsoleLogConfig() LogConfig {
+	return LogConfig{
+		Enabled:   os.Getenv("LOG_COLLECT_ENABLED") != "false",
+		HeadLines: getIntOrDefault("LOG_HEAD_LINES", 200),
+		TailLines: getIntOrDefault("LOG_TAIL_LINES", 500),
+		MaxBytes:  int64(getIntOrDefault("LOG_MAX_BYTES", 20<<20)),
+		BatchSize: getIntOrDefault("LOG_COLLECT_BATCH", 50),
+		Timeout:   time.Duration(getIntOrDefault("LOG_FETCH_TIMEOUT_SECONDS", 300)) * time.Second,
+	}
 }
 
 func getOrExit(key string) string {
@@ -88,3 +186,18 @@ func getIntOrDefault(envVar string, defaultVal int) int {
 	return val
 }
 
+
+// getListOrDefault splits a comma-separated env var, dropping empty entries.
+func getListOrDefault(key string, defaultVal []string) []string {
+	valStr := os.Getenv(key)
+	if valStr == "" {
+		return defaultVal
+	}
+	var vals []string
+	for _, v := range strings.Split(valStr, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			vals = append(vals, v)
+		}
+	}
+	return vals
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultController names the controller of a single JENKINS_URL setup and of
+// rows stored before multi-controller support.
+const DefaultController = "default"
+
+// Controller is one Jenkins instance builds are collected from.
+type Controller struct {
+	Name         string        `yaml:"name"`
+	URL          string        `yaml:"url"`
+	User         string        `yaml:"user"`
+	Token        string        `yaml:"token"`
+	TokenEnv     string        `yaml:"token_env"` // read the token from this env var instead
+	CACert       string        `yaml:"ca_cert"`
+	Insecure     bool          `yaml:"insecure"`
+	PollMinutes  int           `yaml:"poll_interval_minutes"`
+	PollInterval time.Duration `yaml:"-"`
+}
+
+type controllerFile struct {
+	Controllers []Controller `yaml:"controllers"`
+}
+
+// LoadControllers returns the controllers listed in JENKINS_CONTROLLERS_FILE, or a
+// single controller built from JENKINS_URL / JENKINS_USER / JENKINS_TOKEN.
+func LoadControllers(cfg *EnvConfig) ([]Controller, error) {
+	if cfg.ControllersFile == "" {
+		return []Controller{{
+			Name:         getOrDefault("JENKINS_CONTROLLER_NAME", DefaultController),
+			URL:          cfg.JenkinsURL,
+			User:         cfg.JenkinsUser,
+			Token:        cfg.JenkinsToken,
+			CACert:       os.Getenv("JENKINS_CACERT"),
+			Insecure:     !cfg.JenkinsTLS,
+			PollInterval: cfg.PollInterval,
+		}}, nil
+	}
+
+	data, err := os.ReadFile(cfg.ControllersFile)
+	if err != nil {
+		return nil, fmt.Errorf("controllers file: %w", err)
+	}
+	return ParseControllers(data, cfg.PollInterval)
+}
+
+// ParseControllers decodes and validates a controllers document. Controllers
+// without poll_interval_minutes use defaultPoll.
+func ParseControllers(data []byte, defaultPoll time.Duration) ([]Controller, error) {
+	var f controllerFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid controllers file: %w", err)
+	}
+	if len(f.Controllers) == 0 {
+		return nil, fmt.Errorf("controllers file lists no controllers")
+	}
+
+	seen := make(map[string]bool)
+	for i := range f.Controllers {
+		c := &f.Controllers[i]
+		c.Name = strings.TrimSpace(c.Name)
+		if c.Name == "" || c.URL == "" {
+			return nil, fmt.Errorf("controller %d: name and url are required", i+1)
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("controller %q is listed twice", c.Name)
+		}
+		seen[c.Name] = true
+
+		if c.TokenEnv != "" {
+			c.Token = os.Getenv(c.TokenEnv)
+		}
+		if c.User == "" || c.Token == "" {
+			return nil, fmt.Errorf("controller %q: user and token (or token_env) are required", c.Name)
+		}
+		c.PollInterval = defaultPoll
+		if c.PollMinutes > 0 {
+			c.PollInterval = time.Duration(c.PollMinutes) * time.Minute
+		}
+	}
+	return f.Controllers, nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseControllers(t *testing.T) {
+	t.Setenv("CI_TOKEN", "from-env")
+	doc := `
+controllers:
+  - name: " ci "
+    url: https://ci.example.com
+    user: bot
+    token_env: CI_TOKEN
+  - name: release
+    url: https://release.example.com
+    user: bot
+    token: secret
+    insecure: true
+    poll_interval_minutes: 15
+`
+	controllers, err := ParseControllers([]byte(doc), 5*time.Minute)
+	if err != nil {
+		t.Fatalf("ParseControllers failed: %v", err)
+	}
+	if len(controllers) != 2 {
+		t.Fatalf("expected 2 controllers, got %d", len(controllers))
+	}
+	ci, release := controllers[0], controllers[1]
+	if ci.Name != "ci" || ci.Token != "from-env" || ci.PollInterval != 5*time.Minute {
+		t.Errorf("unexpected first controller %+v", ci)
+	}
+	if release.Token != "secret" || !release.Insecure || release.PollInterval != 15*time.Minute {
+		t.Errorf("unexpected second controller %+v", release)
+	}
+
+	bad := map[string]string{
+		"not yaml":     "controllers: [",
+		"no entries":   "controllers: []",
+		"missing url":  "controllers:\n  - {name: a, user: u, token: t}",
+		"missing name": "controllers:\n  - {url: http://a, user: u, token: t}",
+		"duplicate":    "controllers:\n  - {name: a, url: http://a, user: u, token: t}\n  - {name: a, url: http://b, user: u, token: t}",
+		"no token":     "controllers:\n  - {name: a, url: http://a, user: u}",
+		"empty env":    "controllers:\n  - {name: a, url: http://a, user: u, token_env: UNSET_TOKEN_FOR_TEST}",
+	}
+	for name, doc := range bad {
+		if _, err := ParseControllers([]byte(doc), time.Minute); err == nil {
+			t.Errorf("%s: expected an error", name)
+		} else if name == "duplicate" && !strings.Contains(err.Error(), `"a"`) {
+			t.Errorf("duplicate: error should name the controller, got %v", err)
+		}
+	}
+}
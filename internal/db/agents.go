@@ -0,0 +1,187 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// builtOnWindow is how long after its start a build that is no longer running
+// can still be attributed to the agent an executor reports it on.
+const builtOnWindow = 48 * time.Hour
+
+// latestLabels selects the labels of every node from its most recent snapshot,
+// without the node's own name which Jenkins always adds as a label.
+const latestLabels = `
+	SELECT DISTINCT ON (controller, node_name) controller, node_name,
+	       ARRAY(SELECT l FROM unnest(labels) AS l WHERE l <> node_name ORDER BY l) AS labels
+	FROM agent_snapshots
+	ORDER BY controller, node_name, taken_at DESC
+`
+
+// SaveAgentSnapshots stores one collector run and drops snapshots past retention.
+func (db *DB) SaveAgentSnapshots(snapshots []models.AgentSnapshot, retention time.Duration) error {
+	tx, err := db.conn.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin tx failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, s := range snapshots {
+		if _, err := tx.NamedExec(`
+			INSERT INTO agent_snapshots (
+				controller, node_name, labels, num_executors, busy_executors,
+				offline, temporarily_offline, offline_reason, taken_at
+			)
+			VALUES (
+				:controller, :node_name, :labels, :num_executors, :busy_executors,
+				:offline, :temporarily_offline, NULLIF(:offline_reason, ''), :taken_at
+			)
+		`, s); err != nil {
+			return fmt.Errorf("insert agent snapshot %s failed: %w", s.NodeName, err)
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM agent_snapshots WHERE taken_at < $1`, time.Now().Add(-retention)); err != nil {
+		return fmt.Errorf("prune agent snapshots failed: %w", err)
+	}
+	return tx.Commit()
+}
+
+// SetBuiltOnByExecutable records the agent of a build from an executor's
+// currentExecutable URL. Pipeline builds report no builtOn, but the executors of
+// their node blocks point below the build URL. The build may already be marked
+// finished when it ends between two polls, so recent builds match as well as
+// running ones; the time bound keeps the scan to the newest partitions.
+func (db *DB) SetBuiltOnByExecutable(controller, executableURL, node string) error {
+	_, err := db.conn.Exec(`
+		UPDATE builds SET built_on = $3
+		WHERE controller = $1 AND built_on IS NULL
+		  AND (building OR timestamp >= $4)
+		  AND $2 LIKE job_url || '%'
+	`, controller, executableURL, node, time.Now().Add(-builtOnWindow))
+	if err != nil {
+		return fmt.Errorf("set built_on failed: %w", err)
+	}
+	return nil
+}
+
+// AgentUsageReport sums execution time per agent for builds started in [from, to].
+func (db *DB) AgentUsageReport(from, to time.Time) ([]models.AgentUsage, error) {
+	var rows []models.AgentUsage
+	err := db.conn.Select(&rows, fmt.Sprintf(`
+		WITH nodes AS (%s)
+		SELECT b.built_on AS agent,
+		       COALESCE((SELECT n.labels FROM nodes n WHERE n.controller = b.controller AND n.node_name = b.built_on), '{}') AS labels,
+		       COUNT(*) AS builds,
+		       ROUND(SUM(b.duration_ms) / 60000.0, 1)::float8 AS executor_minutes
+		FROM builds b
+		WHERE b.timestamp BETWEEN $1 AND $2 AND b.built_on IS NOT NULL
+		GROUP BY b.controller, b.built_on
+		ORDER BY executor_minutes DESC
+	`, latestLabels), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("agent usage report failed: %w", err)
+	}
+	return rows, nil
+}
+
+// LabelUsageReport sums execution time per agent label. A build counts towards
+// every label of the agent it ran on.
+func (db *DB) LabelUsageReport(from, to time.Time) ([]models.LabelUsage, error) {
+	var rows []models.LabelUsage
+	err := db.conn.Select(&rows, fmt.Sprintf(`
+		WITH nodes AS (%s)
+		SELECT l.label,
+		       COUNT(DISTINCT b.built_on) AS agents,
+		       COUNT(*) AS builds,
+		       ROUND(SUM(b.duration_ms) / 60000.0, 1)::float8 AS executor_minutes
+		FROM builds b
+		JOIN nodes n ON n.controller = b.controller AND n.node_name = b.built_on
+		CROSS JOIN LATERAL unnest(n.labels) AS l(label)
+		WHERE b.timestamp BETWEEN $1 AND $2
+		GROUP BY l.label
+		ORDER BY executor_minutes DESC
+	`, latestLabels), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("label usage report failed: %w", err)
+	}
+	return rows, nil
+}
+
+// FolderPoolReport sums execution time per folder and agent label.
+func (db *DB) FolderPoolReport(from, to time.Time) ([]models.FolderPoolUsage, error) {
+	var rows []models.FolderPoolUsage
+	err := db.conn.Select(&rows, fmt.Sprintf(`
+		WITH nodes AS (%s)
+		SELECT regexp_replace(b.project_path, '/[^/]*$', '') AS folder,
+		       l.label,
+		       COUNT(*) AS builds,
+		       ROUND(SUM(b.duration_ms) / 60000.0, 1)::float8 AS executor_minutes
+		FROM builds b
+		JOIN nodes n ON n.controller = b.controller AND n.node_name = b.built_on
+		CROSS JOIN LATERAL unnest(n.labels) AS l(label)
+		WHERE b.timestamp BETWEEN $1 AND $2
+		GROUP BY 1, 2
+		ORDER BY 1, executor_minutes DESC
+	`, latestLabels), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("folder pool report failed: %w", err)
+	}
+	return rows, nil
+}
+
+// BusiestHours averages executor utilisation per hour of day over the snapshots.
+func (db *DB) BusiestHours(from, to time.Time) ([]models.HourlyUtilisation, error) {
+	var rows []models.HourlyUtilisation
+	err := db.conn.Select(&rows, `
+		WITH runs AS (
+			SELECT taken_at, SUM(busy_executors) AS busy, SUM(num_executors) AS total
+			FROM agent_snapshots
+			WHERE taken_at BETWEEN $1 AND $2 AND NOT offline
+			GROUP BY taken_at
+		)
+		SELECT EXTRACT(HOUR FROM taken_at)::int AS hour,
+		       ROUND(AVG(busy), 1)::float8 AS avg_busy,
+		       ROUND(AVG(total), 1)::float8 AS avg_executors,
+		       COALESCE(SUM(busy)::float8 / NULLIF(SUM(total), 0), 0) AS utilisation
+		FROM runs
+		GROUP BY 1
+		ORDER BY 1
+	`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("busiest hours report failed: %w", err)
+	}
+	return rows, nil
+}
+
+// OfflineIncidents groups consecutive offline snapshots of a node into incidents.
+func (db *DB) OfflineIncidents(from, to time.Time) ([]models.OfflineIncident, error) {
+	var rows []models.OfflineIncident
+	err := db.conn.Select(&rows, `
+		WITH s AS (
+			SELECT controller, node_name, offline, offline_reason, taken_at,
+			       ROW_NUMBER() OVER (PARTITION BY controller, node_name ORDER BY taken_at)
+			     - ROW_NUMBER() OVER (PARTITION BY controller, node_name, offline ORDER BY taken_at) AS grp
+			FROM agent_snapshots
+			WHERE taken_at BETWEEN $1 AND $2
+		),
+		latest AS (
+			SELECT controller, MAX(taken_at) AS taken_at FROM agent_snapshots GROUP BY controller
+		)
+		SELECT s.controller, s.node_name AS agent,
+		       MIN(s.taken_at) AS start_at, MAX(s.taken_at) AS end_at,
+		       COALESCE(MAX(s.offline_reason), '') AS reason,
+		       MAX(s.taken_at) = MAX(l.taken_at) AS ongoing
+		FROM s
+		JOIN latest l ON l.controller = s.controller
+		WHERE s.offline
+		GROUP BY s.controller, s.node_name, s.grp
+		ORDER BY start_at DESC
+	`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("offline incidents report failed: %w", err)
+	}
+	return rows, nil
+}
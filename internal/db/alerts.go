@@ -0,0 +1,170 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/lib/pq"
+)
+
+const alertColumns = `id, rule, severity, dedup_key, COALESCE(build_id, 0) AS build_id, controller,
+	project_path, title, message, url, channels, state, COALESCE(error, '') AS error, fired_at`
+
+// GetBuildsPendingAlerts returns finished builds the alert rules have not seen
+// yet, oldest first.
+func (db *DB) GetBuildsPendingAlerts(limit int) ([]models.Build, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM builds
+		WHERE alerts_checked_at IS NULL AND NOT building AND COALESCE(status, '') <> ''
+		ORDER BY timestamp ASC
+		LIMIT $1
+	`, strings.Join(buildColumns, ", "))
+
+	var builds []models.Build
+	if err := db.conn.Select(&builds, query, limit); err != nil {
+		return nil, fmt.Errorf("get builds pending alerts failed: %w", err)
+	}
+	return builds, nil
+}
+
+// MarkAlertsChecked records that the alert rules have seen builds.
+func (db *DB) MarkAlertsChecked(builds []models.Build) error {
+	if len(builds) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(builds))
+	from, to := builds[0].Timestamp, builds[0].Timestamp
+	for i, b := range builds {
+		ids[i] = int64(b.ID)
+		if b.Timestamp.Before(from) {
+			from = b.Timestamp
+		}
+		if b.Timestamp.After(to) {
+			to = b.Timestamp
+		}
+	}
+	// the timestamp range lets the planner skip other monthly partitions
+	_, err := db.conn.Exec(`
+		UPDATE builds SET alerts_checked_at = now()
+		WHERE id = ANY($1) AND timestamp BETWEEN $2 AND $3
+	`, pq.Array(ids), from, to)
+	if err != nil {
+		return fmt.Errorf("mark alerts checked failed: %w", err)
+	}
+	return nil
+}
+
+// RecentStatuses returns the statuses of the last limit finished builds of a
+// job started at or before the given time, newest first.
+func (db *DB) RecentStatuses(controller, projectPath string, before time.Time, limit int) ([]string, error) {
+	var statuses []string
+	err := db.conn.Select(&statuses, `
+		SELECT status
+		FROM builds
+		WHERE controller = $1 AND project_path = $2 AND timestamp <= $3
+		  AND NOT building AND COALESCE(status, '') <> ''
+		ORDER BY timestamp DESC
+		LIMIT $4
+	`, controller, projectPath, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("recent statuses of %s failed: %w", projectPath, err)
+	}
+	return statuses, nil
+}
+
+// FolderOutcomes counts the finished and the successful builds under a folder
+// started in [from, to).
+func (db *DB) FolderOutcomes(folder string, from, to time.Time) (total, succeeded int, err error) {
+	row := db.conn.QueryRow(`
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE status = 'SUCCESS')
+		FROM builds
+		WHERE (project_path = $1 OR project_path LIKE $2)
+		  AND timestamp >= $3 AND timestamp < $4
+		  AND NOT building AND COALESCE(status, '') <> ''
+	`, folder, escapeLike(folder)+"/%", from, to)
+	if err := row.Scan(&total, &succeeded); err != nil {
+		return 0, 0, fmt.Errorf("folder outcomes of %s failed: %w", folder, err)
+	}
+	return total, succeeded, nil
+}
+
+// LastAlertAt returns when an alert with the dedup key was last sent or
+// silenced, or nil. Failed deliveries do not count.
+func (db *DB) LastAlertAt(dedupKey string) (*time.Time, error) {
+	var last sql.NullTime
+	err := db.conn.Get(&last, `
+		SELECT MAX(fired_at) FROM alerts WHERE dedup_key = $1 AND state <> $2
+	`, dedupKey, models.AlertFailed)
+	if err != nil {
+		return nil, fmt.Errorf("last alert of %s failed: %w", dedupKey, err)
+	}
+	if !last.Valid {
+		return nil, nil
+	}
+	return &last.Time, nil
+}
+
+// InsertAlert stores a fired alert in the history and sets its ID.
+func (db *DB) InsertAlert(a *models.Alert) error {
+	err := db.conn.QueryRow(`
+		INSERT INTO alerts (rule, severity, dedup_key, build_id, controller, project_path,
+		                    title, message, url, channels, state, error, fired_at)
+		VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), $13)
+		RETURNING id
+	`, a.Rule, a.Severity, a.DedupKey, a.BuildID, a.Controller, a.ProjectPath,
+		a.Title, a.Message, a.URL, a.Channels, a.State, a.Error, a.FiredAt).Scan(&a.ID)
+	if err != nil {
+		return fmt.Errorf("insert alert %s failed: %w", a.Rule, err)
+	}
+	return nil
+}
+
+// AlertFilter narrows the alert history; empty fields match everything.
+type AlertFilter struct {
+	Rule  string
+	State string
+	From  time.Time
+	To    time.Time
+	Limit int
+}
+
+// ListAlerts returns fired alerts, newest first.
+func (db *DB) ListAlerts(f AlertFilter) ([]models.Alert, error) {
+	var where []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Rule != "" {
+		add("rule = $%d", f.Rule)
+	}
+	if f.State != "" {
+		add("state = $%d", f.State)
+	}
+	if !f.From.IsZero() {
+		add("fired_at >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		add("fired_at <= $%d", f.To)
+	}
+	if f.Limit <= 0 {
+		f.Limit = 100
+	}
+	query := "SELECT " + alertColumns + " FROM alerts"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, f.Limit)
+	query += fmt.Sprintf(" ORDER BY fired_at DESC, id DESC LIMIT $%d", len(args))
+
+	var alerts []models.Alert
+	if err := db.conn.Select(&alerts, query, args...); err != nil {
+		return nil, fmt.Errorf("list alerts failed: %w", err)
+	}
+	return alerts, nil
+}
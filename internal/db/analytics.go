@@ -0,0 +1,111 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/lib/pq"
+)
+
+// GetDeployments returns builds in [from, to] that targeted a deploy environment.
+func (db *DB) GetDeployments(from, to time.Time) ([]models.Build, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM builds
+		WHERE timestamp BETWEEN $1 AND $2
+		  AND deploy_env <> ''
+		ORDER BY timestamp ASC
+	`, strings.Join(buildColumns, ", "))
+
+	var builds []models.Build
+	if err := db.conn.Select(&builds, query, from, to); err != nil {
+		return nil, fmt.Errorf("GetDeployments: %w", err)
+	}
+	return builds, nil
+}
+
+// GetEnvDeployments returns builds of a canonical env in [from, to], only those
+// that targeted a deploy environment when deploysOnly is set.
+func (db *DB) GetEnvDeployments(env string, deploysOnly bool, from, to time.Time) ([]models.Build, error) {
+	// spelled out literally so the planner can use idx_builds_deployments
+	deploys := ""
+	if deploysOnly {
+		deploys = "AND deploy_env <> ''"
+	}
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM builds
+		WHERE timestamp BETWEEN $1 AND $2
+		  AND env = $3
+		  %s
+		ORDER BY timestamp ASC
+	`, strings.Join(buildColumns, ", "), deploys)
+
+	var builds []models.Build
+	if err := db.conn.Select(&builds, query, from, to, env); err != nil {
+		return nil, fmt.Errorf("GetEnvDeployments: %w", err)
+	}
+	return builds, nil
+}
+
+// GetIGRMUsage maps each normalized IGRM number to the project paths of env
+// whose builds used it, across every stored build.
+func (db *DB) GetIGRMUsage(env string, igrms []string) (map[string][]string, error) {
+	out := make(map[string][]string, len(igrms))
+	if len(igrms) == 0 {
+		return out, nil
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT UPPER(TRIM(igrm_no)), array_agg(DISTINCT project_path ORDER BY project_path)
+		FROM builds
+		WHERE UPPER(TRIM(igrm_no)) = ANY($1)
+		  AND env = $2
+		GROUP BY 1
+	`, pq.Array(igrms), env)
+	if err != nil {
+		return nil, fmt.Errorf("GetIGRMUsage: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var igrm string
+		var paths []string
+		if err := rows.Scan(&igrm, pq.Array(&paths)); err != nil {
+			return nil, err
+		}
+		out[igrm] = paths
+	}
+	return out, rows.Err()
+}
+
+// GetCommitFirstSeen maps each commit SHA to the timestamp of the earliest build containing it.
+func (db *DB) GetCommitFirstSeen(shas []string) (map[string]time.Time, error) {
+	out := make(map[string]time.Time, len(shas))
+	if len(shas) == 0 {
+		return out, nil
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT commit_sha, MIN(timestamp)
+		FROM builds
+		WHERE commit_sha = ANY($1)
+		GROUP BY commit_sha
+	`, pq.Array(shas))
+	if err != nil {
+		return nil, fmt.Errorf("GetCommitFirstSeen: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sha string
+		var ts time.Time
+		if err := rows.Scan(&sha, &ts); err != nil {
+			return nil, err
+		}
+		out[sha] = ts
+	}
+	return out, rows.Err()
+}
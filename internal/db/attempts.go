@@ -0,0 +1,74 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Collectors sharing the collect_attempts backoff.
+const (
+	CollectLogs    = "logs"
+	CollectStages  = "stages"
+	CollectTests   = "tests"
+	CollectChanges = "changes"
+	CollectIGRM    = "igrm"
+)
+
+// MaxCollectAttempts is how often a collector tries a build before giving up on it.
+const MaxCollectAttempts = 8
+
+// collectBackoff is the wait after the n-th failed attempt: 5m, 10m, 20m, ... capped at 12h.
+func collectBackoff(attempts int) time.Duration {
+	d := 5 * time.Minute
+	for i := 1; i < attempts && d < 12*time.Hour; i++ {
+		d *= 2
+	}
+	if d > 12*time.Hour {
+		d = 12 * time.Hour
+	}
+	return d
+}
+
+// notBackingOff is a WHERE condition excluding builds (aliased b) that collector
+// failed on recently. The collector name is passed as parameter $n.
+func notBackingOff(n int) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM collect_attempts a
+		WHERE a.collector = $%d AND a.build_id = b.id AND a.next_attempt_at > now()
+	)`, n)
+}
+
+// RecordCollectFailure counts a failed fetch of a build and schedules the next
+// attempt. It returns the number of attempts made so far.
+func (db *DB) RecordCollectFailure(collector string, buildID int, fetchErr error) (int, error) {
+	var attempts int
+	err := db.conn.Get(&attempts, `
+		INSERT INTO collect_attempts (collector, build_id, attempts, last_error, next_attempt_at, updated_at)
+		VALUES ($1, $2, 1, $3, now(), now())
+		ON CONFLICT (collector, build_id) DO UPDATE SET
+			attempts   = collect_attempts.attempts + 1,
+			last_error = EXCLUDED.last_error,
+			updated_at = now()
+		RETURNING attempts
+	`, collector, buildID, fetchErr.Error())
+	if err != nil {
+		return 0, fmt.Errorf("record %s attempt for build %d failed: %w", collector, buildID, err)
+	}
+
+	_, err = db.conn.Exec(`
+		UPDATE collect_attempts SET next_attempt_at = now() + $3 * interval '1 second'
+		WHERE collector = $1 AND build_id = $2
+	`, collector, buildID, collectBackoff(attempts).Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("schedule %s attempt for build %d failed: %w", collector, buildID, err)
+	}
+	return attempts, nil
+}
+
+// ClearCollectAttempts forgets earlier failures once a build was collected.
+func (db *DB) ClearCollectAttempts(collector string, buildID int) error {
+	if _, err := db.conn.Exec(`DELETE FROM collect_attempts WHERE collector = $1 AND build_id = $2`, collector, buildID); err != nil {
+		return fmt.Errorf("clear %s attempts for build %d failed: %w", collector, buildID, err)
+	}
+	return nil
+}
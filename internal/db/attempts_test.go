@@ -0,0 +1,22 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCollectBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  5 * time.Minute,
+		2:  10 * time.Minute,
+		4:  40 * time.Minute,
+		8:  640 * time.Minute,
+		9:  12 * time.Hour,
+		50: 12 * time.Hour,
+	}
+	for attempts, want := range cases {
+		if got := collectBackoff(attempts); got != want {
+			t.Errorf("collectBackoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
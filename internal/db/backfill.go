@@ -2,59 +2,79 @@ package db
 
 import (
 	"fmt"
-	"log"
 	"strings"
-)
-
-func (db *DB) BackfillEnvColumn() error {
-	fmt.Println("Started Backfill")
-	
-    rows, err := db.conn.Query(`SELECT id, project_path FROM builds WHERE env IS NULL OR env = ''`)
-    if err != nil {
-        return err
-    }
-    defer rows.Close()
-
-    type rowData struct {
-        ID          int
-        ProjectPath string
-    }
-
-    var updates []rowData
+	"time"
 
-    for rows.Next() {
-        var r rowData
-        if err := rows.Scan(&r.ID, &r.ProjectPath); err != nil {
-            return err
-        }
-        updates = append(updates, r)
-    }
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/lib/pq"
+)
 
-    for _, r := range updates {
-        env := extractEnv(r.ProjectPath)
-        _, err := db.conn.Exec(`UPDATE builds SET env = $1 WHERE id = $2`, env, r.ID)
-        if err != nil {
-            log.Printf("Failed to update env for ID %d: %v", r.ID, err)
-        }
-    }
+// derivedColumns whitelists the text columns a backfill task may rewrite.
+var derivedColumns = map[string]bool{
+	"env":          true,
+	"deploy_env":   true,
+	"igrm_no":      true,
+	"trigger_type": true,
+}
 
-    log.Printf("Backfilled env for %d records", len(updates))
-    return nil
+// GetBuildsAfter returns up to limit builds with an id above afterID, in id
+// order; a non-zero since skips builds started before it.
+func (db *DB) GetBuildsAfter(afterID, limit int, since time.Time) ([]models.Build, error) {
+	var builds []models.Build
+	query := fmt.Sprintf(`SELECT %s FROM builds WHERE id > $1 AND timestamp >= $2 ORDER BY id LIMIT $3`, strings.Join(buildColumns, ", "))
+	if err := db.conn.Select(&builds, query, afterID, since, limit); err != nil {
+		return nil, fmt.Errorf("get builds after %d failed: %w", afterID, err)
+	}
+	return builds, nil
 }
 
-func extractEnv(path string) string {
-    parts := strings.Split(path, "/")
-    if len(parts) == 0 {
-        return "UNKNOWN"
-    }
+// UpdateBuildColumns rewrites the given columns of many builds in one statement.
+// values[i] holds the new values of ids[i], in columns order.
+func (db *DB) UpdateBuildColumns(columns []string, ids []int, values [][]string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if len(values) != len(ids) {
+		return fmt.Errorf("update build columns: %d ids but %d value rows", len(ids), len(values))
+	}
+
+	sets := make([]string, len(columns))
+	params := make([]string, len(columns))
+	aliases := make([]string, len(columns))
+	args := []interface{}{pq.Array(ids)}
+	for i, col := range columns {
+		if !derivedColumns[col] {
+			return fmt.Errorf("update build columns: column %q is not derived", col)
+		}
+		vals := make([]string, len(ids))
+		for j, row := range values {
+			if len(row) != len(columns) {
+				return fmt.Errorf("update build columns: build %d has %d values, want %d", ids[j], len(row), len(columns))
+			}
+			vals[j] = row[i]
+		}
+		args = append(args, pq.Array(vals))
+		params[i] = fmt.Sprintf("$%d::text[]", i+2)
+		aliases[i] = fmt.Sprintf("c%d", i)
+		sets[i] = fmt.Sprintf("%s = u.c%d", col, i)
+		if col == "igrm_no" { // a changed ticket is checked again
+			sets = append(sets, fmt.Sprintf("igrm_checked_at = CASE WHEN b.igrm_no IS DISTINCT FROM u.c%d THEN NULL ELSE b.igrm_checked_at END", i))
+		}
+	}
 
-    switch strings.ToUpper(parts[0]) {
-    case "DEV":
-        return "DEV"
-    case "NONPROD", "NON_PROD":
-        return "NON_PROD"
-    case "PROD_AND_DR", "PROD-DR":
-        return "PROD_AND_DR"
-    }
-    return "UNKNOWN"
+	query := fmt.Sprintf(`
+		UPDATE builds AS b SET %s
+		FROM unnest($1::int[], %s) AS u(id, %s)
+		WHERE b.id = u.id
+	`, strings.Join(sets, ", "), strings.Join(params, ", "), strings.Join(aliases, ", "))
+	if _, err := db.conn.Exec(query, args...); err != nil {
+		return fmt.Errorf("update %s of %d builds failed: %w", strings.Join(columns, ", "), len(ids), err)
+	}
+	for _, col := range columns {
+		if col == "env" { // a rollup dimension
+			markRollupsDirty(db.conn, ids)
+			break
+		}
+	}
+	return nil
 }
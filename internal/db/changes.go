@@ -0,0 +1,177 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// storeBuildChanges saves the changes that came with a stored build. Failures
+// only leave the build to the change collector.
+func (db *DB) storeBuildChanges(b *models.Build) {
+	if b.ID == 0 || b.Changes == nil {
+		return
+	}
+	if err := db.SaveBuildChanges(b.ID, b.Changes); err != nil {
+		log.Printf("[Changes] store failed for build ID %d: %v", b.ID, err)
+	}
+}
+
+// SaveBuildChanges replaces the commits of a build and marks its changes as
+// collected. An empty list records that the build shipped no commits.
+func (db *DB) SaveBuildChanges(buildID int, changes []models.BuildChange) error {
+	tx, err := db.conn.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin tx failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM build_changes WHERE build_id = $1`, buildID); err != nil {
+		return fmt.Errorf("delete build changes failed: %w", err)
+	}
+	if len(changes) > 0 {
+		stmt, err := tx.PrepareNamed(`
+			INSERT INTO build_changes (build_id, commit_id, author, message, paths, kind, position)
+			VALUES (:build_id, :commit_id, :author, :message, :paths, :kind, :position)
+		`)
+		if err != nil {
+			return fmt.Errorf("prepare build change insert failed: %w", err)
+		}
+		defer stmt.Close()
+		for i := range changes {
+			changes[i].BuildID = buildID
+			changes[i].Position = i
+			if changes[i].Paths == nil {
+				changes[i].Paths = []string{}
+			}
+			if _, err := stmt.Exec(changes[i]); err != nil {
+				return fmt.Errorf("insert change %s failed: %w", changes[i].CommitID, err)
+			}
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE builds SET changes_fetched_at = now() WHERE id = $1`, buildID); err != nil {
+		return fmt.Errorf("mark changes fetched failed: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetBuildsMissingChanges returns builds of a controller whose changes are not
+// stored yet, newest first, skipping builds whose last fetch failed and is
+// backing off.
+func (db *DB) GetBuildsMissingChanges(controller string, limit int) ([]*models.Build, error) {
+	var builds []*models.Build
+	err := db.conn.Select(&builds, `
+		SELECT id, build_number, COALESCE(project_path, '') AS project_path,
+		       COALESCE(job_url, '') AS job_url
+		FROM builds b
+		WHERE changes_fetched_at IS NULL
+		  AND controller = $1
+		  AND `+notBackingOff(3)+`
+		ORDER BY timestamp DESC
+		LIMIT $2
+	`, controller, limit, CollectChanges)
+	if err != nil {
+		return nil, fmt.Errorf("get builds missing changes failed: %w", err)
+	}
+	return builds, nil
+}
+
+// GetBuildChanges returns the commits of a build in changeSet order.
+func (db *DB) GetBuildChanges(buildID int) ([]models.BuildChange, error) {
+	var changes []models.BuildChange
+	err := db.conn.Select(&changes, `
+		SELECT id, build_id, commit_id, author, message, paths, kind, position
+		FROM build_changes
+		WHERE build_id = $1
+		ORDER BY position
+	`, buildID)
+	if err != nil {
+		return nil, fmt.Errorf("get build changes failed: %w", err)
+	}
+	return changes, nil
+}
+
+// breakingRuns selects finished builds between $1 and $2; the job filter is
+// appended by breakingWhere, which then adds each job's last build before the
+// window so LAG gives its first build in the window a previous status too.
+const breakingRuns = `
+	WITH windowed AS (
+		SELECT id, controller, project_path, build_number, status, timestamp
+		FROM builds
+		WHERE timestamp >= $1 AND timestamp <= $2 AND COALESCE(status, '') <> '' AND NOT building`
+
+// breakingWhere limits breakingRuns to a folder or job, matched by path prefix.
+func breakingWhere(folder string, from, to time.Time) (string, []interface{}) {
+	args := []interface{}{from, to}
+	query := breakingRuns
+	if folder = strings.Trim(folder, "/"); folder != "" {
+		args = append(args, folder, escapeLike(folder)+"/%")
+		query += ` AND (project_path = $3 OR project_path LIKE $4)`
+	}
+	query += `
+	), runs AS (
+		SELECT j.*, LAG(j.status) OVER (PARTITION BY j.controller, j.project_path ORDER BY j.build_number) AS prev_status
+		FROM (
+			SELECT * FROM windowed
+			UNION ALL
+			SELECT p.* FROM (
+				SELECT controller, project_path, MIN(build_number) AS first_number FROM windowed GROUP BY 1, 2
+			) f
+			CROSS JOIN LATERAL (
+				SELECT pb.id, pb.controller, pb.project_path, pb.build_number, pb.status, pb.timestamp
+				FROM builds pb
+				WHERE pb.controller = f.controller AND pb.project_path = f.project_path
+				  AND pb.build_number < f.first_number
+				  AND COALESCE(pb.status, '') <> '' AND NOT pb.building
+				ORDER BY pb.build_number DESC
+				LIMIT 1
+			) p
+		) j
+	), broken AS (
+		SELECT * FROM runs
+		WHERE timestamp >= $1 AND timestamp <= $2
+		  AND status IN ('FAILURE', 'UNSTABLE') AND prev_status = 'SUCCESS'
+	)`
+	return query, args
+}
+
+// BreakingChanges returns the commits of builds between from and to that
+// turned a job of folder from green to red, newest first.
+func (db *DB) BreakingChanges(folder string, from, to time.Time, limit int) ([]models.BreakingChange, error) {
+	query, args := breakingWhere(folder, from, to)
+	args = append(args, limit)
+	var rows []models.BreakingChange
+	err := db.conn.Select(&rows, query+fmt.Sprintf(`
+		SELECT b.id AS build_id, b.controller, b.project_path, b.build_number, b.status, b.timestamp,
+		       c.commit_id, c.author, c.message
+		FROM broken b
+		JOIN build_changes c ON c.build_id = b.id
+		ORDER BY b.timestamp DESC, c.position
+		LIMIT $%d
+	`, len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("breaking changes failed: %w", err)
+	}
+	return rows, nil
+}
+
+// BreakingAuthors ranks the authors of BreakingChanges by breaking commits.
+func (db *DB) BreakingAuthors(folder string, from, to time.Time) ([]models.BreakingAuthor, error) {
+	query, args := breakingWhere(folder, from, to)
+	var rows []models.BreakingAuthor
+	err := db.conn.Select(&rows, query+`
+		SELECT c.author, count(DISTINCT c.commit_id) AS commits, count(DISTINCT b.id) AS builds
+		FROM broken b
+		JOIN build_changes c ON c.build_id = b.id
+		GROUP BY c.author
+		ORDER BY commits DESC, c.author
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("breaking authors failed: %w", err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBreakingWhere(t *testing.T) {
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	query, args := breakingWhere("/payments/", from, from.AddDate(0, 0, 7))
+	if len(args) != 4 || args[2] != "payments" || args[3] != "payments/%" {
+		t.Fatalf("args = %v", args)
+	}
+
+	// the folder limits the window, before the previous builds are looked up
+	window := query[:strings.Index(query, "), runs AS")]
+	if !strings.Contains(window, "(project_path = $3 OR project_path LIKE $4)") {
+		t.Errorf("folder filter missing from the window:\n%s", window)
+	}
+	// previous builds only feed LAG; broken builds stay within [from, to]
+	broken := query[strings.Index(query, "broken AS"):]
+	if !strings.Contains(query, "pb.build_number < f.first_number") || !strings.Contains(broken, "timestamp >= $1 AND timestamp <= $2") {
+		t.Errorf("previous builds not added or not filtered out:\n%s", query)
+	}
+}
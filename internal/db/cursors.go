@@ -0,0 +1,76 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/lib/pq"
+)
+
+// GetJobCursors returns the cursors of a controller keyed by project path.
+func (db *DB) GetJobCursors(controller string) (map[string]models.JobCursor, error) {
+	var rows []models.JobCursor
+	err := db.conn.Select(&rows, `
+		SELECT controller, project_path, last_build_number, last_polled_at,
+		       COALESCE(last_error, '') AS last_error
+		FROM job_cursors
+		WHERE controller = $1
+	`, controller)
+	if err != nil {
+		return nil, fmt.Errorf("get job cursors failed: %w", err)
+	}
+
+	cursors := make(map[string]models.JobCursor, len(rows))
+	for _, r := range rows {
+		cursors[r.ProjectPath] = r
+	}
+	return cursors, nil
+}
+
+// ListJobCursors returns all cursors, jobs with errors first.
+func (db *DB) ListJobCursors() ([]models.JobCursor, error) {
+	var rows []models.JobCursor
+	err := db.conn.Select(&rows, `
+		SELECT controller, project_path, last_build_number, last_polled_at,
+		       COALESCE(last_error, '') AS last_error
+		FROM job_cursors
+		ORDER BY (COALESCE(last_error, '') = ''), controller, project_path
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list job cursors failed: %w", err)
+	}
+	return rows, nil
+}
+
+// SaveJobCursors upserts a batch of cursors in one statement. A cursor never
+// moves backwards; LastPolledAt and LastError are replaced.
+func (db *DB) SaveJobCursors(cursors []models.JobCursor) error {
+	if len(cursors) == 0 {
+		return nil
+	}
+
+	controllers := make([]string, len(cursors))
+	paths := make([]string, len(cursors))
+	numbers := make([]int64, len(cursors))
+	errs := make([]string, len(cursors))
+	for i, c := range cursors {
+		controllers[i] = c.Controller
+		paths[i] = c.ProjectPath
+		numbers[i] = int64(c.LastBuildNumber)
+		errs[i] = c.LastError
+	}
+
+	_, err := db.conn.Exec(`
+		INSERT INTO job_cursors (controller, project_path, last_build_number, last_polled_at, last_error)
+		SELECT c, p, n, now(), NULLIF(e, '')
+		FROM unnest($1::text[], $2::text[], $3::int[], $4::text[]) AS t(c, p, n, e)
+		ON CONFLICT (controller, project_path) DO UPDATE SET
+			last_build_number = GREATEST(job_cursors.last_build_number, EXCLUDED.last_build_number),
+			last_polled_at    = EXCLUDED.last_polled_at,
+			last_error        = EXCLUDED.last_error
+	`, pq.Array(controllers), pq.Array(paths), pq.Array(numbers), pq.Array(errs))
+	if err != nil {
+		return fmt.Errorf("save job cursors failed: %w", err)
+	}
+	return nil
+}
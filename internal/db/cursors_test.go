@@ -0,0 +1,59 @@
+package db
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// testDB connects to TEST_DATABASE_DSN and migrates it, or skips the test.
+func testDB(t *testing.T) *DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN not set")
+	}
+	database, err := NewDB(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+func TestSaveJobCursors(t *testing.T) {
+	database := testDB(t)
+	const controller = "cursors-test"
+	t.Cleanup(func() {
+		database.conn.Exec(`DELETE FROM job_cursors WHERE controller = $1`, controller)
+	})
+
+	save := func(number int, lastErr string) models.JobCursor {
+		t.Helper()
+		if err := database.SaveJobCursors([]models.JobCursor{{Controller: controller, ProjectPath: "app", LastBuildNumber: number, LastError: lastErr}}); err != nil {
+			t.Fatal(err)
+		}
+		cursors, err := database.GetJobCursors(controller)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cursors["app"]
+	}
+
+	if c := save(5, ""); c.LastBuildNumber != 5 || c.LastPolledAt == nil {
+		t.Errorf("expected a new cursor at 5, got %+v", c)
+	}
+	if c := save(9, ""); c.LastBuildNumber != 9 {
+		t.Errorf("expected the cursor to advance to 9, got %+v", c)
+	}
+	// a poll whose inserts failed saves a lower number; the cursor keeps its place
+	if c := save(7, "insert failed"); c.LastBuildNumber != 9 || c.LastError != "insert failed" {
+		t.Errorf("expected the cursor to stay at 9 with the error, got %+v", c)
+	}
+	if c := save(9, ""); c.LastError != "" {
+		t.Errorf("expected the error to clear, got %+v", c)
+	}
+}
@@ -5,7 +5,6 @@ package db
 // Use github.com/jmoiron/sqlx for simpler DB access with structs
 import (
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
@@ -42,38 +41,151 @@ func (db *DB) FetchRecentBuilds(limit int) ([]*models.Build, error) {
 	return builds, nil
 }
 
-// ALTER TABLE builds ADD CONSTRAINT unique_build_path UNIQUE (build_number, project_path);
+// Unique key is (controller, build_number, project_path, timestamp); builds is
+// partitioned by timestamp, see migrations/013_partition_builds.up.sql. The
+// build's identity is claimed in build_keys first, so a build reported again
+// with a different timestamp still maps onto its stored row.
 func (db *DB) InsertBuild(b *models.Build) error {
+	tx, err := db.conn.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin tx failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := claimBuildKey(tx, b); err != nil {
+		return err
+	}
 	query := `
 	INSERT INTO public.builds (
-		build_number, project_name, project_path, user_id, status,
-		timestamp, duration_ms, job_url, branch, git_url, commit_sha, deploy_env, trigger_type, env, igrm_no
+		controller, build_number, project_name, project_path, user_id, status,
+		timestamp, duration_ms, job_url, branch, git_url, commit_sha, deploy_env, trigger_type, env, igrm_no,
+		building, queue_id, estimated_duration_ms, queue_wait_ms, built_on, parameters, causes
 	)
 	VALUES (
-		:build_number, :project_name, :project_path, :user_id, :status,
-		:timestamp, :duration_ms, :job_url, :branch, :git_url, :commit_sha, :deploy_env, :trigger_type, :env, :igrm_no
+		:controller, :build_number, :project_name, :project_path, :user_id, :status,
+		:timestamp, :duration_ms, :job_url, :branch, :git_url, :commit_sha, :deploy_env, :trigger_type, :env, :igrm_no,
+		:building, NULLIF(:queue_id, 0), :estimated_duration_ms, NULLIF(:queue_wait_ms, 0), NULLIF(:built_on, ''), :parameters, :causes
 	)
-	ON CONFLICT (build_number, project_path) DO NOTHING
+	ON CONFLICT (controller, build_number, project_path, timestamp) DO NOTHING
 	RETURNING id
 	`
-	rows, err := db.conn.NamedQuery(query, b)
+	inserted, err := namedReturningID(tx, query, b)
 	if err != nil {
 		return fmt.Errorf("insert build failed: %w", err)
 	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("insert build failed: %w", err)
+	}
+
+	if inserted {
+		db.markRollupDirty(b.Timestamp, b.Controller, b.ProjectPath)
+		db.storeBuildChanges(b)
+	}
+	return nil
+}
+
+// claimBuildKey records the identity of b in build_keys, or locks the existing
+// entry until the transaction ends, and sets b.Timestamp to the timestamp the
+// build was first stored with. builds is partitioned by timestamp, so its own
+// unique key cannot catch the same build reported with a drifted timestamp.
+func claimBuildKey(tx *sqlx.Tx, b *models.Build) error {
+	var ts time.Time
+	err := tx.Get(&ts, `
+		INSERT INTO build_keys (controller, project_path, build_number, timestamp)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (controller, project_path, build_number) DO UPDATE SET timestamp = build_keys.timestamp
+		RETURNING timestamp
+	`, b.Controller, b.ProjectPath, b.BuildNumber, b.Timestamp)
+	if err != nil {
+		return fmt.Errorf("claim build key failed: %w", err)
+	}
+	b.Timestamp = LocalWallClock(ts)
+	return nil
+}
+
+// namedReturningID runs an INSERT ... RETURNING id and stores the id in b.ID.
+// inserted is false when the statement returned no row (ON CONFLICT DO NOTHING).
+func namedReturningID(tx *sqlx.Tx, query string, b *models.Build) (inserted bool, err error) {
+	rows, err := tx.NamedQuery(query, b)
+	if err != nil {
+		return false, err
+	}
 	defer rows.Close()
 
-	if rows.Next() {
-		if err := rows.Scan(&b.ID); err != nil {
-			return fmt.Errorf("could not scan returned ID: %w", err)
-		}
+	if !rows.Next() {
+		return false, rows.Err()
+	}
+	if err := rows.Scan(&b.ID); err != nil {
+		return false, fmt.Errorf("could not scan returned ID: %w", err)
+	}
+	return true, nil
+}
+
+// UpsertBuild inserts a build or refreshes an existing row, e.g. when a webhook
+// reports completion of a build first stored while it was still running.
+// Empty incoming values never overwrite stored ones.
+func (db *DB) UpsertBuild(b *models.Build) error {
+	tx, err := db.conn.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin tx failed: %w", err)
 	}
+	defer tx.Rollback()
+
+	if err := claimBuildKey(tx, b); err != nil {
+		return err
+	}
+	query := `
+	INSERT INTO public.builds (
+		controller, build_number, project_name, project_path, user_id, status,
+		timestamp, duration_ms, job_url, branch, git_url, commit_sha, deploy_env, trigger_type, env, igrm_no,
+		building, queue_id, estimated_duration_ms, queue_wait_ms, built_on, parameters, causes
+	)
+	VALUES (
+		:controller, :build_number, :project_name, :project_path, :user_id, :status,
+		:timestamp, :duration_ms, :job_url, :branch, :git_url, :commit_sha, :deploy_env, :trigger_type, :env, :igrm_no,
+		:building, NULLIF(:queue_id, 0), :estimated_duration_ms, NULLIF(:queue_wait_ms, 0), NULLIF(:built_on, ''), :parameters, :causes
+	)
+	ON CONFLICT (controller, build_number, project_path, timestamp) DO UPDATE SET
+		status       = COALESCE(NULLIF(EXCLUDED.status, ''), builds.status),
+		duration_ms  = GREATEST(EXCLUDED.duration_ms, builds.duration_ms),
+		user_id      = COALESCE(NULLIF(EXCLUDED.user_id, 'unknown@jenkins'), builds.user_id),
+		branch       = COALESCE(NULLIF(EXCLUDED.branch, ''), builds.branch),
+		git_url      = COALESCE(NULLIF(EXCLUDED.git_url, ''), builds.git_url),
+		commit_sha   = COALESCE(NULLIF(EXCLUDED.commit_sha, ''), builds.commit_sha),
+		deploy_env   = COALESCE(NULLIF(EXCLUDED.deploy_env, ''), builds.deploy_env),
+		trigger_type = COALESCE(NULLIF(EXCLUDED.trigger_type, 'unknown'), builds.trigger_type),
+		igrm_no      = COALESCE(NULLIF(EXCLUDED.igrm_no, ''), builds.igrm_no),
+		igrm_checked_at = CASE WHEN EXCLUDED.igrm_no <> '' AND EXCLUDED.igrm_no IS DISTINCT FROM builds.igrm_no
+		                       THEN NULL ELSE builds.igrm_checked_at END,
+		building     = EXCLUDED.building,
+		queue_id     = COALESCE(EXCLUDED.queue_id, builds.queue_id),
+		estimated_duration_ms = GREATEST(EXCLUDED.estimated_duration_ms, builds.estimated_duration_ms),
+		queue_wait_ms         = COALESCE(EXCLUDED.queue_wait_ms, builds.queue_wait_ms),
+		built_on              = COALESCE(EXCLUDED.built_on, builds.built_on),
+		parameters            = CASE WHEN EXCLUDED.parameters = '{}' THEN builds.parameters ELSE EXCLUDED.parameters END,
+		causes                = CASE WHEN EXCLUDED.causes = '[]' THEN builds.causes ELSE EXCLUDED.causes END
+	RETURNING id
+	`
+	stored, err := namedReturningID(tx, query, b)
+	if err != nil {
+		return fmt.Errorf("upsert build failed: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("upsert build failed: %w", err)
+	}
+
+	if stored {
+		db.storeBuildChanges(b)
+	}
+	db.markRollupDirty(b.Timestamp, b.Controller, b.ProjectPath)
 
 	return nil
 }
 
 func (db *DB) GetBuildByID(id int) (*models.Build, error) {
 	var build models.Build
-	err := db.conn.Get(&build, `SELECT * FROM builds WHERE id = $1`, id)
+	query := fmt.Sprintf(`SELECT %s FROM builds WHERE id = $1`, strings.Join(buildColumns, ", "))
+	err := db.conn.Get(&build, query, id)
 	if err != nil {
 		return nil, fmt.Errorf("get build by id failed: %w", err)
 	}
@@ -100,65 +212,15 @@ func (db *DB) GetAllBuilds() ([]*models.Build, error) {
 }
 
 // GetBuildsByTime fetches builds in [from, to], sorted and paginated.
-func (db *DB) GetBuildsByTime(from, to time.Time,limit, offset int,sortBy, order string) ([]models.Build, error) {
-    // Whitelist allowed sort columns to avoid SQL injection
-    // whitelist allowed sort columns -> actual DB column names
-    allowed := map[string]string{
-        "timestamp":   "timestamp",
-        "env":         "env",
-		"deploy_env":  "deploy_env",
-		"igrm_no":     "igrm_no",
-        "status":      "status",
-        "user_id":     "user_id",
-        "duration_ms": "duration_ms",
-    }
-    col, ok := allowed[sortBy]
-    if !ok {
-        col = "timestamp"
-    }
-
-    // normalize order
-    if strings.ToUpper(order) == "ASC" {
-        order = "ASC"
-    } else {
-        order = "DESC"
-    }
-
-    // Explicit column list (must match your models.Build tags)
-    cols := []string{
-        "id",
-        "build_number",
-        "project_name",
-        "project_path",
-        "user_id",
-        "status",
-        "timestamp",
-        "duration_ms",
-        "job_url",
-        "branch",
-        "git_url",
-        "commit_sha",
-        "deploy_env",
-        "trigger_type",
-        "env",
-		"COALESCE(igrm_no, '') AS igrm_no",
-    }
-    colList := strings.Join(cols, ", ")
-
-    // build query with dynamic ORDER BY
-    query := fmt.Sprintf(`
-        SELECT %s
-        FROM builds
-        WHERE timestamp BETWEEN $1 AND $2
-        ORDER BY %s %s
-        LIMIT $3 OFFSET $4
-    `, colList, col, order)
-
-    var builds []models.Build
-    if err := db.conn.Select(&builds, query, from, to, limit, offset); err != nil {
-        return nil, fmt.Errorf("GetBuildsByTime: %w", err)
-    }
-    return builds, nil
+func (db *DB) GetBuildsByTime(from, to time.Time, limit, offset int, sortBy, order string) ([]models.Build, error) {
+	return db.QueryBuilds(BuildQuery{
+		From:   from,
+		To:     to,
+		SortBy: sortBy,
+		Order:  order,
+		Limit:  limit,
+		Offset: offset,
+	})
 }
 
 // count query for pagination
@@ -174,15 +236,6 @@ func (db *DB) CountBuildsByTime(from, to time.Time) (int, error) {
 	return count, nil
 }
 
-// Used during incremental fetch of build records
-func (db *DB) GetLastSeenBuildNumber(projectName string) (int, error) {
-	var lastSeen int
-	err := db.conn.QueryRow(`
-        SELECT COALESCE(MAX(build_number), 0) FROM builds WHERE project_name = $1
-    `, projectName).Scan(&lastSeen)
-	return lastSeen, err
-}
-
 // Uses project path to construct hierarchy of builds for each folder.
 func (db *DB) GetBuildsByFolder() (map[string]map[string][]string, error) {
 	paths, err := db.GetAllProjectPaths()
@@ -218,9 +271,9 @@ func appendIfMissing(slice []string, val string) []string {
 	return append(slice, val)
 }
 
-// Retrieves the project paths
+// Retrieves the project paths from the project catalogue
 func (db *DB) GetAllProjectPaths() ([]string, error) {
-	rows, err := db.conn.Query(`SELECT DISTINCT project_path FROM builds`)
+	rows, err := db.conn.Query(`SELECT DISTINCT project_path FROM projects`)
 	if err != nil {
 		return nil, err
 	}
@@ -249,15 +302,17 @@ func (db *DB) GetBuildsByProject(projectPath string) ([]models.Build, error) {
     return builds, err
 }
 
-func (db *DB) GetBuildsByProjectPath(path string) ([]models.Build, error) {
+// GetBuildsByProjectPath returns the latest builds of a job; an empty controller matches all controllers.
+func (db *DB) GetBuildsByProjectPath(controller, path string) ([]models.Build, error) {
 	rows, err := db.conn.Query(`
-        SELECT build_number, env, project_path, status, user_id,
-               timestamp, duration_ms, job_url, trigger_type, git_url, branch, commit_sha
+        SELECT id, controller, build_number, env, project_path, status, user_id,
+               timestamp, duration_ms, job_url, trigger_type, git_url, branch, commit_sha, parameters
         FROM builds
         WHERE project_path = $1
+          AND ($2 = '' OR controller = $2)
         ORDER BY build_number DESC
         LIMIT 100
-    `, path)
+    `, path, controller)
 	if err != nil {
 		return nil, err
 	}
@@ -267,6 +322,8 @@ func (db *DB) GetBuildsByProjectPath(path string) ([]models.Build, error) {
 	for rows.Next() {
 		var b models.Build
 		err := rows.Scan(
+			&b.ID,
+			&b.Controller,
 			&b.BuildNumber,
 			&b.Env,
 			&b.ProjectPath,
@@ -279,6 +336,7 @@ func (db *DB) GetBuildsByProjectPath(path string) ([]models.Build, error) {
 			&b.GitRepo,
 			&b.Branch,
 			&b.CommitSHA,
+			&b.Parameters,
 		)
 		if err != nil {
 			return nil, err
@@ -289,21 +347,48 @@ func (db *DB) GetBuildsByProjectPath(path string) ([]models.Build, error) {
 	return builds, nil
 }
 
+// GetBuildTree builds the folder hierarchy of all project paths. When builds of
+// more than one controller are stored, the first level is the controller name.
+// Paths come from the project catalogue kept by the rollup refresher.
 func (db *DB) GetBuildTree() (*models.FolderNode, error) {
-	paths, err := db.GetAllProjectPaths()
-	if err != nil {
+	var rows []struct {
+		Controller  string `db:"controller"`
+		ProjectPath string `db:"project_path"`
+	}
+	if err := db.conn.Select(&rows, `SELECT controller, project_path FROM projects`); err != nil {
 		return nil, err
 	}
 
+	controllers := make(map[string]bool)
+	for _, r := range rows {
+		controllers[r.Controller] = true
+	}
+	grouped := len(controllers) > 1
+
 	root := &models.FolderNode{
 		Name:     "root",
 		FullPath: "",
 		Children: map[string]*models.FolderNode{},
 	}
 
-	for _, path := range paths {
-		parts := strings.Split(path, "/")
+	for _, r := range rows {
 		curr := root
+		controller := ""
+		if grouped {
+			controller = r.Controller
+			child, exists := curr.Children[controller]
+			if !exists {
+				child = &models.FolderNode{
+					Name:       controller,
+					Controller: controller,
+					Children:   map[string]*models.FolderNode{},
+				}
+				curr.Children[controller] = child
+			}
+			curr = child
+		}
+
+		parts := strings.Split(r.ProjectPath, "/")
 		currPath := ""
 
 		for i, part := range parts {
@@ -316,9 +401,10 @@ func (db *DB) GetBuildTree() (*models.FolderNode, error) {
 			child, exists := curr.Children[part]
 			if !exists {
 				child = &models.FolderNode{
-					Name:     part,
-					FullPath: currPath,
-					Children: map[string]*models.FolderNode{},
+					Name:       part,
+					FullPath:   currPath,
+					Controller: controller,
+					Children:   map[string]*models.FolderNode{},
 				}
 				curr.Children[part] = child
 			}
@@ -334,14 +420,15 @@ func (db *DB) GetBuildTree() (*models.FolderNode, error) {
 	return root, nil
 }
 
-func (db *DB) GetRecentBuildsMissingStatus(limit int) ([]*models.Build, error) {
+func (db *DB) GetRecentBuildsMissingStatus(controller string, limit int) ([]*models.Build, error) {
     rows, err := db.conn.Query(`
         SELECT id, build_number, job_url
         FROM builds
         WHERE (status IS NULL OR status = '')
+          AND controller = $1
         ORDER BY timestamp DESC
-        LIMIT $1
-    `, limit)
+        LIMIT $2
+    `, controller, limit)
     if err != nil {
         return nil, err
     }
@@ -365,76 +452,40 @@ func (db *DB) UpdateBuildStatus(id int, status string) error {
     _, err := db.conn.Exec(`
         UPDATE builds SET status = $1 WHERE id = $2
     `, status, id)
-    return err
-}
-
-// CleanupToMax deletes the oldest builds so that only cfg.MaxRecords remain.
-func (db *DB) CleanupToMax(maxRecords, deleteMultiple int) error {
-    // 1) Total before
-    var totalBefore int
-    if err := db.conn.QueryRow(`SELECT count(*) FROM builds`).Scan(&totalBefore); err != nil {
-        return fmt.Errorf("count before delete failed: %w", err)
-    }
-    log.Printf("[Cleaner] totalBefore=%d, maxRecords=%d", totalBefore, maxRecords)
-    if totalBefore <= maxRecords {
-        return nil
-    }
-
-    // 2) Oldest timestamp
-    var oldest time.Time
-    if err := db.conn.QueryRow(`SELECT min("timestamp") FROM builds`).Scan(&oldest); err != nil {
-        return fmt.Errorf("min timestamp query failed: %w", err)
-    }
-    log.Printf("[Cleaner] oldest record is from %s", oldest.Format(time.RFC3339))
-
-    // 3) Compute delete count
-    toDelete := totalBefore - maxRecords
-    rounded := ((toDelete + deleteMultiple - 1) / deleteMultiple) * deleteMultiple
-
-    // 4) Peek the IDs we’ll delete
-    ids := []int{}
-    rows, err := db.conn.Query(`
-      SELECT id
-        FROM builds
-       ORDER BY "timestamp" ASC
-       LIMIT $1
-    `, rounded)
-    if err != nil {
-        return fmt.Errorf("peek IDs failed: %w", err)
-    }
-    for rows.Next() {
-        var id int
-        if err := rows.Scan(&id); err != nil {
-            return fmt.Errorf("scan id failed: %w", err)
-        }
-        ids = append(ids, id)
-    }
-    rows.Close()
-
-    // 5) Perform the delete
-	const deleteSQL = `
-	DELETE FROM builds
-	WHERE id IN (
-		SELECT id FROM builds ORDER BY "timestamp" ASC LIMIT $1
-	);
-	`
-    res, err := db.conn.Exec(deleteSQL, rounded)
-    if err != nil {
-        return fmt.Errorf("delete failed: %w", err)
+    if err == nil {
+        markRollupsDirty(db.conn, []int{id})
     }
-    deleted, _ := res.RowsAffected()
-    log.Printf("[Cleaner] deleted %d rows", deleted)
-
-    // 6) Total after
-    var totalAfter int
-    if err := db.conn.QueryRow(`SELECT count(*) FROM builds`).Scan(&totalAfter); err != nil {
-        return fmt.Errorf("count after delete failed: %w", err)
-    }
-    return nil
+    return err
 }
 
-func (db *DB) CountBuilds() (int, error) {
-    var total int
-    err := db.conn.QueryRow(`SELECT count(*) FROM builds`).Scan(&total)
-    return total, err
+// ListProjects returns one summary per project path ordered by path,
+// starting strictly after the given path (keyset pagination). Paths and counts
+// come from the project catalogue and daily rollups, so builds removed by
+// retention still count.
+func (db *DB) ListProjects(after string, limit int) ([]models.ProjectSummary, error) {
+	var projects []models.ProjectSummary
+	err := db.conn.Select(&projects, `
+		WITH p AS (
+			SELECT DISTINCT project_path FROM projects
+			WHERE project_path > $1
+			ORDER BY project_path
+			LIMIT $2
+		)
+		SELECT p.project_path,
+		       COALESCE(last.project_name, '') AS project_name,
+		       COALESCE((SELECT SUM(r.builds) FROM build_daily_rollups r WHERE r.project_path = p.project_path), 0) AS build_count,
+		       COALESCE(last.timestamp, 'epoch') AS last_build_at
+		FROM p
+		LEFT JOIN LATERAL (
+			SELECT b.project_name, b.timestamp FROM builds b
+			WHERE b.project_path = p.project_path
+			ORDER BY b.timestamp DESC
+			LIMIT 1
+		) last ON true
+		ORDER BY p.project_path
+	`, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list projects failed: %w", err)
+	}
+	return projects, nil
 }
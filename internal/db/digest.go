@@ -0,0 +1,132 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// DigestFilter selects the finished builds a scheduled report covers; empty
+// Env and Folder match everything.
+type DigestFilter struct {
+	From   time.Time
+	To     time.Time
+	Env    string
+	Folder string // project path prefix
+}
+
+// where returns the shared predicate over builds and its arguments ($1..$5).
+func (f DigestFilter) where() (string, []interface{}) {
+	return `timestamp BETWEEN $1 AND $2
+		  AND NOT building AND COALESCE(status, '') <> ''
+		  AND ($3 = '' OR env = $3)
+		  AND ($4 = '' OR project_path = $4 OR project_path LIKE $5)`,
+		[]interface{}{f.From, f.To, f.Env, f.Folder, escapeLike(f.Folder) + "/%"}
+}
+
+// EnvTotals counts the builds of each env in the range, busiest env first.
+func (db *DB) EnvTotals(f DigestFilter) ([]models.EnvTotal, error) {
+	where, args := f.where()
+	var rows []models.EnvTotal
+	err := db.conn.Select(&rows, `
+		SELECT COALESCE(NULLIF(env, ''), 'unknown') AS env,
+		       COUNT(*) AS builds,
+		       COUNT(*) FILTER (WHERE status = 'SUCCESS') AS succeeded,
+		       COUNT(*) FILTER (WHERE status IN ('FAILURE', 'UNSTABLE')) AS failed,
+		       COUNT(*) FILTER (WHERE status = 'ABORTED') AS aborted,
+		       COALESCE(AVG(duration_ms), 0)::bigint AS avg_duration_ms
+		FROM builds
+		WHERE `+where+`
+		GROUP BY 1
+		ORDER BY 2 DESC, 1
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("env totals failed: %w", err)
+	}
+	return rows, nil
+}
+
+// pipelineStats summarises each job in the range, ordered by orderBy.
+func (db *DB) pipelineStats(f DigestFilter, having, orderBy string, limit int) ([]models.PipelineStat, error) {
+	where, args := f.where()
+	args = append(args, limit)
+	var rows []models.PipelineStat
+	err := db.conn.Select(&rows, `
+		SELECT controller, project_path,
+		       COUNT(*) AS builds,
+		       COUNT(*) FILTER (WHERE status IN ('FAILURE', 'UNSTABLE')) AS failures,
+		       COALESCE(AVG(duration_ms), 0)::bigint AS avg_duration_ms,
+		       COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY duration_ms), 0)::bigint AS p95_duration_ms,
+		       COALESCE(MAX(duration_ms), 0) AS max_duration_ms,
+		       MAX(timestamp) AS last_build_at
+		FROM builds
+		WHERE `+where+`
+		GROUP BY controller, project_path
+		`+having+`
+		ORDER BY `+orderBy+`
+		LIMIT $6
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline stats failed: %w", err)
+	}
+	return rows, nil
+}
+
+// TopFailingPipelines returns the jobs with the most failed builds in the range.
+func (db *DB) TopFailingPipelines(f DigestFilter, limit int) ([]models.PipelineStat, error) {
+	return db.pipelineStats(f, `HAVING COUNT(*) FILTER (WHERE status IN ('FAILURE', 'UNSTABLE')) > 0`, "failures DESC, builds DESC, project_path", limit)
+}
+
+// SlowestPipelines returns the jobs with the longest average build in the range.
+func (db *DB) SlowestPipelines(f DigestFilter, limit int) ([]models.PipelineStat, error) {
+	return db.pipelineStats(f, "", "avg_duration_ms DESC, project_path", limit)
+}
+
+// InsertReportRun records a delivery of a scheduled report and sets its ID.
+func (db *DB) InsertReportRun(r *models.ReportRun) error {
+	err := db.conn.QueryRow(`
+		INSERT INTO report_runs (report, trigger, scheduled_for, range_from, range_to,
+		                         recipients, status, error, started_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10)
+		RETURNING id
+	`, r.Report, r.Trigger, r.ScheduledFor, r.RangeFrom, r.RangeTo,
+		r.Recipients, r.Status, r.Error, r.StartedAt, r.FinishedAt).Scan(&r.ID)
+	if err != nil {
+		return fmt.Errorf("insert report run %s failed: %w", r.Report, err)
+	}
+	return nil
+}
+
+// ListReportRuns returns the latest deliveries, of one report or of all when
+// report is empty.
+func (db *DB) ListReportRuns(report string, limit int) ([]models.ReportRun, error) {
+	var runs []models.ReportRun
+	err := db.conn.Select(&runs, `
+		SELECT id, report, trigger, scheduled_for, range_from, range_to, recipients,
+		       status, COALESCE(error, '') AS error, started_at, finished_at
+		FROM report_runs
+		WHERE $1 = '' OR report = $1
+		ORDER BY started_at DESC, id DESC
+		LIMIT $2
+	`, report, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list report runs failed: %w", err)
+	}
+	return runs, nil
+}
+
+// LastScheduledRun returns the cron time of the latest scheduled delivery of a
+// report, or nil.
+func (db *DB) LastScheduledRun(report string) (*time.Time, error) {
+	var last sql.NullTime
+	err := db.conn.Get(&last, `SELECT MAX(scheduled_for) FROM report_runs WHERE report = $1`, report)
+	if err != nil {
+		return nil, fmt.Errorf("last scheduled run of %s failed: %w", report, err)
+	}
+	if !last.Valid {
+		return nil, nil
+	}
+	return &last.Time, nil
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// GetFailedBuildLogs pages (by build id) through captured logs of FAILURE/UNSTABLE
+// builds started at or after since. With onlyUnclassified, builds that already
+// carry a failure_category are skipped.
+func (db *DB) GetFailedBuildLogs(afterID, limit int, onlyUnclassified bool, since time.Time) ([]models.BuildLog, error) {
+	var logs []models.BuildLog
+	err := db.conn.Select(&logs, `
+		SELECT l.build_id, l.build_number, COALESCE(l.project_name, '') AS project_name,
+		       COALESCE(l.console_log_head, '') AS console_log_head,
+		       COALESCE(l.console_log_tail, '') AS console_log_tail,
+		       l.console_log_gz, COALESCE(l.truncated, false) AS truncated
+		FROM builds b
+		JOIN build_logs l ON l.build_id = b.id
+		WHERE UPPER(b.status) IN ('FAILURE', 'UNSTABLE')
+		  AND COALESCE(l.fetch_error, '') = ''
+		  AND b.id > $1
+		  AND ($3 = false OR b.failure_category IS NULL)
+		  AND b.timestamp >= $4
+		ORDER BY b.id
+		LIMIT $2
+	`, afterID, limit, onlyUnclassified, since)
+	if err != nil {
+		return nil, fmt.Errorf("get failed build logs failed: %w", err)
+	}
+	return logs, nil
+}
+
+// SetFailureCategory records the classifier outcome for a build.
+func (db *DB) SetFailureCategory(buildID int, category, rule string) error {
+	_, err := db.conn.Exec(`
+		UPDATE builds
+		SET failure_category = $1, failure_rule = NULLIF($2, ''), classified_at = now()
+		WHERE id = $3
+	`, category, rule, buildID)
+	return err
+}
+
+// FailureCauseReport counts classified failures per ISO week, folder and category.
+func (db *DB) FailureCauseReport(from, to time.Time) ([]models.FailureCause, error) {
+	var rows []models.FailureCause
+	err := db.conn.Select(&rows, `
+		SELECT date_trunc('week', timestamp) AS week,
+		       regexp_replace(project_path, '/[^/]*$', '') AS folder,
+		       failure_category AS category,
+		       COUNT(*) AS count
+		FROM builds
+		WHERE timestamp BETWEEN $1 AND $2
+		  AND failure_category IS NOT NULL
+		GROUP BY 1, 2, 3
+		ORDER BY 1 DESC, 4 DESC, 2, 3
+	`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failure cause report failed: %w", err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// GetBuildsPendingIGRMCheck returns builds of env with an IGRM number that has
+// not been checked against the change-management system, newest first, only
+// those that targeted a deploy environment when deploysOnly is set. Builds
+// whose last lookup failed are skipped while backing off. An empty env checks
+// builds of every env.
+func (db *DB) GetBuildsPendingIGRMCheck(env string, deploysOnly bool, limit int) ([]*models.Build, error) {
+	where := ""
+	args := []interface{}{limit, CollectIGRM}
+	if env != "" {
+		args = append(args, env)
+		where += " AND env = $3"
+	}
+	if deploysOnly {
+		where += " AND deploy_env <> ''"
+	}
+	var builds []*models.Build
+	err := db.conn.Select(&builds, `
+		SELECT id, timestamp, igrm_no
+		FROM builds b
+		WHERE igrm_checked_at IS NULL AND igrm_no <> ''`+where+`
+		  AND `+notBackingOff(2)+`
+		ORDER BY timestamp DESC
+		LIMIT $1
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get builds pending igrm check failed: %w", err)
+	}
+	return builds, nil
+}
+
+// SetIGRMStatus stores the outcome of a build's IGRM check. Unless final is
+// set the build stays pending and is checked again.
+func (db *DB) SetIGRMStatus(b *models.Build, status, detail string, final bool) error {
+	_, err := db.conn.Exec(`
+		UPDATE builds
+		SET igrm_status = $3, igrm_detail = NULLIF($4, ''),
+		    igrm_checked_at = CASE WHEN $5 THEN now() END
+		WHERE id = $1 AND timestamp = $2
+	`, b.ID, b.Timestamp, status, detail, final)
+	if err != nil {
+		return fmt.Errorf("set igrm status of build %d failed: %w", b.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// GetBuildsMissingLogs returns finished builds of a controller that have no build_logs row yet, newest first,
+// skipping builds whose last fetch failed and is backing off.
+func (db *DB) GetBuildsMissingLogs(controller string, limit int) ([]*models.Build, error) {
+	var builds []*models.Build
+	err := db.conn.Select(&builds, `
+		SELECT b.id, b.build_number, COALESCE(b.project_name, '') AS project_name,
+		       COALESCE(b.job_url, '') AS job_url, b.status
+		FROM builds b
+		LEFT JOIN build_logs l ON l.build_id = b.id
+		WHERE l.id IS NULL
+		  AND COALESCE(b.status, '') <> ''
+		  AND b.controller = $1
+		  AND `+notBackingOff(3)+`
+		ORDER BY b.timestamp DESC
+		LIMIT $2
+	`, controller, limit, CollectLogs)
+	if err != nil {
+		return nil, fmt.Errorf("get builds missing logs failed: %w", err)
+	}
+	return builds, nil
+}
+
+// InsertBuildLog stores a captured console log, replacing any previous capture.
+func (db *DB) InsertBuildLog(l *models.BuildLog) error {
+	_, err := db.conn.NamedExec(`
+		INSERT INTO build_logs (
+			build_id, build_number, project_name, console_log_head, console_log_tail,
+			console_log_gz, size_bytes, truncated, fetch_error, fetched_at
+		)
+		VALUES (
+			:build_id, :build_number, :project_name, :console_log_head, :console_log_tail,
+			:console_log_gz, :size_bytes, :truncated, :fetch_error, now()
+		)
+		ON CONFLICT (build_id) DO UPDATE SET
+			console_log_head = EXCLUDED.console_log_head,
+			console_log_tail = EXCLUDED.console_log_tail,
+			console_log_gz   = EXCLUDED.console_log_gz,
+			size_bytes       = EXCLUDED.size_bytes,
+			truncated        = EXCLUDED.truncated,
+			fetch_error      = EXCLUDED.fetch_error,
+			fetched_at       = now()
+	`, l)
+	if err != nil {
+		return fmt.Errorf("insert build log failed: %w", err)
+	}
+	return nil
+}
+
+// GetBuildLog returns the captured log of a build; withFull controls whether
+// the compressed full copy is loaded too.
+func (db *DB) GetBuildLog(buildID int, withFull bool) (*models.BuildLog, error) {
+	gzCol := "NULL::bytea AS console_log_gz"
+	if withFull {
+		gzCol = "console_log_gz"
+	}
+
+	var l models.BuildLog
+	err := db.conn.Get(&l, fmt.Sprintf(`
+		SELECT id, build_id, build_number, COALESCE(project_name, '') AS project_name,
+		       COALESCE(console_log_head, '') AS console_log_head,
+		       COALESCE(console_log_tail, '') AS console_log_tail,
+		       %s, COALESCE(size_bytes, 0) AS size_bytes, COALESCE(truncated, false) AS truncated,
+		       COALESCE(fetch_error, '') AS fetch_error, fetched_at
+		FROM build_logs
+		WHERE build_id = $1
+	`, gzCol), buildID)
+	if err != nil {
+		return nil, fmt.Errorf("get build log failed: %w", err)
+	}
+	return &l, nil
+}
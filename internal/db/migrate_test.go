@@ -0,0 +1,71 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"002_indexes.up.sql":   {Data: []byte("CREATE INDEX i ON t (a);")},
+		"002_indexes.down.sql": {Data: []byte("DROP INDEX i;")},
+		"001_init.up.sql":      {Data: []byte("CREATE TABLE t (a INT);")},
+		"001_init.down.sql":    {Data: []byte("DROP TABLE t;")},
+	}
+
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("got %d migrations, want 2", len(migrations))
+	}
+	if m := migrations[0]; m.Version != 1 || m.Name != "init" || m.Down != "DROP TABLE t;" {
+		t.Errorf("first migration = %+v", m)
+	}
+	if m := migrations[1]; m.Version != 2 || m.Name != "indexes" {
+		t.Errorf("second migration = %+v", m)
+	}
+}
+
+func TestLoadMigrationsInvalid(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"missing down": {
+			"001_init.up.sql": {Data: []byte("CREATE TABLE t (a INT);")},
+		},
+		"duplicate version": {
+			"001_init.up.sql":    {Data: []byte("x")},
+			"001_init.down.sql":  {Data: []byte("x")},
+			"001_other.up.sql":   {Data: []byte("x")},
+			"001_other.down.sql": {Data: []byte("x")},
+		},
+		"no version": {
+			"init.up.sql":   {Data: []byte("x")},
+			"init.down.sql": {Data: []byte("x")},
+		},
+		"no direction": {
+			"001_init.sql": {Data: []byte("x")},
+		},
+	}
+	for name, fsys := range cases {
+		if _, err := LoadMigrations(fsys); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %s has version %d, want %d", m.Name, m.Version, i+1)
+		}
+		if strings.Contains(m.Up, `\connect`) {
+			t.Errorf("migration %03d_%s uses a psql meta-command", m.Version, m.Name)
+		}
+	}
+}
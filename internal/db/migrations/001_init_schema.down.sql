@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS sync_status;
+DROP TABLE IF EXISTS builds CASCADE;
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_builds_igrm_no_trgm;
+DROP INDEX IF EXISTS idx_builds_deploy_env_trgm;
+DROP INDEX IF EXISTS idx_builds_user_id_trgm;
+DROP INDEX IF EXISTS idx_builds_project_path_trgm;
+DROP INDEX IF EXISTS idx_builds_project_path_timestamp;
+DROP INDEX IF EXISTS idx_builds_user_timestamp;
+DROP INDEX IF EXISTS idx_builds_env_timestamp;
+DROP INDEX IF EXISTS idx_builds_status_timestamp;
+DROP INDEX IF EXISTS idx_builds_timestamp;
@@ -0,0 +1,16 @@
+-- Indexes backing the /builds/filter and /builds/export query builder (internal/db/query.go)
+-- Time range scans and the default ORDER BY timestamp
+CREATE INDEX IF NOT EXISTS idx_builds_timestamp ON builds (timestamp DESC, id DESC);
+
+-- Equality filters combined with a time range
+CREATE INDEX IF NOT EXISTS idx_builds_status_timestamp ON builds (status, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_builds_env_timestamp ON builds (env, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_builds_user_timestamp ON builds (user_id, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_builds_project_path_timestamp ON builds (project_path, timestamp DESC);
+
+-- Substring search (ILIKE '%term%') on search_by fields
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_builds_project_path_trgm ON builds USING gin (project_path gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_builds_user_id_trgm ON builds USING gin (user_id gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_builds_deploy_env_trgm ON builds USING gin (deploy_env gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_builds_igrm_no_trgm ON builds USING gin (igrm_no gin_trgm_ops);
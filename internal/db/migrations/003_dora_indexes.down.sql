@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_builds_commit_sha;
+DROP INDEX IF EXISTS idx_builds_deployments;
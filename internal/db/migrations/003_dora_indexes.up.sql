@@ -0,0 +1,6 @@
+-- Indexes backing the DORA metrics engine (internal/analytics/dora.go)
+-- Deployment scans: builds that carry a deploy_env parameter
+CREATE INDEX IF NOT EXISTS idx_builds_deployments ON builds (timestamp) WHERE deploy_env <> '';
+
+-- Lead time: earliest build containing a commit
+CREATE INDEX IF NOT EXISTS idx_builds_commit_sha ON builds (commit_sha, timestamp);
@@ -0,0 +1,14 @@
+-- Console logs captured by the log collector (internal/jenkins/logs.go)
+CREATE TABLE IF NOT EXISTS build_logs (
+    id SERIAL PRIMARY KEY,
+    build_id INT NOT NULL UNIQUE REFERENCES builds(id) ON DELETE CASCADE,
+    build_number INT NOT NULL,
+    project_name TEXT,
+    console_log_head TEXT,
+    console_log_tail TEXT,
+    console_log_gz BYTEA,          -- gzip of the full console text (up to LOG_MAX_BYTES)
+    size_bytes BIGINT DEFAULT 0,
+    truncated BOOLEAN DEFAULT false,
+    fetch_error TEXT,              -- set when Jenkins no longer has the log, so it is not retried
+    fetched_at TIMESTAMPTZ DEFAULT now()
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_builds_failure_category;
+ALTER TABLE builds DROP COLUMN IF EXISTS classified_at;
+ALTER TABLE builds DROP COLUMN IF EXISTS failure_rule;
+ALTER TABLE builds DROP COLUMN IF EXISTS failure_category;
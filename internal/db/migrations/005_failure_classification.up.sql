@@ -0,0 +1,7 @@
+-- Failure categories assigned by the rule-based classifier (internal/classify)
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS failure_category TEXT;
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS failure_rule TEXT;
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS classified_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_builds_failure_category ON builds (failure_category, timestamp)
+    WHERE failure_category IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_builds_stages_pending;
+ALTER TABLE builds DROP COLUMN IF EXISTS stages_fetched_at;
+DROP TABLE IF EXISTS build_stages;
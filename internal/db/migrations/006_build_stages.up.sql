@@ -0,0 +1,20 @@
+-- Pipeline stages collected from <build>/wfapi/describe (internal/jenkins/stages.go)
+CREATE TABLE IF NOT EXISTS build_stages (
+    id SERIAL PRIMARY KEY,
+    build_id INT NOT NULL REFERENCES builds(id) ON DELETE CASCADE,
+    stage_id TEXT NOT NULL,          -- flow node id from wfapi
+    name TEXT NOT NULL,
+    status TEXT,
+    start_time TIMESTAMPTZ,
+    duration_ms BIGINT DEFAULT 0,
+    pause_duration_ms BIGINT DEFAULT 0,
+    position INT NOT NULL DEFAULT 0, -- order of the stage within the run
+    UNIQUE (build_id, stage_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_build_stages_name ON build_stages (name);
+
+-- NULL until the stage collector has looked at the build; non-Pipeline jobs get a
+-- timestamp and no stage rows.
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS stages_fetched_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_builds_stages_pending ON builds (timestamp DESC) WHERE stages_fetched_at IS NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_builds_tests_pending;
+ALTER TABLE builds DROP COLUMN IF EXISTS tests_fetched_at;
+DROP TABLE IF EXISTS test_cases;
+DROP TABLE IF EXISTS test_reports;
@@ -0,0 +1,28 @@
+-- JUnit results collected from <build>/testReport/api/json (internal/jenkins/tests.go)
+CREATE TABLE IF NOT EXISTS test_reports (
+    build_id INT PRIMARY KEY REFERENCES builds(id) ON DELETE CASCADE,
+    total INT NOT NULL DEFAULT 0,
+    passed INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    skipped INT NOT NULL DEFAULT 0,
+    duration_ms BIGINT DEFAULT 0,
+    fetched_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS test_cases (
+    id BIGSERIAL PRIMARY KEY,
+    build_id INT NOT NULL REFERENCES builds(id) ON DELETE CASCADE,
+    suite TEXT,
+    class_name TEXT NOT NULL,
+    name TEXT NOT NULL,
+    status TEXT NOT NULL,            -- PASSED, FAILED or SKIPPED
+    duration_ms BIGINT DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_test_cases_build ON test_cases (build_id);
+CREATE INDEX IF NOT EXISTS idx_test_cases_test ON test_cases (class_name, name);
+
+-- NULL until the test collector has looked at the build; builds without a
+-- published report get a timestamp and no rows.
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS tests_fetched_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_builds_tests_pending ON builds (timestamp DESC) WHERE tests_fetched_at IS NULL;
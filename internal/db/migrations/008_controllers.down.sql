@@ -0,0 +1,5 @@
+-- Fails when two controllers stored the same build number of a job path.
+DROP INDEX IF EXISTS idx_builds_controller_ts;
+ALTER TABLE builds DROP CONSTRAINT IF EXISTS unique_controller_build_path;
+ALTER TABLE builds ADD CONSTRAINT unique_build_path UNIQUE (build_number, project_path);
+ALTER TABLE builds DROP COLUMN IF EXISTS controller;
@@ -0,0 +1,17 @@
+-- Multi-controller support: every build belongs to a Jenkins controller and
+-- (controller, build_number, project_path) identifies it.
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS controller TEXT NOT NULL DEFAULT 'default';
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'unique_controller_build_path'
+    ) THEN
+        ALTER TABLE builds DROP CONSTRAINT IF EXISTS unique_build_path;
+        ALTER TABLE builds
+        ADD CONSTRAINT unique_controller_build_path UNIQUE (controller, build_number, project_path);
+    END IF;
+END
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_builds_controller_ts ON builds (controller, timestamp DESC);
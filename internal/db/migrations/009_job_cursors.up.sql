@@ -0,0 +1,18 @@
+-- Per-job sync cursors: the highest build number stored for each job of a
+-- controller, so the incremental poller does not scan builds per job.
+CREATE TABLE IF NOT EXISTS job_cursors (
+    controller        TEXT NOT NULL,
+    project_path      TEXT NOT NULL,
+    last_build_number INT NOT NULL DEFAULT 0,
+    last_polled_at    TIMESTAMPTZ,
+    last_error        TEXT,
+    PRIMARY KEY (controller, project_path)
+);
+
+-- seed from builds stored before cursors existed
+INSERT INTO job_cursors (controller, project_path, last_build_number)
+SELECT controller, project_path, MAX(build_number)
+FROM builds
+WHERE project_path IS NOT NULL
+GROUP BY controller, project_path
+ON CONFLICT (controller, project_path) DO NOTHING;
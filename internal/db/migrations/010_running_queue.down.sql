@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS queue_items;
+DROP INDEX IF EXISTS idx_builds_building;
+ALTER TABLE builds DROP COLUMN IF EXISTS queue_wait_ms;
+ALTER TABLE builds DROP COLUMN IF EXISTS estimated_duration_ms;
+ALTER TABLE builds DROP COLUMN IF EXISTS queue_id;
+ALTER TABLE builds DROP COLUMN IF EXISTS building;
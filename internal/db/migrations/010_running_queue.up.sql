@@ -0,0 +1,26 @@
+-- Live state of running builds and queue wait time. duration_ms stays the
+-- execution time; queue_wait_ms is the time spent waiting for an executor.
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS building BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS queue_id BIGINT;
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS estimated_duration_ms BIGINT;
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS queue_wait_ms BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_builds_building ON builds (controller) WHERE building;
+
+-- Snapshot of the Jenkins build queue; left_at is set once an item is no
+-- longer queued (started or cancelled).
+CREATE TABLE IF NOT EXISTS queue_items (
+    controller     TEXT NOT NULL,
+    queue_id       BIGINT NOT NULL,
+    task_name      TEXT,
+    task_url       TEXT,
+    why            TEXT,
+    blocked        BOOLEAN NOT NULL DEFAULT false,
+    stuck          BOOLEAN NOT NULL DEFAULT false,
+    in_queue_since TIMESTAMPTZ NOT NULL,
+    last_seen_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    left_at        TIMESTAMPTZ,
+    PRIMARY KEY (controller, queue_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_queue_items_waiting ON queue_items (controller) WHERE left_at IS NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS agent_snapshots;
+DROP INDEX IF EXISTS idx_builds_built_on;
+ALTER TABLE builds DROP COLUMN IF EXISTS built_on;
@@ -0,0 +1,20 @@
+-- Agent utilisation: the agent each build ran on and periodic snapshots of the
+-- controller's nodes from /computer/api/json.
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS built_on TEXT;
+CREATE INDEX IF NOT EXISTS idx_builds_built_on ON builds (built_on, timestamp) WHERE built_on IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS agent_snapshots (
+    id                  BIGSERIAL PRIMARY KEY,
+    controller          TEXT NOT NULL,
+    node_name           TEXT NOT NULL,
+    labels              TEXT[] NOT NULL DEFAULT '{}',
+    num_executors       INT NOT NULL DEFAULT 0,
+    busy_executors      INT NOT NULL DEFAULT 0,
+    offline             BOOLEAN NOT NULL DEFAULT false,
+    temporarily_offline BOOLEAN NOT NULL DEFAULT false,
+    offline_reason      TEXT,
+    taken_at            TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_snapshots_node ON agent_snapshots (controller, node_name, taken_at DESC);
+CREATE INDEX IF NOT EXISTS idx_agent_snapshots_taken ON agent_snapshots (taken_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_builds_parameters;
+ALTER TABLE builds DROP COLUMN IF EXISTS causes;
+ALTER TABLE builds DROP COLUMN IF EXISTS parameters;
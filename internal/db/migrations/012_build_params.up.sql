@@ -0,0 +1,6 @@
+-- All build parameters and causes as JSONB, so new parameters need neither a
+-- column nor a backfill. The GIN index serves parameters @> '{"NAME":"value"}'.
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS parameters JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS causes JSONB NOT NULL DEFAULT '[]';
+
+CREATE INDEX IF NOT EXISTS idx_builds_parameters ON builds USING GIN (parameters jsonb_path_ops);
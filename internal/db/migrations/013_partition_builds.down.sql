@@ -0,0 +1,40 @@
+-- Back to a single builds table. Detached partitions are not restored.
+ALTER SEQUENCE builds_id_seq OWNED BY NONE;
+ALTER TABLE builds RENAME TO builds_partitioned;
+
+CREATE TABLE builds (LIKE builds_partitioned INCLUDING DEFAULTS);
+INSERT INTO builds SELECT * FROM builds_partitioned;
+DROP TABLE builds_partitioned;
+ALTER SEQUENCE builds_id_seq OWNED BY builds.id;
+
+ALTER TABLE builds ADD CONSTRAINT builds_pkey PRIMARY KEY (id);
+ALTER TABLE builds ADD CONSTRAINT unique_controller_build_path UNIQUE (controller, build_number, project_path);
+
+CREATE INDEX idx_builds_timestamp ON builds (timestamp DESC, id DESC);
+CREATE INDEX idx_builds_status_timestamp ON builds (status, timestamp DESC);
+CREATE INDEX idx_builds_env_timestamp ON builds (env, timestamp DESC);
+CREATE INDEX idx_builds_user_timestamp ON builds (user_id, timestamp DESC);
+CREATE INDEX idx_builds_project_path_timestamp ON builds (project_path, timestamp DESC);
+CREATE INDEX idx_builds_project_path_trgm ON builds USING gin (project_path gin_trgm_ops);
+CREATE INDEX idx_builds_user_id_trgm ON builds USING gin (user_id gin_trgm_ops);
+CREATE INDEX idx_builds_deploy_env_trgm ON builds USING gin (deploy_env gin_trgm_ops);
+CREATE INDEX idx_builds_igrm_no_trgm ON builds USING gin (igrm_no gin_trgm_ops);
+CREATE INDEX idx_builds_deployments ON builds (timestamp) WHERE deploy_env <> '';
+CREATE INDEX idx_builds_commit_sha ON builds (commit_sha, timestamp);
+CREATE INDEX idx_builds_failure_category ON builds (failure_category, timestamp)
+    WHERE failure_category IS NOT NULL;
+CREATE INDEX idx_builds_stages_pending ON builds (timestamp DESC) WHERE stages_fetched_at IS NULL;
+CREATE INDEX idx_builds_tests_pending ON builds (timestamp DESC) WHERE tests_fetched_at IS NULL;
+CREATE INDEX idx_builds_controller_ts ON builds (controller, timestamp DESC);
+CREATE INDEX idx_builds_building ON builds (controller) WHERE building;
+CREATE INDEX idx_builds_built_on ON builds (built_on, timestamp) WHERE built_on IS NOT NULL;
+CREATE INDEX idx_builds_parameters ON builds USING GIN (parameters jsonb_path_ops);
+
+DELETE FROM build_logs WHERE build_id NOT IN (SELECT id FROM builds);
+DELETE FROM build_stages WHERE build_id NOT IN (SELECT id FROM builds);
+DELETE FROM test_cases WHERE build_id NOT IN (SELECT id FROM builds);
+DELETE FROM test_reports WHERE build_id NOT IN (SELECT id FROM builds);
+ALTER TABLE build_logs ADD CONSTRAINT build_logs_build_id_fkey FOREIGN KEY (build_id) REFERENCES builds(id) ON DELETE CASCADE;
+ALTER TABLE build_stages ADD CONSTRAINT build_stages_build_id_fkey FOREIGN KEY (build_id) REFERENCES builds(id) ON DELETE CASCADE;
+ALTER TABLE test_reports ADD CONSTRAINT test_reports_build_id_fkey FOREIGN KEY (build_id) REFERENCES builds(id) ON DELETE CASCADE;
+ALTER TABLE test_cases ADD CONSTRAINT test_cases_build_id_fkey FOREIGN KEY (build_id) REFERENCES builds(id) ON DELETE CASCADE;
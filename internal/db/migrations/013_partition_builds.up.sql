@@ -0,0 +1,72 @@
+-- Monthly range partitions of builds on timestamp, so retention can drop or
+-- detach whole months. Unique keys of a partitioned table must contain the
+-- partition key, hence (id, timestamp) and the timestamp in the build key;
+-- build_keys (021) identifies a build regardless of its timestamp.
+--
+-- NOTE: this drops the foreign keys (and with them ON DELETE CASCADE) of
+-- build_logs, build_stages, test_reports and test_cases, as builds(id) alone
+-- is no longer unique. Deleting builds does not clean those tables any more;
+-- every delete path has to go through buildChildTables in
+-- internal/db/retention.go (DeleteBuilds, DropBuildPartition).
+DO $$
+DECLARE
+    m DATE;
+    last_month DATE;
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_partitioned_table WHERE partrelid = 'builds'::regclass) THEN
+        RETURN;
+    END IF;
+
+    ALTER TABLE build_logs DROP CONSTRAINT IF EXISTS build_logs_build_id_fkey;
+    ALTER TABLE build_stages DROP CONSTRAINT IF EXISTS build_stages_build_id_fkey;
+    ALTER TABLE test_reports DROP CONSTRAINT IF EXISTS test_reports_build_id_fkey;
+    ALTER TABLE test_cases DROP CONSTRAINT IF EXISTS test_cases_build_id_fkey;
+
+    -- the id sequence outlives the old table
+    ALTER SEQUENCE builds_id_seq OWNED BY NONE;
+    ALTER TABLE builds RENAME TO builds_unpartitioned;
+    UPDATE builds_unpartitioned SET timestamp = 'epoch' WHERE timestamp IS NULL;
+
+    CREATE TABLE builds (LIKE builds_unpartitioned INCLUDING DEFAULTS) PARTITION BY RANGE (timestamp);
+    ALTER TABLE builds ALTER COLUMN timestamp SET NOT NULL;
+    ALTER SEQUENCE builds_id_seq OWNED BY builds.id;
+
+    -- rows outside every monthly partition
+    CREATE TABLE builds_default PARTITION OF builds DEFAULT;
+
+    SELECT date_trunc('month', COALESCE(MIN(timestamp), now()))::date INTO m FROM builds_unpartitioned WHERE timestamp > 'epoch';
+    last_month := (date_trunc('month', now()) + interval '3 months')::date;
+    m := COALESCE(m, date_trunc('month', now())::date);
+    WHILE m <= last_month LOOP
+        EXECUTE format('CREATE TABLE %I PARTITION OF builds FOR VALUES FROM (%L) TO (%L)',
+            'builds_' || to_char(m, 'YYYY_MM'), m, (m + interval '1 month')::date);
+        m := (m + interval '1 month')::date;
+    END LOOP;
+
+    INSERT INTO builds SELECT * FROM builds_unpartitioned;
+    DROP TABLE builds_unpartitioned;
+
+    ALTER TABLE builds ADD CONSTRAINT builds_pkey PRIMARY KEY (id, timestamp);
+    ALTER TABLE builds ADD CONSTRAINT unique_controller_build_path UNIQUE (controller, build_number, project_path, timestamp);
+END
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_builds_timestamp ON builds (timestamp DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_builds_status_timestamp ON builds (status, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_builds_env_timestamp ON builds (env, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_builds_user_timestamp ON builds (user_id, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_builds_project_path_timestamp ON builds (project_path, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_builds_project_path_trgm ON builds USING gin (project_path gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_builds_user_id_trgm ON builds USING gin (user_id gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_builds_deploy_env_trgm ON builds USING gin (deploy_env gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_builds_igrm_no_trgm ON builds USING gin (igrm_no gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_builds_deployments ON builds (timestamp) WHERE deploy_env <> '';
+CREATE INDEX IF NOT EXISTS idx_builds_commit_sha ON builds (commit_sha, timestamp);
+CREATE INDEX IF NOT EXISTS idx_builds_failure_category ON builds (failure_category, timestamp)
+    WHERE failure_category IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_builds_stages_pending ON builds (timestamp DESC) WHERE stages_fetched_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_builds_tests_pending ON builds (timestamp DESC) WHERE tests_fetched_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_builds_controller_ts ON builds (controller, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_builds_building ON builds (controller) WHERE building;
+CREATE INDEX IF NOT EXISTS idx_builds_built_on ON builds (built_on, timestamp) WHERE built_on IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_builds_parameters ON builds USING GIN (parameters jsonb_path_ops);
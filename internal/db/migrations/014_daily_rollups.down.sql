@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS projects;
+DROP TABLE IF EXISTS rollup_dirty;
+DROP TABLE IF EXISTS build_daily_rollups;
@@ -0,0 +1,51 @@
+-- Daily rollups of builds per job, env and status for dashboards. Writes mark
+-- (day, controller, project_path) groups in rollup_dirty and the rollup worker
+-- recomputes them from builds, so percentiles and distinct users stay exact.
+CREATE TABLE IF NOT EXISTS build_daily_rollups (
+    day               DATE NOT NULL,
+    controller        TEXT NOT NULL,
+    project_path      TEXT NOT NULL,
+    env               TEXT NOT NULL,
+    status            TEXT NOT NULL,
+    builds            INT NOT NULL,
+    total_duration_ms BIGINT NOT NULL,
+    avg_duration_ms   BIGINT NOT NULL,
+    p50_duration_ms   BIGINT NOT NULL,
+    p95_duration_ms   BIGINT NOT NULL,
+    distinct_users    INT NOT NULL,
+    users             TEXT[] NOT NULL DEFAULT '{}', -- for distinct users across groups
+    refreshed_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (day, controller, project_path, env, status)
+);
+
+CREATE INDEX IF NOT EXISTS idx_rollups_project_day ON build_daily_rollups (project_path, day);
+
+CREATE TABLE IF NOT EXISTS rollup_dirty (
+    day          DATE NOT NULL,
+    controller   TEXT NOT NULL,
+    project_path TEXT NOT NULL,
+    marked_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (day, controller, project_path)
+);
+
+-- Every job that has builds, for the folder tree
+CREATE TABLE IF NOT EXISTS projects (
+    controller     TEXT NOT NULL,
+    project_path   TEXT NOT NULL,
+    first_build_on DATE NOT NULL,
+    last_build_on  DATE NOT NULL,
+    PRIMARY KEY (controller, project_path)
+);
+
+CREATE INDEX IF NOT EXISTS idx_projects_project_path ON projects (project_path);
+
+-- seed: every stored day is recomputed by the rollup worker
+INSERT INTO rollup_dirty (day, controller, project_path)
+SELECT DISTINCT timestamp::date, controller, project_path FROM builds
+ON CONFLICT DO NOTHING;
+
+INSERT INTO projects (controller, project_path, first_build_on, last_build_on)
+SELECT controller, project_path, MIN(timestamp)::date, MAX(timestamp)::date
+FROM builds
+GROUP BY controller, project_path
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_builds_changes_pending;
+ALTER TABLE builds DROP COLUMN IF EXISTS changes_fetched_at;
+DROP TABLE IF EXISTS build_changes;
@@ -0,0 +1,21 @@
+-- SCM commits of each build from changeSet (freestyle) or changeSets (Pipeline),
+-- i.e. the commits since the previous build of the job
+CREATE TABLE IF NOT EXISTS build_changes (
+    id         BIGSERIAL PRIMARY KEY,
+    build_id   INT NOT NULL,
+    commit_id  TEXT NOT NULL,
+    author     TEXT NOT NULL DEFAULT '',
+    message    TEXT NOT NULL DEFAULT '',
+    paths      TEXT[] NOT NULL DEFAULT '{}', -- affected files
+    kind       TEXT NOT NULL DEFAULT '',     -- git, svn, ...
+    position   INT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_build_changes_build ON build_changes (build_id);
+CREATE INDEX IF NOT EXISTS idx_build_changes_commit ON build_changes (commit_id text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_build_changes_author ON build_changes (author);
+
+-- NULL until the changes of the build are stored; builds without commits get
+-- a timestamp and no rows.
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS changes_fetched_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_builds_changes_pending ON builds (timestamp DESC) WHERE changes_fetched_at IS NULL;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_builds_igrm_norm;
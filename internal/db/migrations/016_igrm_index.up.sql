@@ -0,0 +1,2 @@
+-- IGRM compliance looks change tickets up case- and space-insensitively
+CREATE INDEX IF NOT EXISTS idx_builds_igrm_norm ON builds (UPPER(TRIM(igrm_no)));
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_builds_igrm_pending;
+ALTER TABLE builds DROP COLUMN IF EXISTS igrm_checked_at;
+ALTER TABLE builds DROP COLUMN IF EXISTS igrm_detail;
+ALTER TABLE builds DROP COLUMN IF EXISTS igrm_status;
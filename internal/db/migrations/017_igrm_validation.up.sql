@@ -0,0 +1,10 @@
+-- Outcome of checking igrm_no against the change-management system
+-- (internal/igrm): valid, not_found, not_approved, outside_window or error.
+-- igrm_checked_at stays NULL until a definite outcome is stored, so builds
+-- whose check failed are tried again.
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS igrm_status TEXT;
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS igrm_detail TEXT;
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS igrm_checked_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_builds_igrm_pending ON builds (timestamp DESC)
+    WHERE igrm_checked_at IS NULL AND igrm_no <> '';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_builds_alerts_pending;
+ALTER TABLE builds DROP COLUMN IF EXISTS alerts_checked_at;
+DROP TABLE IF EXISTS alerts;
@@ -0,0 +1,27 @@
+-- History of alerts fired by the rules in ALERT_RULES_FILE (internal/alerts).
+-- state is sent, failed (at least one channel failed) or silenced.
+CREATE TABLE IF NOT EXISTS alerts (
+    id           BIGSERIAL PRIMARY KEY,
+    rule         TEXT NOT NULL,
+    severity     TEXT NOT NULL DEFAULT 'warning',
+    dedup_key    TEXT NOT NULL,
+    build_id     INT,
+    controller   TEXT NOT NULL DEFAULT '',
+    project_path TEXT NOT NULL DEFAULT '',
+    title        TEXT NOT NULL,
+    message      TEXT NOT NULL DEFAULT '',
+    url          TEXT NOT NULL DEFAULT '',
+    channels     TEXT[] NOT NULL DEFAULT '{}',
+    state        TEXT NOT NULL,
+    error        TEXT,
+    fired_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_alerts_dedup ON alerts (dedup_key, fired_at DESC);
+CREATE INDEX IF NOT EXISTS idx_alerts_fired_at ON alerts (fired_at DESC);
+
+-- NULL until the alert rules have seen the finished build. Builds stored before
+-- this migration get its time so the first run does not alert on history.
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS alerts_checked_at TIMESTAMPTZ DEFAULT now();
+ALTER TABLE builds ALTER COLUMN alerts_checked_at DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_builds_alerts_pending ON builds (timestamp) WHERE alerts_checked_at IS NULL;
@@ -0,0 +1,17 @@
+-- Deliveries of the scheduled reports in REPORT_SCHEDULES_FILE (internal/reports).
+-- scheduled_for is the cron time of scheduled runs and NULL for "send now".
+CREATE TABLE IF NOT EXISTS report_runs (
+    id            BIGSERIAL PRIMARY KEY,
+    report        TEXT NOT NULL,
+    trigger       TEXT NOT NULL,             -- schedule or manual
+    scheduled_for TIMESTAMPTZ,
+    range_from    TIMESTAMPTZ NOT NULL,
+    range_to      TIMESTAMPTZ NOT NULL,
+    recipients    TEXT[] NOT NULL DEFAULT '{}',
+    status        TEXT NOT NULL,             -- sent or failed
+    error         TEXT,
+    started_at    TIMESTAMPTZ NOT NULL,
+    finished_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_runs_report ON report_runs (report, started_at DESC);
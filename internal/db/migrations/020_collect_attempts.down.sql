@@ -0,0 +1 @@
+DROP TABLE IF EXISTS collect_attempts;
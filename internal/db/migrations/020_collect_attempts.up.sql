@@ -0,0 +1,14 @@
+-- Failed fetches of the per-build collectors (logs, stages, tests). A build with
+-- a row here is skipped until next_attempt_at, so builds that keep failing do not
+-- hold up the newest-first batch. The row is removed once the fetch succeeds.
+CREATE TABLE IF NOT EXISTS collect_attempts (
+    collector       TEXT NOT NULL,            -- logs, stages, tests, changes or igrm
+    build_id        INT NOT NULL,
+    attempts        INT NOT NULL DEFAULT 1,
+    last_error      TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (collector, build_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collect_attempts_build ON collect_attempts (build_id);
@@ -0,0 +1,37 @@
+-- Identity of a build independent of its timestamp. The unique key of the
+-- partitioned builds table has to contain the partition key (see 013), so it
+-- only catches a build reported again with exactly the same timestamp.
+-- InsertBuild and UpsertBuild claim the key here first and reuse the stored
+-- timestamp, which keeps one row per build. Rows go when their build is
+-- deleted (buildChildTables in internal/db/retention.go).
+CREATE TABLE IF NOT EXISTS build_keys (
+    controller   TEXT NOT NULL,
+    project_path TEXT NOT NULL,
+    build_number INT NOT NULL,
+    timestamp    TIMESTAMP NOT NULL,
+    PRIMARY KEY (controller, project_path, build_number)
+);
+
+-- Copies stored with a drifted timestamp before this table existed: the first
+-- stored row of each build is kept, later copies go with their child rows.
+CREATE TEMP TABLE build_key_dupes ON COMMIT DROP AS
+SELECT id FROM (
+    SELECT id, row_number() OVER (
+        PARTITION BY controller, COALESCE(project_path, ''), build_number ORDER BY id
+    ) AS n
+    FROM builds
+) ranked
+WHERE n > 1;
+
+DELETE FROM build_logs WHERE build_id IN (SELECT id FROM build_key_dupes);
+DELETE FROM build_stages WHERE build_id IN (SELECT id FROM build_key_dupes);
+DELETE FROM test_cases WHERE build_id IN (SELECT id FROM build_key_dupes);
+DELETE FROM test_reports WHERE build_id IN (SELECT id FROM build_key_dupes);
+DELETE FROM build_changes WHERE build_id IN (SELECT id FROM build_key_dupes);
+DELETE FROM collect_attempts WHERE build_id IN (SELECT id FROM build_key_dupes);
+DELETE FROM builds WHERE id IN (SELECT id FROM build_key_dupes);
+
+INSERT INTO build_keys (controller, project_path, build_number, timestamp)
+SELECT controller, COALESCE(project_path, ''), build_number, timestamp
+FROM builds
+ON CONFLICT DO NOTHING;
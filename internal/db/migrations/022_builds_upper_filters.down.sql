@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_builds_upper_env_timestamp;
+DROP INDEX IF EXISTS idx_builds_upper_status_timestamp;
+
+CREATE INDEX IF NOT EXISTS idx_builds_status_timestamp ON builds (status, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_builds_env_timestamp ON builds (env, timestamp DESC);
@@ -0,0 +1,8 @@
+-- The query builder matches status and env case-insensitively (UPPER(col) =
+-- UPPER($n)), which the plain column indexes from 002 cannot serve. Nothing
+-- else filters on the bare columns, so the expression indexes replace them.
+DROP INDEX IF EXISTS idx_builds_status_timestamp;
+DROP INDEX IF EXISTS idx_builds_env_timestamp;
+
+CREATE INDEX IF NOT EXISTS idx_builds_upper_status_timestamp ON builds (UPPER(status), timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_builds_upper_env_timestamp ON builds (UPPER(env), timestamp DESC);
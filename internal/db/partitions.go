@@ -0,0 +1,140 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// partitionLayout names the monthly partitions of builds, e.g. builds_2025_01.
+const partitionLayout = "builds_2006_01"
+
+// BuildPartition is one monthly partition of builds covering [From, To).
+type BuildPartition struct {
+	Name string
+	From time.Time
+	To   time.Time
+}
+
+// PartitionFor names the partition holding builds of t's month.
+func PartitionFor(t time.Time) BuildPartition {
+	from := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return BuildPartition{Name: from.Format(partitionLayout), From: from, To: from.AddDate(0, 1, 0)}
+}
+
+// ListBuildPartitions returns the attached monthly partitions, oldest first.
+// The default partition is not included.
+func (db *DB) ListBuildPartitions() ([]BuildPartition, error) {
+	var names []string
+	err := db.conn.Select(&names, `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'builds'::regclass
+		ORDER BY c.relname
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list build partitions failed: %w", err)
+	}
+
+	var partitions []BuildPartition
+	for _, name := range names {
+		month, err := time.Parse(partitionLayout, name)
+		if err != nil {
+			continue // builds_default
+		}
+		partitions = append(partitions, PartitionFor(month))
+	}
+	return partitions, nil
+}
+
+// EnsureBuildPartitions creates the monthly partitions from from's month up to
+// monthsAhead months after the current one and returns the ones it created.
+func (db *DB) EnsureBuildPartitions(from time.Time, monthsAhead int) ([]string, error) {
+	existing, err := db.ListBuildPartitions()
+	if err != nil {
+		return nil, err
+	}
+	have := make(map[string]bool, len(existing))
+	for _, p := range existing {
+		have[p.Name] = true
+	}
+
+	var created []string
+	last := PartitionFor(time.Now().UTC().AddDate(0, monthsAhead, 0))
+	for p := PartitionFor(from); !p.From.After(last.From); p = PartitionFor(p.To) {
+		if have[p.Name] {
+			continue
+		}
+		_, err := db.conn.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF builds FOR VALUES FROM ('%s') TO ('%s')`,
+			pq.QuoteIdentifier(p.Name), p.From.Format("2006-01-02"), p.To.Format("2006-01-02")))
+		if err != nil {
+			return created, fmt.Errorf("create partition %s failed: %w", p.Name, err)
+		}
+		created = append(created, p.Name)
+	}
+	return created, nil
+}
+
+// CountPartitionBuilds returns the number of builds stored in a partition.
+func (db *DB) CountPartitionBuilds(p BuildPartition) (int, error) {
+	var n int
+	if err := db.conn.Get(&n, fmt.Sprintf(`SELECT count(*) FROM %s`, pq.QuoteIdentifier(p.Name))); err != nil {
+		return 0, fmt.Errorf("count partition %s failed: %w", p.Name, err)
+	}
+	return n, nil
+}
+
+// PartitionBuildIDs returns the ids of the builds stored in a partition.
+func (db *DB) PartitionBuildIDs(p BuildPartition) ([]int, error) {
+	var ids []int
+	if err := db.conn.Select(&ids, fmt.Sprintf(`SELECT id FROM %s ORDER BY id`, pq.QuoteIdentifier(p.Name))); err != nil {
+		return nil, fmt.Errorf("list builds of partition %s failed: %w", p.Name, err)
+	}
+	return ids, nil
+}
+
+// ExportPartition streams every build of a partition as a JSON object.
+func (db *DB) ExportPartition(p BuildPartition, emit func(id int, row []byte) error) error {
+	return db.exportBuilds(fmt.Sprintf(`SELECT b.id, row_to_json(b)::text FROM %s b ORDER BY b.timestamp, b.id`, pq.QuoteIdentifier(p.Name)), nil, emit)
+}
+
+// DropBuildPartition drops a partition together with the logs, stages, test
+// results, changes and build keys of its builds.
+func (db *DB) DropBuildPartition(p BuildPartition) error {
+	return db.expirePartition(p, fmt.Sprintf(`DROP TABLE %s`, pq.QuoteIdentifier(p.Name)))
+}
+
+// DetachBuildPartition turns a partition into a standalone table that queries
+// on builds no longer see, e.g. for archiving. Only the builds rows are kept:
+// the logs, stages, test results, changes and build keys of its builds are
+// deleted like on drop, so they do not linger without a build and a rebuilt
+// build of the same number can be stored again.
+func (db *DB) DetachBuildPartition(p BuildPartition) error {
+	return db.expirePartition(p, fmt.Sprintf(`ALTER TABLE builds DETACH PARTITION %s`, pq.QuoteIdentifier(p.Name)))
+}
+
+// expirePartition deletes the child rows and build keys of a partition's
+// builds, then runs stmt to drop or detach it, in one transaction.
+func (db *DB) expirePartition(p BuildPartition, stmt string) error {
+	tx, err := db.conn.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids := fmt.Sprintf(`SELECT id FROM %s`, pq.QuoteIdentifier(p.Name))
+	for _, table := range buildChildTables {
+		if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE build_id IN (%s)`, table, ids)); err != nil {
+			return fmt.Errorf("expire partition %s: delete %s failed: %w", p.Name, table, err)
+		}
+	}
+	if _, err := tx.Exec(deleteBuildKeysSQL(pq.QuoteIdentifier(p.Name), "")); err != nil {
+		return fmt.Errorf("expire partition %s: delete build keys failed: %w", p.Name, err)
+	}
+	if _, err := tx.Exec(stmt); err != nil {
+		return fmt.Errorf("expire partition %s failed: %w", p.Name, err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,313 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// buildColumns is the explicit column list scanned into models.Build.
+var buildColumns = []string{
+	"id",
+	"controller",
+	"build_number",
+	"project_name",
+	"project_path",
+	"user_id",
+	"status",
+	"timestamp",
+	"duration_ms",
+	"job_url",
+	"branch",
+	"git_url",
+	"commit_sha",
+	"deploy_env",
+	"trigger_type",
+	"env",
+	"COALESCE(igrm_no, '') AS igrm_no",
+	"COALESCE(igrm_status, '') AS igrm_status",
+	"COALESCE(igrm_detail, '') AS igrm_detail",
+	"COALESCE(failure_category, '') AS failure_category",
+	"COALESCE(failure_rule, '') AS failure_rule",
+	"building",
+	"COALESCE(queue_id, 0) AS queue_id",
+	"COALESCE(estimated_duration_ms, 0) AS estimated_duration_ms",
+	"COALESCE(queue_wait_ms, 0) AS queue_wait_ms",
+	"COALESCE(built_on, '') AS built_on",
+	"parameters",
+	"causes",
+}
+
+// sortColumns whitelists sortable fields -> actual DB column names
+var sortColumns = map[string]string{
+	"timestamp":   "timestamp",
+	"env":         "env",
+	"deploy_env":  "deploy_env",
+	"igrm_no":     "igrm_no",
+	"status":      "status",
+	"user_id":     "user_id",
+	"duration_ms": "duration_ms",
+}
+
+// searchColumns whitelists fields usable with SearchTerm
+var searchColumns = map[string]string{
+	"env":          "env",
+	"deploy_env":   "deploy_env",
+	"project_path": "project_path",
+	"user_id":      "user_id",
+	"igrm_no":      "igrm_no",
+}
+
+// BuildQuery describes a filtered, sorted and paginated view over the builds table.
+// Zero values mean "no filter"; a zero Limit returns every matching row.
+type BuildQuery struct {
+	From         time.Time
+	To           time.Time
+	SearchFields []string // any of searchColumns, matched case-insensitively against SearchTerm
+	SearchTerm   string
+	ProjectPath  string // exact match
+	Status       string
+	Env          string
+	UserID       string
+	Controller   string            // exact match, empty for all controllers
+	Params       map[string]string // build parameters that must all match exactly
+	Commit       string            // commit id prefix, built at or shipped in the changeSet
+	SortBy       string
+	Order        string
+	Limit        int
+	Offset       int
+
+	// Keyset cursor: when AfterID is set, only rows strictly after
+	// (AfterTime, AfterID) in the current sort order are returned.
+	// Only meaningful when sorting by timestamp.
+	AfterTime time.Time
+	AfterID   int
+}
+
+// IsSearchable reports whether field can be used in BuildQuery.SearchFields.
+func IsSearchable(field string) bool {
+	_, ok := searchColumns[field]
+	return ok
+}
+
+// ParamFilterPrefix marks a search term as a parameter filter: param.VERSION=1.4.2
+const ParamFilterPrefix = "param."
+
+// ParseParamFilter parses space-separated param.NAME=value terms. Values with
+// spaces are double-quoted: param.MESSAGE="fix login". Names and values are
+// case-sensitive, as in Jenkins.
+func ParseParamFilter(term string) (map[string]string, error) {
+	terms, err := splitParamTerms(term)
+	if err != nil {
+		return nil, err
+	}
+	params := make(map[string]string)
+	for _, f := range terms {
+		kv, ok := strings.CutPrefix(f, ParamFilterPrefix)
+		name, value, hasValue := strings.Cut(kv, "=")
+		if !ok || !hasValue || name == "" {
+			return nil, fmt.Errorf("invalid parameter filter %q, expected %sNAME=value", f, ParamFilterPrefix)
+		}
+		if strings.HasPrefix(value, `"`) {
+			if value, err = strconv.Unquote(value); err != nil {
+				return nil, fmt.Errorf("invalid quoted value in parameter filter %q", f)
+			}
+		}
+		params[name] = value
+	}
+	if len(params) == 0 {
+		return nil, fmt.Errorf("empty parameter filter")
+	}
+	return params, nil
+}
+
+// splitParamTerms splits on whitespace outside double quotes; a backslash
+// escapes the next character inside quotes.
+func splitParamTerms(term string) ([]string, error) {
+	var terms []string
+	var cur strings.Builder
+	quoted, escaped := false, false
+	for _, r := range term {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case !quoted && unicode.IsSpace(r):
+			if cur.Len() > 0 {
+				terms = append(terms, cur.String())
+				cur.Reset()
+			}
+			continue
+		}
+		cur.WriteRune(r)
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in parameter filter")
+	}
+	if cur.Len() > 0 {
+		terms = append(terms, cur.String())
+	}
+	return terms, nil
+}
+
+// FormatParamFilter renders params back into ParseParamFilter's syntax.
+func FormatParamFilter(params map[string]string) string {
+	terms := make([]string, 0, len(params))
+	for name, value := range params {
+		if strings.ContainsFunc(value, func(r rune) bool { return r == '"' || unicode.IsSpace(r) }) {
+			value = strconv.Quote(value)
+		}
+		terms = append(terms, ParamFilterPrefix+name+"="+value)
+	}
+	sort.Strings(terms)
+	return strings.Join(terms, " ")
+}
+
+// where renders the WHERE clause and its positional args.
+func (q BuildQuery) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	add := func(cond string, val interface{}) {
+		args = append(args, val)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if !q.From.IsZero() {
+		add("timestamp >= $%d", q.From)
+	}
+	if !q.To.IsZero() {
+		add("timestamp <= $%d", q.To)
+	}
+	if q.ProjectPath != "" {
+		add("project_path = $%d", q.ProjectPath)
+	}
+	if q.Status != "" {
+		add("UPPER(status) = UPPER($%d)", q.Status)
+	}
+	if q.Env != "" {
+		add("UPPER(env) = UPPER($%d)", q.Env)
+	}
+	if q.UserID != "" {
+		add("user_id = $%d", q.UserID)
+	}
+	if q.Controller != "" {
+		add("controller = $%d", q.Controller)
+	}
+	if len(q.Params) > 0 {
+		// containment is served by the GIN index on parameters
+		doc, _ := json.Marshal(q.Params)
+		add("parameters @> $%d::jsonb", string(doc))
+	}
+	if q.Commit != "" {
+		add("(commit_sha LIKE $%[1]d OR id IN (SELECT build_id FROM build_changes WHERE commit_id LIKE $%[1]d))",
+			escapeLike(strings.ToLower(q.Commit))+"%")
+	}
+
+	if q.AfterID > 0 {
+		cmp := "<"
+		if q.ascending() {
+			cmp = ">"
+		}
+		args = append(args, q.AfterTime, q.AfterID)
+		conds = append(conds, fmt.Sprintf("(timestamp, id) %s ($%d, $%d)", cmp, len(args)-1, len(args)))
+	}
+
+	term := strings.TrimSpace(q.SearchTerm)
+	if term != "" {
+		var ors []string
+		for _, f := range q.SearchFields {
+			col, ok := searchColumns[f]
+			if !ok {
+				continue
+			}
+			ors = append(ors, fmt.Sprintf("%s ILIKE $%d", col, len(args)+1))
+		}
+		if len(ors) > 0 {
+			args = append(args, "%"+escapeLike(term)+"%")
+			conds = append(conds, "("+strings.Join(ors, " OR ")+")")
+		}
+	}
+
+	if len(conds) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+// orderBy renders a whitelisted ORDER BY clause, id breaks ties for stable paging.
+func (q BuildQuery) orderBy() string {
+	col, ok := sortColumns[q.SortBy]
+	if !ok {
+		col = "timestamp"
+	}
+	order := "DESC"
+	if q.ascending() {
+		order = "ASC"
+	}
+	return fmt.Sprintf("ORDER BY %s %s, id %s", col, order, order)
+}
+
+func (q BuildQuery) ascending() bool {
+	return strings.ToUpper(q.Order) == "ASC"
+}
+
+// SelectSQL returns the parameterised page query for q.
+func (q BuildQuery) SelectSQL() (string, []interface{}) {
+	where, args := q.where()
+	query := fmt.Sprintf("SELECT %s FROM builds %s %s",
+		strings.Join(buildColumns, ", "), where, q.orderBy())
+
+	if q.Limit > 0 {
+		args = append(args, q.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if q.Offset > 0 {
+		args = append(args, q.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	return query, args
+}
+
+// CountSQL returns the COUNT(*) query matching SelectSQL's filters.
+func (q BuildQuery) CountSQL() (string, []interface{}) {
+	where, args := q.where()
+	return "SELECT COUNT(*) FROM builds " + where, args
+}
+
+// QueryBuilds returns one page of builds matching q.
+func (db *DB) QueryBuilds(q BuildQuery) ([]models.Build, error) {
+	query, args := q.SelectSQL()
+
+	var builds []models.Build
+	if err := db.conn.Select(&builds, query, args...); err != nil {
+		return nil, fmt.Errorf("QueryBuilds: %w", err)
+	}
+	return builds, nil
+}
+
+// CountBuildsMatching returns the total number of builds matching q, ignoring paging.
+func (db *DB) CountBuildsMatching(q BuildQuery) (int, error) {
+	query, args := q.CountSQL()
+
+	var count int
+	if err := db.conn.Get(&count, query, args...); err != nil {
+		return 0, fmt.Errorf("failed to count builds: %w", err)
+	}
+	return count, nil
+}
+
+// escapeLike escapes LIKE wildcards so user input is matched literally.
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
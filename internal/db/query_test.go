@@ -0,0 +1,110 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildQuerySelectSQL(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+
+	q := BuildQuery{
+		From:         from,
+		To:           to,
+		SearchFields: []string{"project_path", "user_id", "bogus"},
+		SearchTerm:   "app_1%",
+		Status:       "failure",
+		SortBy:       "duration_ms; DROP TABLE builds",
+		Order:        "asc",
+		Limit:        35,
+		Offset:       70,
+	}
+
+	query, args := q.SelectSQL()
+
+	wantParts := []string{
+		"WHERE timestamp >= $1 AND timestamp <= $2 AND UPPER(status) = UPPER($3)",
+		"(project_path ILIKE $4 OR user_id ILIKE $4)",
+		"ORDER BY timestamp ASC, id ASC",
+		"LIMIT $5 OFFSET $6",
+	}
+	for _, w := range wantParts {
+		if !strings.Contains(query, w) {
+			t.Errorf("query missing %q:\n%s", w, query)
+		}
+	}
+	if strings.Contains(query, "DROP") || strings.Contains(query, "bogus") {
+		t.Errorf("unsanitised input leaked into query: %s", query)
+	}
+	if len(args) != 6 {
+		t.Fatalf("expected 6 args, got %d: %v", len(args), args)
+	}
+	if args[3] != `%app\_1\%%` {
+		t.Errorf("search term not escaped: %v", args[3])
+	}
+
+	countQuery, countArgs := q.CountSQL()
+	if strings.Contains(countQuery, "LIMIT") || len(countArgs) != 4 {
+		t.Errorf("count query should not page: %s %v", countQuery, countArgs)
+	}
+}
+
+func TestBuildQueryNoFilters(t *testing.T) {
+	query, args := BuildQuery{}.SelectSQL()
+	if strings.Contains(query, "WHERE") || strings.Contains(query, "LIMIT") || len(args) != 0 {
+		t.Errorf("unexpected filters for empty query: %s %v", query, args)
+	}
+}
+
+func TestBuildQueryParams(t *testing.T) {
+	params, err := ParseParamFilter("param.VERSION=1.4.2  param.DRY_RUN=")
+	if err != nil {
+		t.Fatalf("ParseParamFilter failed: %v", err)
+	}
+	if params["VERSION"] != "1.4.2" || params["DRY_RUN"] != "" || len(params) != 2 {
+		t.Fatalf("unexpected params: %v", params)
+	}
+	if got := FormatParamFilter(params); got != "param.DRY_RUN= param.VERSION=1.4.2" {
+		t.Errorf("FormatParamFilter = %q", got)
+	}
+
+	params, err = ParseParamFilter(`param.MESSAGE="fix \"login\" page" param.VERSION=1.4.2`)
+	if err != nil {
+		t.Fatalf("ParseParamFilter with a quoted value failed: %v", err)
+	}
+	if params["MESSAGE"] != `fix "login" page` || params["VERSION"] != "1.4.2" || len(params) != 2 {
+		t.Fatalf("unexpected params: %v", params)
+	}
+	if got := FormatParamFilter(params); got != `param.MESSAGE="fix \"login\" page" param.VERSION=1.4.2` {
+		t.Errorf("FormatParamFilter = %q", got)
+	}
+	if again, err := ParseParamFilter(FormatParamFilter(params)); err != nil || again["MESSAGE"] != params["MESSAGE"] {
+		t.Errorf("round trip: got %v (%v)", again, err)
+	}
+
+	for _, bad := range []string{"", "VERSION=1", "param.VERSION", "param.=1", `param.MESSAGE="fix login`} {
+		if _, err := ParseParamFilter(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+
+	query, args := BuildQuery{Controller: "ci", Params: map[string]string{"VERSION": "1.4.2"}}.SelectSQL()
+	if !strings.Contains(query, "WHERE controller = $1 AND parameters @> $2::jsonb") {
+		t.Errorf("unexpected query: %s", query)
+	}
+	if len(args) != 2 || args[1] != `{"VERSION":"1.4.2"}` {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
+
+func TestBuildQueryCommit(t *testing.T) {
+	query, args := BuildQuery{Commit: "AB12"}.SelectSQL()
+	if !strings.Contains(query, "WHERE (commit_sha LIKE $1 OR id IN (SELECT build_id FROM build_changes WHERE commit_id LIKE $1))") {
+		t.Errorf("unexpected query: %s", query)
+	}
+	if len(args) != 1 || args[0] != "ab12%" {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// CreateRestoreTable creates an empty scratch table shaped like source, builds
+// or one of its archived child tables, without partitions, keys or defaults,
+// for loading archived rows.
+func (db *DB) CreateRestoreTable(table, source string) error {
+	if _, err := db.conn.Exec(fmt.Sprintf(`CREATE TABLE %s (LIKE %s)`, pq.QuoteIdentifier(table), pq.QuoteIdentifier(source))); err != nil {
+		return fmt.Errorf("create restore table %s failed: %w", table, err)
+	}
+	return nil
+}
+
+// InsertRestoredRows loads archived JSON rows into a scratch table. Keys that
+// are no longer columns are ignored and newer columns stay NULL.
+func (db *DB) InsertRestoredRows(table string, rows [][]byte) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	arr := make([]byte, 0, len(rows)*512)
+	arr = append(arr, '[')
+	for i, r := range rows {
+		if i > 0 {
+			arr = append(arr, ',')
+		}
+		arr = append(arr, r...)
+	}
+	arr = append(arr, ']')
+
+	t := pq.QuoteIdentifier(table)
+	query := fmt.Sprintf(`INSERT INTO %s SELECT * FROM json_populate_recordset(NULL::%s, $1::json)`, t, t)
+	if _, err := db.conn.Exec(query, string(arr)); err != nil {
+		return fmt.Errorf("insert restored rows into %s failed: %w", table, err)
+	}
+	return nil
+}
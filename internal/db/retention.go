@@ -0,0 +1,188 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// buildChildTables hold rows keyed by build_id that go with their build. They
+// have no foreign key to the partitioned builds table (see migration 013), so
+// every path deleting builds must clear them, and build_keys, explicitly.
+var buildChildTables = []string{"build_logs", "build_stages", "test_cases", "test_reports", "build_changes", "collect_attempts"}
+
+// ArchivedChildTables are the child tables archived with their builds;
+// collect_attempts is collector bookkeeping and not worth keeping.
+var ArchivedChildTables = []string{"build_logs", "build_stages", "test_cases", "test_reports", "build_changes"}
+
+// exportChildBatch bounds the build ids per child export query.
+const exportChildBatch = 5000
+
+// deleteBuildKeysSQL removes the build_keys entries of the builds in the given
+// relation (builds or one of its partitions), filtered by an optional condition.
+func deleteBuildKeysSQL(relation, where string) string {
+	if where == "" {
+		where = "TRUE"
+	}
+	return fmt.Sprintf(`
+		DELETE FROM build_keys k USING %s b
+		WHERE k.controller = b.controller
+		  AND k.project_path = COALESCE(b.project_path, '')
+		  AND k.build_number = b.build_number
+		  AND %s
+	`, relation, where)
+}
+
+// RetentionRule selects builds by env and folder; builds older than Cutoff
+// expire. A zero Cutoff keeps matching builds forever.
+type RetentionRule struct {
+	Env         string // canonical env, empty for any
+	Folder      string // project path prefix, empty for any
+	DeploysOnly bool   // only builds with a deploy_env parameter
+	Cutoff      time.Time
+}
+
+func (r RetentionRule) matchSQL(args *[]interface{}) string {
+	var conds []string
+	if r.Env != "" {
+		*args = append(*args, r.Env)
+		conds = append(conds, fmt.Sprintf("UPPER(COALESCE(env, '')) = UPPER($%d)", len(*args)))
+	}
+	if r.Folder != "" {
+		folder := strings.Trim(r.Folder, "/")
+		*args = append(*args, folder, folder+"/%")
+		conds = append(conds, fmt.Sprintf("(project_path = $%d OR project_path LIKE $%d)", len(*args)-1, len(*args)))
+	}
+	if r.DeploysOnly {
+		conds = append(conds, "COALESCE(deploy_env, '') <> ''")
+	}
+	if len(conds) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// expiredWhere selects builds expired under rules[i]: builds matching it but
+// no earlier rule, older than its cutoff. Rules are evaluated in order and the
+// first match decides.
+func expiredWhere(rules []RetentionRule, i int) (string, []interface{}) {
+	var args []interface{}
+	args = append(args, rules[i].Cutoff)
+	conds := []string{"timestamp < $1", rules[i].matchSQL(&args)}
+	for _, earlier := range rules[:i] {
+		conds = append(conds, "NOT ("+earlier.matchSQL(&args)+")")
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// CountExpiredBuilds counts the builds expired under rules[i] that started
+// at or after since.
+func (db *DB) CountExpiredBuilds(rules []RetentionRule, i int, since time.Time) (int, error) {
+	if rules[i].Cutoff.IsZero() {
+		return 0, nil
+	}
+	where, args := expiredWhere(rules, i)
+	args = append(args, since)
+	where += fmt.Sprintf(" AND timestamp >= $%d", len(args))
+	var n int
+	if err := db.conn.Get(&n, `SELECT count(*) FROM builds WHERE `+where, args...); err != nil {
+		return 0, fmt.Errorf("count expired builds failed: %w", err)
+	}
+	return n, nil
+}
+
+// DeleteExpiredBuilds deletes up to limit builds expired under rules[i], with
+// their logs, stages and test results, and returns how many it deleted.
+func (db *DB) DeleteExpiredBuilds(rules []RetentionRule, i, limit int) (int, error) {
+	if rules[i].Cutoff.IsZero() {
+		return 0, nil
+	}
+	where, args := expiredWhere(rules, i)
+	args = append(args, limit)
+
+	var ids []int
+	query := fmt.Sprintf(`SELECT id FROM builds WHERE %s ORDER BY timestamp LIMIT $%d`, where, len(args))
+	if err := db.conn.Select(&ids, query, args...); err != nil {
+		return 0, fmt.Errorf("select expired builds failed: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	if err := db.DeleteBuilds(ids, rules[i].Cutoff); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// DeleteBuilds deletes builds started before the given time, with their logs,
+// stages, test results, changes and build keys.
+func (db *DB) DeleteBuilds(ids []int, before time.Time) error {
+	tx, err := db.conn.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range buildChildTables {
+		if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE build_id = ANY($1)`, table), pq.Array(ids)); err != nil {
+			return fmt.Errorf("delete expired %s failed: %w", table, err)
+		}
+	}
+	if _, err := tx.Exec(deleteBuildKeysSQL("builds", "b.id = ANY($1) AND b.timestamp < $2"), pq.Array(ids), before); err != nil {
+		return fmt.Errorf("delete expired build keys failed: %w", err)
+	}
+	// the time bound lets the delete skip partitions that cannot hold them
+	if _, err := tx.Exec(`DELETE FROM builds WHERE id = ANY($1) AND timestamp < $2`, pq.Array(ids), before); err != nil {
+		return fmt.Errorf("delete expired builds failed: %w", err)
+	}
+	return tx.Commit()
+}
+
+// ExportExpiredBuilds streams every build expired under rules[i] as a JSON
+// object, oldest first.
+func (db *DB) ExportExpiredBuilds(rules []RetentionRule, i int, emit func(id int, row []byte) error) error {
+	if rules[i].Cutoff.IsZero() {
+		return nil
+	}
+	where, args := expiredWhere(rules, i)
+	return db.exportBuilds(`SELECT b.id, row_to_json(b)::text FROM builds b WHERE `+where+` ORDER BY b.timestamp, b.id`, args, emit)
+}
+
+// ExportBuildChildren streams the rows of a child table belonging to the given
+// builds as JSON objects, keyed by build id.
+func (db *DB) ExportBuildChildren(table string, ids []int, emit func(id int, row []byte) error) error {
+	for start := 0; start < len(ids); start += exportChildBatch {
+		end := start + exportChildBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		query := fmt.Sprintf(`SELECT t.build_id, row_to_json(t)::text FROM %s t WHERE t.build_id = ANY($1) ORDER BY t.build_id`, pq.QuoteIdentifier(table))
+		if err := db.exportBuilds(query, []interface{}{pq.Array(ids[start:end])}, emit); err != nil {
+			return fmt.Errorf("%s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func (db *DB) exportBuilds(query string, args []interface{}, emit func(id int, row []byte) error) error {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("export builds failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var row []byte
+		if err := rows.Scan(&id, &row); err != nil {
+			return fmt.Errorf("export builds: scan failed: %w", err)
+		}
+		if err := emit(id, row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,326 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// rollupSelect recomputes build_daily_rollups rows from the builds joined as b.
+const rollupSelect = `
+	SELECT b.timestamp::date, b.controller, b.project_path, COALESCE(b.env, ''), COALESCE(b.status, ''),
+	       count(*),
+	       COALESCE(sum(b.duration_ms), 0),
+	       COALESCE(avg(b.duration_ms), 0)::bigint,
+	       COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY b.duration_ms), 0)::bigint,
+	       COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY b.duration_ms), 0)::bigint,
+	       count(DISTINCT b.user_id),
+	       COALESCE(array_agg(DISTINCT b.user_id) FILTER (WHERE b.user_id IS NOT NULL), '{}')`
+
+const rollupInsert = `
+	INSERT INTO build_daily_rollups (day, controller, project_path, env, status, builds,
+		total_duration_ms, avg_duration_ms, p50_duration_ms, p95_duration_ms, distinct_users, users)`
+
+// markRollupDirty queues the rollups of a build's day and job for recomputation.
+func (db *DB) markRollupDirty(ts time.Time, controller, projectPath string) {
+	_, err := db.conn.Exec(`
+		INSERT INTO rollup_dirty (day, controller, project_path, marked_at)
+		VALUES ($1::timestamp::date, $2, $3, now())
+		ON CONFLICT (day, controller, project_path) DO UPDATE SET marked_at = EXCLUDED.marked_at
+	`, ts, controller, projectPath)
+	if err != nil {
+		log.Printf("[Rollups] mark %s %s dirty failed: %v", controller, projectPath, err)
+	}
+}
+
+// markRollupsDirty queues the rollups of the given builds for recomputation.
+func markRollupsDirty(e sqlx.Execer, ids []int) {
+	_, err := e.Exec(`
+		INSERT INTO rollup_dirty (day, controller, project_path, marked_at)
+		SELECT DISTINCT timestamp::date, controller, project_path, now() FROM builds WHERE id = ANY($1)
+		ON CONFLICT (day, controller, project_path) DO UPDATE SET marked_at = EXCLUDED.marked_at
+	`, pq.Array(ids))
+	if err != nil {
+		log.Printf("[Rollups] mark %d builds dirty failed: %v", len(ids), err)
+	}
+}
+
+// RefreshDirtyRollups recomputes up to limit queued day/job groups and returns
+// how many it dequeued. A group marked again while it is recomputed stays
+// queued for the next run. Days up to retainedFrom's may have lost builds to
+// retention, so their rollups are kept as they are and the groups only
+// dequeued; zero retainedFrom recomputes every day.
+func (db *DB) RefreshDirtyRollups(limit int, retainedFrom time.Time) (int, error) {
+	tx, err := db.conn.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var dirty []struct {
+		Day         time.Time `db:"day"`
+		Controller  string    `db:"controller"`
+		ProjectPath string    `db:"project_path"`
+	}
+	err = tx.Select(&dirty, `
+		SELECT day, controller, project_path FROM rollup_dirty
+		ORDER BY day
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("select dirty rollups failed: %w", err)
+	}
+	if len(dirty) == 0 {
+		return 0, nil
+	}
+
+	var days, controllers, paths []string
+	var keptDays, keptControllers, keptPaths []string
+	for _, d := range dirty {
+		if rollupRetained(d.Day, retainedFrom) {
+			keptDays = append(keptDays, d.Day.Format("2006-01-02"))
+			keptControllers = append(keptControllers, d.Controller)
+			keptPaths = append(keptPaths, d.ProjectPath)
+			continue
+		}
+		days = append(days, d.Day.Format("2006-01-02"))
+		controllers = append(controllers, d.Controller)
+		paths = append(paths, d.ProjectPath)
+	}
+	groups := `unnest($1::date[], $2::text[], $3::text[]) AS g(day, controller, project_path)`
+	args := []interface{}{pq.Array(days), pq.Array(controllers), pq.Array(paths)}
+
+	_, err = tx.Exec(`
+		DELETE FROM build_daily_rollups r USING `+groups+`
+		WHERE r.day = g.day AND r.controller = g.controller AND r.project_path = g.project_path
+	`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("clear dirty rollups failed: %w", err)
+	}
+
+	_, err = tx.Exec(rollupInsert+rollupSelect+`
+		FROM `+groups+`
+		JOIN builds b ON b.controller = g.controller AND b.project_path = g.project_path
+		             AND b.timestamp >= g.day AND b.timestamp < g.day + 1
+		GROUP BY 1, 2, 3, 4, 5
+	`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("recompute rollups failed: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO projects (controller, project_path, first_build_on, last_build_on)
+		SELECT controller, project_path, MIN(day), MAX(day) FROM `+groups+`
+		WHERE EXISTS (SELECT 1 FROM build_daily_rollups r
+		              WHERE r.day = g.day AND r.controller = g.controller AND r.project_path = g.project_path)
+		GROUP BY controller, project_path
+		ON CONFLICT (controller, project_path) DO UPDATE SET
+			first_build_on = LEAST(projects.first_build_on, EXCLUDED.first_build_on),
+			last_build_on  = GREATEST(projects.last_build_on, EXCLUDED.last_build_on)
+	`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("update projects failed: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM rollup_dirty d USING `+groups+`
+		WHERE d.day = g.day AND d.controller = g.controller AND d.project_path = g.project_path
+	`, pq.Array(append(days, keptDays...)), pq.Array(append(controllers, keptControllers...)), pq.Array(append(paths, keptPaths...)))
+	if err != nil {
+		return 0, fmt.Errorf("dequeue dirty rollups failed: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	if len(keptDays) > 0 {
+		log.Printf("[Rollups] Kept rollups of %d day/job groups retention may have cut short", len(keptDays))
+	}
+	return len(dirty), nil
+}
+
+// rollupRetained reports whether retention may have removed builds of day, a
+// date read back as UTC midnight: it is on or before the date of retainedFrom.
+func rollupRetained(day, retainedFrom time.Time) bool {
+	if retainedFrom.IsZero() {
+		return false
+	}
+	last := time.Date(retainedFrom.Year(), retainedFrom.Month(), retainedFrom.Day(), 0, 0, 0, 0, time.UTC)
+	return !day.After(last)
+}
+
+// RebuildRollups recomputes the rollups from since on from builds, and the
+// project catalogue from the rollups. Rollups are only replaced where builds
+// are left to recompute them from: per controller, job and env from the first
+// day that still has builds, so aggregates of days retention already removed
+// are kept. That first day is kept too when it has rollups, as retention may
+// have removed part of it.
+func (db *DB) RebuildRollups(since time.Time) (int, error) {
+	tx, err := db.conn.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var groups []struct {
+		Controller  string    `db:"controller"`
+		ProjectPath string    `db:"project_path"`
+		Env         string    `db:"env"`
+		FirstDay    time.Time `db:"first_day"`
+		RolledUp    bool      `db:"rolled_up"`
+	}
+	err = tx.Select(&groups, `
+		SELECT f.controller, f.project_path, f.env, f.first_day,
+		       EXISTS (SELECT 1 FROM build_daily_rollups r
+		               WHERE r.day = f.first_day AND r.controller = f.controller
+		                 AND r.project_path = f.project_path AND r.env = f.env) AS rolled_up
+		FROM (
+			SELECT controller, project_path, COALESCE(env, '') AS env, MIN(timestamp)::date AS first_day
+			FROM builds
+			GROUP BY 1, 2, 3
+		) f
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("find rollup groups failed: %w", err)
+	}
+
+	controllers := make([]string, len(groups))
+	paths := make([]string, len(groups))
+	envs := make([]string, len(groups))
+	starts := make([]string, len(groups))
+	for i, g := range groups {
+		controllers[i], paths[i], envs[i] = g.Controller, g.ProjectPath, g.Env
+		starts[i] = rebuildStart(since, g.FirstDay, g.RolledUp).Format("2006-01-02")
+	}
+	rebuild := `unnest($1::text[], $2::text[], $3::text[], $4::date[]) AS g(controller, project_path, env, start_day)`
+	args := []interface{}{pq.Array(controllers), pq.Array(paths), pq.Array(envs), pq.Array(starts)}
+
+	_, err = tx.Exec(`
+		DELETE FROM build_daily_rollups r USING `+rebuild+`
+		WHERE r.controller = g.controller AND r.project_path = g.project_path AND r.env = g.env
+		  AND r.day >= g.start_day
+	`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("clear rollups failed: %w", err)
+	}
+	res, err := tx.Exec(rollupInsert+rollupSelect+`
+		FROM `+rebuild+`
+		JOIN builds b ON b.controller = g.controller AND b.project_path = g.project_path
+		             AND COALESCE(b.env, '') = g.env AND b.timestamp >= g.start_day
+		GROUP BY 1, 2, 3, 4, 5
+	`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("rebuild rollups failed: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM rollup_dirty WHERE day >= $1::timestamp::date`, since); err != nil {
+		return 0, fmt.Errorf("clear dirty rollups failed: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO projects (controller, project_path, first_build_on, last_build_on)
+		SELECT controller, project_path, MIN(day), MAX(day) FROM build_daily_rollups
+		GROUP BY controller, project_path
+		ON CONFLICT (controller, project_path) DO UPDATE SET
+			first_build_on = EXCLUDED.first_build_on,
+			last_build_on  = EXCLUDED.last_build_on
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("rebuild projects failed: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// rebuildStart is the first day whose rollups of a group are recomputed: since,
+// but not before the group's first day with builds. That day itself is only
+// recomputed when it has no rollups yet, as retention may have cut it short.
+func rebuildStart(since, firstDay time.Time, firstDayRolledUp bool) time.Time {
+	first := time.Date(firstDay.Year(), firstDay.Month(), firstDay.Day(), 0, 0, 0, 0, time.UTC)
+	if firstDayRolledUp {
+		first = first.AddDate(0, 0, 1)
+	}
+	day := time.Date(since.Year(), since.Month(), since.Day(), 0, 0, 0, 0, time.UTC)
+	if day.Before(first) {
+		return first
+	}
+	return day
+}
+
+// RollupFilter narrows the rollup reports; empty fields match everything.
+type RollupFilter struct {
+	From, To   time.Time
+	Folder     string // project path prefix
+	Env        string
+	Controller string
+}
+
+func (f RollupFilter) where() (string, []interface{}) {
+	args := []interface{}{f.From, f.To}
+	conds := []string{"day >= $1::timestamp::date", "day <= $2::timestamp::date"}
+	if folder := strings.Trim(f.Folder, "/"); folder != "" {
+		args = append(args, folder, escapeLike(folder)+"/%")
+		conds = append(conds, fmt.Sprintf("(project_path = $%d OR project_path LIKE $%d)", len(args)-1, len(args)))
+	}
+	if f.Env != "" {
+		args = append(args, f.Env)
+		conds = append(conds, fmt.Sprintf("env = $%d", len(args)))
+	}
+	if f.Controller != "" {
+		args = append(args, f.Controller)
+		conds = append(conds, fmt.Sprintf("controller = $%d", len(args)))
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// rollupTotals are the sums shared by the rollup reports. Percentiles cannot be
+// combined from daily rows, so only the worst daily p95 of a single job is
+// reported, labelled as such.
+const rollupTotals = `
+	SUM(r.builds) AS builds,
+	COALESCE(SUM(r.builds) FILTER (WHERE r.status IN ('FAILURE', 'UNSTABLE')), 0) AS failures,
+	SUM(r.total_duration_ms) AS total_duration_ms,
+	(SUM(r.total_duration_ms) / NULLIF(SUM(r.builds), 0))::bigint AS avg_duration_ms,
+	MAX(r.p95_duration_ms) AS max_daily_p95_ms`
+
+// DailyRollups returns one row per day with builds matching f.
+func (db *DB) DailyRollups(f RollupFilter) ([]models.DailyRollup, error) {
+	where, args := f.where()
+	var rows []models.DailyRollup
+	err := db.conn.Select(&rows, `
+		WITH r AS (SELECT * FROM build_daily_rollups WHERE `+where+`),
+		     u AS (SELECT day, count(DISTINCT usr) AS users FROM r, unnest(r.users) AS usr GROUP BY day)
+		SELECT r.day, `+rollupTotals+`, COALESCE(MAX(u.users), 0) AS users
+		FROM r LEFT JOIN u ON u.day = r.day
+		GROUP BY r.day
+		ORDER BY r.day
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("daily rollups failed: %w", err)
+	}
+	return rows, nil
+}
+
+// FolderRollups returns the totals of each top-level folder with builds matching f.
+func (db *DB) FolderRollups(f RollupFilter) ([]models.FolderRollup, error) {
+	where, args := f.where()
+	var rows []models.FolderRollup
+	err := db.conn.Select(&rows, `
+		WITH r AS (SELECT *, split_part(project_path, '/', 1) AS folder FROM build_daily_rollups WHERE `+where+`),
+		     u AS (SELECT folder, count(DISTINCT usr) AS users FROM r, unnest(r.users) AS usr GROUP BY folder)
+		SELECT r.folder, `+rollupTotals+`, COALESCE(MAX(u.users), 0) AS users
+		FROM r LEFT JOIN u ON u.folder = r.folder
+		GROUP BY r.folder
+		ORDER BY builds DESC
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("folder rollups failed: %w", err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRebuildStart(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	cases := []struct {
+		name     string
+		since    time.Time
+		firstDay time.Time
+		rolledUp bool
+		want     string
+	}{
+		// rollups before the oldest surviving build were made from builds retention removed
+		{"zero since keeps older rollups", time.Time{}, day("2025-03-10"), true, "2025-03-11"},
+		{"first day without rollups is built", time.Time{}, day("2025-03-10"), false, "2025-03-10"},
+		{"since after the first day", day("2025-04-01"), day("2025-03-10"), true, "2025-04-01"},
+		{"since on the first day", day("2025-03-10"), day("2025-03-10"), true, "2025-03-11"},
+		{"since before the first day", day("2025-01-01"), day("2025-03-10"), false, "2025-03-10"},
+		{"since with a time of day", time.Date(2025, 4, 1, 15, 30, 0, 0, time.UTC), day("2025-03-10"), true, "2025-04-01"},
+	}
+	for _, tc := range cases {
+		if got := rebuildStart(tc.since, tc.firstDay, tc.rolledUp).Format("2006-01-02"); got != tc.want {
+			t.Errorf("%s: rebuildStart = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRollupFilterWhere(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := RollupFilter{From: from, To: from.AddDate(0, 1, 0), Folder: "/team_a/", Env: "PROD", Controller: "eu"}
+
+	where, args := f.where()
+	for _, want := range []string{
+		"day >= $1::timestamp::date",
+		"(project_path = $3 OR project_path LIKE $4)",
+		"env = $5",
+		"controller = $6",
+	} {
+		if !strings.Contains(where, want) {
+			t.Errorf("expected %q in %s", want, where)
+		}
+	}
+	if len(args) != 6 || args[2] != "team_a" || args[3] != `team\_a/%` {
+		t.Errorf("unexpected args %v", args)
+	}
+
+	if where, args := (RollupFilter{From: from, To: from}).where(); len(args) != 2 || strings.Contains(where, "project_path") {
+		t.Errorf("empty filters must only bound the days, got %s %v", where, args)
+	}
+}
+
+func TestRollupRetained(t *testing.T) {
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name         string
+		retainedFrom time.Time
+		want         bool
+	}{
+		{"no retention", time.Time{}, false},
+		{"cutoff later that day", time.Date(2025, 3, 10, 14, 0, 0, 0, time.Local), true},
+		{"cutoff on a later day", time.Date(2025, 6, 1, 0, 0, 0, 0, time.Local), true},
+		{"cutoff the day before", time.Date(2025, 3, 9, 23, 0, 0, 0, time.Local), false},
+	}
+	for _, tc := range cases {
+		if got := rollupRetained(day, tc.retainedFrom); got != tc.want {
+			t.Errorf("%s: rollupRetained = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/lib/pq"
+)
+
+// GetRunningBuilds returns builds of a controller stored while still running.
+// An empty controller returns the running builds of all controllers.
+func (db *DB) GetRunningBuilds(controller string) ([]models.Build, error) {
+	var builds []models.Build
+	err := db.conn.Select(&builds, fmt.Sprintf(`
+		SELECT %s FROM builds
+		WHERE building AND ($1 = '' OR controller = $1)
+		ORDER BY timestamp
+	`, strings.Join(buildColumns, ", ")), controller)
+	if err != nil {
+		return nil, fmt.Errorf("get running builds failed: %w", err)
+	}
+	return builds, nil
+}
+
+// FinishBuild moves a running build to its final state. An empty status keeps
+// the stored one, e.g. when the build disappeared from Jenkins.
+func (db *DB) FinishBuild(id int, status string, durationMS, queueWaitMS int64) error {
+	_, err := db.conn.Exec(`
+		UPDATE builds
+		SET building      = false,
+		    status        = COALESCE(NULLIF($2, ''), status),
+		    duration_ms   = GREATEST($3, duration_ms),
+		    queue_wait_ms = COALESCE(NULLIF($4, 0), queue_wait_ms)
+		WHERE id = $1
+	`, id, status, durationMS, queueWaitMS)
+	if err != nil {
+		return fmt.Errorf("finish build %d failed: %w", id, err)
+	}
+	markRollupsDirty(db.conn, []int{id})
+	return nil
+}
+
+// SaveQueueItems stores the current queue of a controller and marks items that
+// are no longer queued as left.
+func (db *DB) SaveQueueItems(controller string, items []models.QueueItem) error {
+	tx, err := db.conn.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin tx failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	ids := make([]int64, 0, len(items))
+	for _, q := range items {
+		q.Controller = controller
+		if _, err := tx.NamedExec(`
+			INSERT INTO queue_items (controller, queue_id, task_name, task_url, why, blocked, stuck, in_queue_since, last_seen_at)
+			VALUES (:controller, :queue_id, :task_name, :task_url, :why, :blocked, :stuck, :in_queue_since, now())
+			ON CONFLICT (controller, queue_id) DO UPDATE SET
+				why          = EXCLUDED.why,
+				blocked      = EXCLUDED.blocked,
+				stuck        = EXCLUDED.stuck,
+				last_seen_at = now(),
+				left_at      = NULL
+		`, q); err != nil {
+			return fmt.Errorf("save queue item %d failed: %w", q.QueueID, err)
+		}
+		ids = append(ids, q.QueueID)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE queue_items SET left_at = now()
+		WHERE controller = $1 AND left_at IS NULL AND NOT (queue_id = ANY($2))
+	`, controller, pq.Array(ids)); err != nil {
+		return fmt.Errorf("mark left queue items failed: %w", err)
+	}
+
+	// keep a week of left items to match builds that start late
+	if _, err := tx.Exec(`
+		DELETE FROM queue_items WHERE controller = $1 AND left_at < $2
+	`, controller, time.Now().AddDate(0, 0, -7)); err != nil {
+		return fmt.Errorf("prune queue items failed: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetQueuedItems returns items still waiting in the queue, longest waiting first.
+func (db *DB) GetQueuedItems() ([]models.QueueItem, error) {
+	var items []models.QueueItem
+	err := db.conn.Select(&items, `
+		SELECT controller, queue_id, COALESCE(task_name, '') AS task_name, COALESCE(task_url, '') AS task_url,
+		       COALESCE(why, '') AS why, blocked, stuck, in_queue_since, last_seen_at, left_at
+		FROM queue_items
+		WHERE left_at IS NULL
+		ORDER BY in_queue_since
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("get queued items failed: %w", err)
+	}
+	return items, nil
+}
+
+// ApplyQueueWaits fills queue_wait_ms of builds without the metrics plugin's
+// value from the time their queue item was first seen. The wait is computed
+// here rather than in SQL: builds.timestamp is a TIMESTAMP holding the app's
+// local wall clock while in_queue_since is a TIMESTAMPTZ, so subtracting them
+// in Postgres is off by the UTC offset whenever the two zones differ. The
+// results are written back in one statement.
+func (db *DB) ApplyQueueWaits(controller string) (int64, error) {
+	var pending []struct {
+		ID           int       `db:"id"`
+		Timestamp    time.Time `db:"timestamp"`
+		InQueueSince time.Time `db:"in_queue_since"`
+	}
+	err := db.conn.Select(&pending, `
+		SELECT b.id, b.timestamp, q.in_queue_since
+		FROM builds b
+		JOIN queue_items q ON q.controller = b.controller AND q.queue_id = b.queue_id
+		WHERE b.queue_wait_ms IS NULL
+		  AND b.controller = $1
+	`, controller)
+	if err != nil {
+		return 0, fmt.Errorf("apply queue waits failed: %w", err)
+	}
+
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	ids := make([]int64, len(pending))
+	waits := make([]int64, len(pending))
+	for i, p := range pending {
+		ids[i] = int64(p.ID)
+		waits[i] = queueWaitMS(LocalWallClock(p.Timestamp), p.InQueueSince)
+	}
+
+	res, err := db.conn.Exec(`
+		UPDATE builds b SET queue_wait_ms = w.wait
+		FROM unnest($1::bigint[], $2::bigint[]) AS w(id, wait)
+		WHERE b.id = w.id AND b.controller = $3 AND b.queue_wait_ms IS NULL
+	`, pq.Array(ids), pq.Array(waits), controller)
+	if err != nil {
+		return 0, fmt.Errorf("apply queue waits failed: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// LocalWallClock reads a TIMESTAMP column written from an app-local time.Time,
+// such as builds.timestamp, back in the app's zone; the driver returns its wall
+// clock as UTC. Use it before comparing such a time with real instants.
+func LocalWallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+}
+
+// queueWaitMS is the time between entering the queue and starting, never negative.
+func queueWaitMS(started, inQueueSince time.Time) int64 {
+	if wait := started.Sub(inQueueSince).Milliseconds(); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// QueueWaitReport aggregates queue wait per ISO week and folder.
+func (db *DB) QueueWaitReport(from, to time.Time, folder string) ([]models.QueueWaitTrend, error) {
+	var rows []models.QueueWaitTrend
+	err := db.conn.Select(&rows, `
+		SELECT date_trunc('week', timestamp) AS week,
+		       regexp_replace(project_path, '/[^/]*$', '') AS folder,
+		       COUNT(*) AS builds,
+		       AVG(queue_wait_ms)::bigint AS avg_ms,
+		       (percentile_cont(0.9) WITHIN GROUP (ORDER BY queue_wait_ms))::bigint AS p90_ms,
+		       MAX(queue_wait_ms) AS max_ms
+		FROM builds
+		WHERE timestamp BETWEEN $1 AND $2
+		  AND queue_wait_ms IS NOT NULL
+		  AND ($3 = '' OR project_path LIKE $3 || '%')
+		GROUP BY 1, 2
+		ORDER BY 1 DESC, 5 DESC, 2
+	`, from, to, folder)
+	if err != nil {
+		return nil, fmt.Errorf("queue wait report failed: %w", err)
+	}
+	return rows, nil
+}
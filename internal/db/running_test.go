@@ -0,0 +1,22 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueueWaitMS(t *testing.T) {
+	defer func(loc *time.Location) { time.Local = loc }(time.Local)
+	time.Local = time.FixedZone("UTC+2", 2*3600)
+
+	// the build started 10:00:30 local time, stored as wall clock and read back as UTC
+	stored := time.Date(2025, 3, 1, 10, 0, 30, 0, time.UTC)
+	inQueueSince := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC) // 10:00:00 local
+
+	if got := queueWaitMS(LocalWallClock(stored), inQueueSince); got != 30000 {
+		t.Errorf("expected a 30s wait, got %dms", got)
+	}
+	if got := queueWaitMS(inQueueSince, LocalWallClock(stored)); got != 0 {
+		t.Errorf("expected a negative wait to be clamped, got %dms", got)
+	}
+}
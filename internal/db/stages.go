@@ -0,0 +1,112 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/lib/pq"
+)
+
+// GetBuildsMissingStages returns finished builds of a controller the stage collector has not visited yet, newest first,
+// skipping builds whose last fetch failed and is backing off.
+func (db *DB) GetBuildsMissingStages(controller string, limit int) ([]*models.Build, error) {
+	var builds []*models.Build
+	err := db.conn.Select(&builds, `
+		SELECT id, build_number, COALESCE(project_path, '') AS project_path,
+		       COALESCE(job_url, '') AS job_url, status
+		FROM builds b
+		WHERE stages_fetched_at IS NULL
+		  AND COALESCE(status, '') <> ''
+		  AND controller = $1
+		  AND `+notBackingOff(3)+`
+		ORDER BY timestamp DESC
+		LIMIT $2
+	`, controller, limit, CollectStages)
+	if err != nil {
+		return nil, fmt.Errorf("get builds missing stages failed: %w", err)
+	}
+	return builds, nil
+}
+
+// SaveBuildStages replaces the stages of a build and marks it as collected.
+// An empty slice records that the build has no stage data (not a Pipeline job).
+func (db *DB) SaveBuildStages(buildID int, stages []models.BuildStage) error {
+	tx, err := db.conn.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin tx failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM build_stages WHERE build_id = $1`, buildID); err != nil {
+		return fmt.Errorf("delete build stages failed: %w", err)
+	}
+	for i := range stages {
+		stages[i].BuildID = buildID
+		if _, err := tx.NamedExec(`
+			INSERT INTO build_stages (
+				build_id, stage_id, name, status, start_time, duration_ms, pause_duration_ms, position
+			)
+			VALUES (
+				:build_id, :stage_id, :name, :status, :start_time, :duration_ms, :pause_duration_ms, :position
+			)
+		`, stages[i]); err != nil {
+			return fmt.Errorf("insert build stage %q failed: %w", stages[i].Name, err)
+		}
+	}
+	if _, err := tx.Exec(`UPDATE builds SET stages_fetched_at = now() WHERE id = $1`, buildID); err != nil {
+		return fmt.Errorf("mark stages fetched failed: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetStagesForBuilds loads the stages of the given builds keyed by build id, in run order.
+func (db *DB) GetStagesForBuilds(buildIDs []int) (map[int][]models.BuildStage, error) {
+	out := make(map[int][]models.BuildStage)
+	if len(buildIDs) == 0 {
+		return out, nil
+	}
+
+	var stages []models.BuildStage
+	err := db.conn.Select(&stages, `
+		SELECT id, build_id, stage_id, name, COALESCE(status, '') AS status,
+		       COALESCE(start_time, 'epoch'::timestamptz) AS start_time,
+		       COALESCE(duration_ms, 0) AS duration_ms,
+		       COALESCE(pause_duration_ms, 0) AS pause_duration_ms, position
+		FROM build_stages
+		WHERE build_id = ANY($1)
+		ORDER BY build_id, position
+	`, pq.Array(buildIDs))
+	if err != nil {
+		return nil, fmt.Errorf("get build stages failed: %w", err)
+	}
+	for _, s := range stages {
+		out[s.BuildID] = append(out[s.BuildID], s)
+	}
+	return out, nil
+}
+
+// StageDurationTrend summarises stage durations of one project path per week.
+// Stages are listed in the order they usually run.
+func (db *DB) StageDurationTrend(projectPath string, from, to time.Time) ([]models.StageTrend, error) {
+	var rows []models.StageTrend
+	err := db.conn.Select(&rows, `
+		SELECT date_trunc('week', b.timestamp) AS week,
+		       s.name AS stage,
+		       COUNT(*) AS runs,
+		       AVG(s.duration_ms)::bigint AS avg_ms,
+		       MAX(s.duration_ms) AS max_ms,
+		       AVG(s.pause_duration_ms)::bigint AS avg_pause_ms
+		FROM build_stages s
+		JOIN builds b ON b.id = s.build_id
+		WHERE b.project_path = $1
+		  AND b.timestamp BETWEEN $2 AND $3
+		  AND s.status <> 'NOT_EXECUTED'
+		GROUP BY 1, 2
+		ORDER BY 1 DESC, MIN(s.position), 2
+	`, projectPath, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("stage duration trend failed: %w", err)
+	}
+	return rows, nil
+}
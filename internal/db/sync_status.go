@@ -4,14 +4,25 @@ import (
 	"database/sql"
 	"fmt"
 	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/config"
 )
 
 const initialSyncKey = "initial_sync"
 
-// Check if the initial sync is marked as done in the DB
-func (db *DB) IsInitialSyncDone() (bool, error) {
+// SyncKey scopes a sync_status key to a controller. The default controller keeps
+// the bare key so existing single-controller installs are not synced again.
+func SyncKey(key, controller string) string {
+	if controller == "" || controller == config.DefaultController {
+		return key
+	}
+	return key + ":" + controller
+}
+
+// Check if the initial sync of a controller is marked as done in the DB
+func (db *DB) IsInitialSyncDone(controller string) (bool, error) {
 	var value string
-	err := db.conn.Get(&value, `SELECT value FROM sync_status WHERE key = $1`, initialSyncKey)
+	err := db.conn.Get(&value, `SELECT value FROM sync_status WHERE key = $1`, SyncKey(initialSyncKey, controller))
 	if err == sql.ErrNoRows {
 		return false, nil // not done yet
 	} else if err != nil {
@@ -20,16 +31,41 @@ func (db *DB) IsInitialSyncDone() (bool, error) {
 	return value == "done", nil
 }
 
-// Mark the initial sync as done in the DB
-func (db *DB) MarkInitialSyncDone() error {
+// Mark the initial sync of a controller as done in the DB
+func (db *DB) MarkInitialSyncDone(controller string) error {
 	_, err := db.conn.Exec(`
 		INSERT INTO sync_status (key, value, updated_at)
 		VALUES ($1, $2, $3)
 		ON CONFLICT (key) DO UPDATE
 		SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at
-	`, initialSyncKey, "done", time.Now().UTC())
+	`, SyncKey(initialSyncKey, controller), "done", time.Now().UTC())
 	if err != nil {
 		return fmt.Errorf("failed to update sync status: %w", err)
 	}
 	return nil
 }
+
+// GetSyncStatus reads a sync_status value; ok is false when the key is not set.
+func (db *DB) GetSyncStatus(key string) (value string, ok bool, err error) {
+	err = db.conn.Get(&value, `SELECT value FROM sync_status WHERE key = $1`, key)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, fmt.Errorf("failed to read sync status %s: %w", key, err)
+	}
+	return value, true, nil
+}
+
+// SetSyncStatus stores a sync_status value, e.g. a resumable checkpoint.
+func (db *DB) SetSyncStatus(key, value string) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO sync_status (key, value, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE
+		SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at
+	`, key, value, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to update sync status %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// GetBuildsMissingTests returns finished builds of a controller the test collector has not visited yet, newest first,
+// skipping builds whose last fetch failed and is backing off.
+func (db *DB) GetBuildsMissingTests(controller string, limit int) ([]*models.Build, error) {
+	var builds []*models.Build
+	err := db.conn.Select(&builds, `
+		SELECT id, build_number, COALESCE(project_path, '') AS project_path,
+		       COALESCE(job_url, '') AS job_url, status
+		FROM builds b
+		WHERE tests_fetched_at IS NULL
+		  AND COALESCE(status, '') <> ''
+		  AND controller = $1
+		  AND `+notBackingOff(3)+`
+		ORDER BY timestamp DESC
+		LIMIT $2
+	`, controller, limit, CollectTests)
+	if err != nil {
+		return nil, fmt.Errorf("get builds missing tests failed: %w", err)
+	}
+	return builds, nil
+}
+
+// SaveTestResults replaces the test report of a build and marks it as collected.
+// A nil report records that the build published no JUnit results.
+func (db *DB) SaveTestResults(buildID int, report *models.TestReport, cases []models.TestCase) error {
+	tx, err := db.conn.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin tx failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM test_cases WHERE build_id = $1`, buildID); err != nil {
+		return fmt.Errorf("delete test cases failed: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM test_reports WHERE build_id = $1`, buildID); err != nil {
+		return fmt.Errorf("delete test report failed: %w", err)
+	}
+
+	if report != nil {
+		report.BuildID = buildID
+		if _, err := tx.NamedExec(`
+			INSERT INTO test_reports (build_id, total, passed, failed, skipped, duration_ms, fetched_at)
+			VALUES (:build_id, :total, :passed, :failed, :skipped, :duration_ms, now())
+		`, report); err != nil {
+			return fmt.Errorf("insert test report failed: %w", err)
+		}
+
+		stmt, err := tx.PrepareNamed(`
+			INSERT INTO test_cases (build_id, suite, class_name, name, status, duration_ms)
+			VALUES (:build_id, :suite, :class_name, :name, :status, :duration_ms)
+		`)
+		if err != nil {
+			return fmt.Errorf("prepare test case insert failed: %w", err)
+		}
+		defer stmt.Close()
+		for i := range cases {
+			cases[i].BuildID = buildID
+			if _, err := stmt.Exec(cases[i]); err != nil {
+				return fmt.Errorf("insert test case %s.%s failed: %w", cases[i].ClassName, cases[i].Name, err)
+			}
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE builds SET tests_fetched_at = now() WHERE id = $1`, buildID); err != nil {
+		return fmt.Errorf("mark tests fetched failed: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetTestReport returns the JUnit totals of a build.
+func (db *DB) GetTestReport(buildID int) (*models.TestReport, error) {
+	var r models.TestReport
+	err := db.conn.Get(&r, `
+		SELECT build_id, total, passed, failed, skipped, COALESCE(duration_ms, 0) AS duration_ms, fetched_at
+		FROM test_reports
+		WHERE build_id = $1
+	`, buildID)
+	if err != nil {
+		return nil, fmt.Errorf("get test report failed: %w", err)
+	}
+	return &r, nil
+}
+
+// GetFailedTestCases lists the failed test cases of a build.
+func (db *DB) GetFailedTestCases(buildID int) ([]models.TestCase, error) {
+	var cases []models.TestCase
+	err := db.conn.Select(&cases, `
+		SELECT build_id, COALESCE(suite, '') AS suite, class_name, name, status, COALESCE(duration_ms, 0) AS duration_ms
+		FROM test_cases
+		WHERE build_id = $1 AND status = 'FAILED'
+		ORDER BY class_name, name
+	`, buildID)
+	if err != nil {
+		return nil, fmt.Errorf("get failed test cases failed: %w", err)
+	}
+	return cases, nil
+}
+
+// GetTestOutcomes returns passed/failed test case results of builds in [from, to]
+// whose project path starts with folder (all when empty), ordered per test by time.
+// Only tests that failed at least once in the range are returned.
+func (db *DB) GetTestOutcomes(from, to time.Time, folder string) ([]models.TestOutcome, error) {
+	prefix := "%"
+	if folder != "" {
+		prefix = escapeLike(folder) + "/%"
+	}
+
+	var rows []models.TestOutcome
+	err := db.conn.Select(&rows, `
+		WITH scope AS (
+			SELECT t.build_id, t.class_name, t.name, t.status,
+			       b.build_number, b.project_path, COALESCE(b.commit_sha, '') AS commit_sha, b.timestamp
+			FROM test_cases t
+			JOIN builds b ON b.id = t.build_id
+			WHERE b.timestamp BETWEEN $1 AND $2
+			  AND b.project_path LIKE $3
+			  AND t.status IN ('PASSED', 'FAILED')
+		),
+		failing AS (
+			SELECT DISTINCT project_path, class_name, name FROM scope WHERE status = 'FAILED'
+		)
+		SELECT s.build_id, s.build_number, s.project_path, s.commit_sha, s.timestamp,
+		       s.class_name, s.name, s.status
+		FROM scope s
+		JOIN failing f USING (project_path, class_name, name)
+		ORDER BY s.project_path, s.class_name, s.name, s.timestamp, s.build_id
+	`, from, to, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("get test outcomes failed: %w", err)
+	}
+	return rows, nil
+}
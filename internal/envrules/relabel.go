@@ -0,0 +1,88 @@
+package envrules
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// Change is how one build's environments move under the current rules.
+type Change struct {
+	BuildID      int
+	ProjectPath  string
+	OldEnv       string
+	NewEnv       string
+	OldDeployEnv string
+	NewDeployEnv string
+	Rule         string
+}
+
+// Transition counts builds moving from one env to another.
+type Transition struct {
+	From, To string
+	Builds   int
+}
+
+// Report summarises the changes of a relabel run.
+type Report struct {
+	Changed int
+	Samples []Change // up to sampleSize changes per transition
+	counts  map[[2]string]int
+}
+
+const sampleSize = 5
+
+// Add counts a changed build towards its transition.
+func (r *Report) Add(c Change) {
+	if r.counts == nil {
+		r.counts = make(map[[2]string]int)
+	}
+	r.Changed++
+	key := [2]string{c.OldEnv, c.NewEnv}
+	r.counts[key]++
+	if r.counts[key] <= sampleSize {
+		r.Samples = append(r.Samples, c)
+	}
+}
+
+// Transitions lists the env transitions seen, most builds first.
+func (r *Report) Transitions() []Transition {
+	transitions := make([]Transition, 0, len(r.counts))
+	for k, n := range r.counts {
+		transitions = append(transitions, Transition{From: k[0], To: k[1], Builds: n})
+	}
+	sort.Slice(transitions, func(i, j int) bool {
+		return transitions[i].Builds > transitions[j].Builds
+	})
+	return transitions
+}
+
+// RelabelBuild re-runs the rules over a stored build. Builds stored without
+// parameters keep their deploy_env.
+func RelabelBuild(rs *RuleSet, b models.Build) (Change, bool) {
+	res := rs.Resolve(Input{ProjectPath: b.ProjectPath, JobName: b.ProjectName, Params: b.Parameters})
+	c := Change{
+		BuildID:      b.ID,
+		ProjectPath:  b.ProjectPath,
+		OldEnv:       b.Env,
+		NewEnv:       res.Env,
+		OldDeployEnv: b.DeployEnv,
+		NewDeployEnv: res.DeployEnv,
+		Rule:         res.Rule,
+	}
+	if len(b.Parameters) == 0 {
+		c.NewDeployEnv = b.DeployEnv
+	}
+	return c, c.NewEnv != c.OldEnv || c.NewDeployEnv != c.OldDeployEnv
+}
+
+// String renders a change for the dry-run listing.
+func (c Change) String() string {
+	rule := c.Rule
+	if rule == "" {
+		rule = "default"
+	}
+	return fmt.Sprintf("build %d %s: env %s -> %s, deploy_env %q -> %q (rule %s)",
+		c.BuildID, c.ProjectPath, c.OldEnv, c.NewEnv, c.OldDeployEnv, c.NewDeployEnv, rule)
+}
@@ -0,0 +1,107 @@
+package envrules
+
+import (
+	"sort"
+	"strings"
+)
+
+// Input is what a build offers for environment detection.
+type Input struct {
+	ProjectPath string
+	JobName     string
+	Params      map[string]string
+}
+
+// Resolution is the outcome for one build.
+type Resolution struct {
+	Env       string // canonical environment, stored in builds.env
+	DeployEnv string // lower-cased value of the winning parameter rule, stored in builds.deploy_env
+	Rule      string // name of the rule that decided Env, empty for the default
+}
+
+// Resolve evaluates every rule against in. The highest priority match decides
+// Env; the highest priority parameter match decides DeployEnv.
+func (rs *RuleSet) Resolve(in Input) Resolution {
+	if rs == nil {
+		return Resolution{Env: Unknown}
+	}
+
+	res := Resolution{Env: rs.Default}
+	envDone := false
+	jobName := in.JobName
+	if jobName == "" {
+		jobName = in.ProjectPath[strings.LastIndex(in.ProjectPath, "/")+1:]
+	}
+
+	for _, r := range rs.Rules {
+		if envDone && res.DeployEnv != "" {
+			break
+		}
+		if envDone && !r.IsParamRule() {
+			continue
+		}
+
+		var env string
+		matched := false
+		switch {
+		case r.folderRe != nil:
+			matched = r.folderRe.MatchString(in.ProjectPath)
+			env = r.Env
+		case r.jobRe != nil:
+			matched = r.jobRe.MatchString(jobName)
+			env = r.Env
+		default:
+			value, ok := r.paramValue(in.Params)
+			if !ok {
+				continue
+			}
+			matched = true
+			env = r.Env
+			if env == "" {
+				env = rs.canonical(value)
+			}
+			if res.DeployEnv == "" {
+				res.DeployEnv = strings.ToLower(value)
+			}
+		}
+
+		if matched && !envDone {
+			res.Env, res.Rule = env, r.Name
+			envDone = true
+		}
+	}
+	return res
+}
+
+// paramValue returns the first non-empty parameter the rule selects. Names are
+// tried in the rule's order, pattern matches in sorted order for stable results.
+func (r Rule) paramValue(params map[string]string) (string, bool) {
+	if len(params) == 0 {
+		return "", false
+	}
+	if len(r.Params) > 0 {
+		for _, want := range r.Params {
+			for name, value := range params {
+				if strings.EqualFold(name, want) && strings.TrimSpace(value) != "" {
+					return strings.TrimSpace(value), true
+				}
+			}
+		}
+		return "", false
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !r.paramRe.MatchString(name) || (r.excludeRe != nil && r.excludeRe.MatchString(name)) {
+			continue
+		}
+		if value := strings.TrimSpace(params[name]); value != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,101 @@
+package envrules
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+func TestResolveShippedRules(t *testing.T) {
+	rs, err := LoadRules(filepath.Join("..", "..", "config", "env-rules.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name      string
+		in        Input
+		env       string
+		deployEnv string
+		rule      string
+	}{
+		{"folder wins over params", Input{ProjectPath: "DEV/app/deploy", Params: map[string]string{"DEPLOY_ENV": "QA"}}, "DEV", "qa", "dev-folder"},
+		{"folder is case-insensitive", Input{ProjectPath: "NonProd/app/deploy"}, "NON_PROD", "", "nonprod-folder"},
+		{"same leaf in another folder", Input{ProjectPath: "PROD_AND_DR/app/deploy"}, "PROD_AND_DR", "", "prod-dr-folder"},
+		{"param priority", Input{ProjectPath: "team/app/deploy", Params: map[string]string{"deploy_env": "dev", "DEPLOYING_ENVIRONMENT": "Production"}}, "PROD_AND_DR", "production", "deploying-environment-param"},
+		{"unknown value is upper-cased", Input{ProjectPath: "team/app/deploy", Params: map[string]string{"ENV": "perf"}}, "PERF", "perf", "env-param"},
+		{"param pattern", Input{ProjectPath: "team/app/deploy", Params: map[string]string{"APP_STAGE": "uat"}}, "NON_PROD", "uat", "env-like-param"},
+		{"excluded param names", Input{ProjectPath: "team/app/deploy", Params: map[string]string{"GIT_ENV_BRANCH": "prod"}}, "UNKNOWN", "", ""},
+		{"job name", Input{ProjectPath: "team/svc-prod-deploy"}, "PROD_AND_DR", "", "prod-job"},
+		{"no match", Input{ProjectPath: "team/app/build", Params: map[string]string{"VERSION": "1.4.2"}}, "UNKNOWN", "", ""},
+	}
+	for _, tc := range cases {
+		got := rs.Resolve(tc.in)
+		if got.Env != tc.env || got.DeployEnv != tc.deployEnv || got.Rule != tc.rule {
+			t.Errorf("%s: Resolve = %+v, want env=%s deploy_env=%q rule=%s", tc.name, got, tc.env, tc.deployEnv, tc.rule)
+		}
+	}
+
+	var none *RuleSet
+	if got := none.Resolve(Input{ProjectPath: "DEV/app"}); got.Env != Unknown {
+		t.Errorf("nil rule set should resolve to %s, got %+v", Unknown, got)
+	}
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	bad := map[string]string{
+		"two kinds":          "rules:\n  - folder: '^DEV'\n    job: 'x'\n    env: DEV\n",
+		"no kind":            "rules:\n  - env: DEV\n",
+		"folder without env": "rules:\n  - folder: '^DEV'\n",
+		"invalid regex":      "rules:\n  - job: '(unclosed'\n    env: DEV\n",
+		"invalid exclude re": "rules:\n  - param_pattern: env\n    exclude_param_pattern: '['\n",
+	}
+	for name, doc := range bad {
+		if _, err := ParseRules([]byte(doc)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	rs, err := ParseRules([]byte("default: OTHER\nrules:\n  - name: low\n    job: app\n    env: A\n  - name: high\n    job: app\n    env: B\n    priority: 5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rs.Resolve(Input{ProjectPath: "x/app"}); got.Env != "B" {
+		t.Errorf("higher priority rule should win, got %+v", got)
+	}
+	if got := rs.Resolve(Input{ProjectPath: "x/other"}); got.Env != "OTHER" {
+		t.Errorf("expected file default, got %+v", got)
+	}
+}
+
+func TestRelabelBuildReport(t *testing.T) {
+	rs, err := LoadRules(filepath.Join("..", "..", "config", "env-rules.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builds := []models.Build{
+		{ID: 1, ProjectPath: "DEV/app/deploy", Env: "UNKNOWN"},
+		{ID: 2, ProjectPath: "DEV/api/deploy", Env: "UNKNOWN", DeployEnv: "qa"}, // no parameters, deploy_env stays
+		{ID: 3, ProjectPath: "DEV/web/deploy", Env: "DEV"},
+		{ID: 4, ProjectPath: "team/app/deploy", Env: "PROD_AND_DR", Parameters: map[string]string{"ENV": "uat"}, DeployEnv: "prod"},
+	}
+	report := &Report{}
+	for _, b := range builds {
+		if c, changed := RelabelBuild(rs, b); changed {
+			report.Add(c)
+		}
+	}
+
+	if report.Changed != 3 || len(report.Samples) != 3 {
+		t.Fatalf("expected 3 changes, got %d with samples %v", report.Changed, report.Samples)
+	}
+	if c := report.Samples[1]; c.NewEnv != "DEV" || c.NewDeployEnv != "qa" {
+		t.Errorf("a build without parameters keeps its deploy_env, got %+v", c)
+	}
+	transitions := report.Transitions()
+	if len(transitions) != 2 || transitions[0] != (Transition{From: "UNKNOWN", To: "DEV", Builds: 2}) {
+		t.Errorf("unexpected transitions %+v", transitions)
+	}
+}
@@ -0,0 +1,135 @@
+package envrules
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Unknown is the environment of builds no rule matched, unless the rules
+// file sets another default.
+const Unknown = "UNKNOWN"
+
+// Rule maps a parameter, folder path or job name onto a canonical environment.
+// Exactly one of Params, ParamPattern, Folder or Job must be set.
+type Rule struct {
+	Name     string `yaml:"name"`
+	Priority int    `yaml:"priority"`
+	// Env is the canonical environment of a match. Parameter rules may leave it
+	// empty to map the parameter's value through the aliases instead.
+	Env string `yaml:"env"`
+
+	Params              []string `yaml:"params"`                // parameter names, case-insensitive
+	ParamPattern        string   `yaml:"param_pattern"`         // regex on parameter names
+	ExcludeParamPattern string   `yaml:"exclude_param_pattern"` // names skipped by param_pattern
+	Folder              string   `yaml:"folder"`                // regex on the project path
+	Job                 string   `yaml:"job"`                   // regex on the job name
+
+	paramRe, excludeRe, folderRe, jobRe *regexp.Regexp
+	order                               int
+}
+
+// IsParamRule reports whether the rule reads a build parameter.
+func (r Rule) IsParamRule() bool {
+	return len(r.Params) > 0 || r.paramRe != nil
+}
+
+type ruleFile struct {
+	Default string              `yaml:"default"`
+	Aliases map[string][]string `yaml:"aliases"`
+	Rules   []Rule              `yaml:"rules"`
+}
+
+// RuleSet is a parsed rules document.
+type RuleSet struct {
+	Default string
+	Rules   []Rule // highest priority first, file order breaks ties
+
+	aliases map[string]string // lower-case spelling -> canonical env
+}
+
+// ParseRules decodes and compiles a rules document.
+func ParseRules(data []byte) (*RuleSet, error) {
+	var f ruleFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid env rules file: %w", err)
+	}
+
+	rs := &RuleSet{Default: f.Default, aliases: make(map[string]string)}
+	if rs.Default == "" {
+		rs.Default = Unknown
+	}
+	for env, spellings := range f.Aliases {
+		rs.aliases[strings.ToLower(env)] = env
+		for _, s := range spellings {
+			rs.aliases[strings.ToLower(strings.TrimSpace(s))] = env
+		}
+	}
+
+	for i := range f.Rules {
+		r := &f.Rules[i]
+		r.order = i
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+
+		var err error
+		compile := func(pattern string) *regexp.Regexp {
+			if pattern == "" || err != nil {
+				return nil
+			}
+			var re *regexp.Regexp
+			re, err = regexp.Compile(pattern)
+			return re
+		}
+		r.paramRe = compile(r.ParamPattern)
+		r.excludeRe = compile(r.ExcludeParamPattern)
+		r.folderRe = compile(r.Folder)
+		r.jobRe = compile(r.Job)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i+1, r.Name, err)
+		}
+
+		kinds := 0
+		for _, set := range []bool{len(r.Params) > 0, r.paramRe != nil, r.folderRe != nil, r.jobRe != nil} {
+			if set {
+				kinds++
+			}
+		}
+		if kinds != 1 {
+			return nil, fmt.Errorf("rule %d (%s): exactly one of params, param_pattern, folder or job is required", i+1, r.Name)
+		}
+		if !r.IsParamRule() && r.Env == "" {
+			return nil, fmt.Errorf("rule %d (%s): env is required for folder and job rules", i+1, r.Name)
+		}
+	}
+
+	rs.Rules = f.Rules
+	sort.SliceStable(rs.Rules, func(i, j int) bool { return rs.Rules[i].Priority > rs.Rules[j].Priority })
+	return rs, nil
+}
+
+// LoadRules reads and parses the rules file at path.
+func LoadRules(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("env rules: %w", err)
+	}
+	rs, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("env rules %s: %w", path, err)
+	}
+	return rs, nil
+}
+
+// canonical maps a parameter value onto a canonical environment.
+func (rs *RuleSet) canonical(value string) string {
+	if env, ok := rs.aliases[strings.ToLower(value)]; ok {
+		return env
+	}
+	return strings.ToUpper(value)
+}
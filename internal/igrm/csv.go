@@ -0,0 +1,87 @@
+package igrm
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CSVValidator looks tickets up in a change-management export with the
+// columns number, state, approval, start and end (in any order). The file is
+// read again when it changes.
+type CSVValidator struct {
+	Path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	tickets map[string]*Ticket
+}
+
+func (v *CSVValidator) Lookup(number string) (*Ticket, error) {
+	if err := v.load(); err != nil {
+		return nil, err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.tickets[number], nil
+}
+
+func (v *CSVValidator) String() string {
+	return v.Path
+}
+
+// load reads the export unless it is unchanged since the last read.
+func (v *CSVValidator) load() error {
+	info, err := os.Stat(v.Path)
+	if err != nil {
+		return fmt.Errorf("read igrm export: %w", err)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.tickets != nil && info.ModTime().Equal(v.modTime) {
+		return nil
+	}
+
+	f, err := os.Open(v.Path)
+	if err != nil {
+		return fmt.Errorf("read igrm export: %w", err)
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return fmt.Errorf("parse igrm export %s: %w", v.Path, err)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("igrm export %s is empty", v.Path)
+	}
+	col := make(map[string]int)
+	for i, name := range rows[0] {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := col["number"]; !ok {
+		return fmt.Errorf("igrm export %s has no number column", v.Path)
+	}
+	field := func(row []string, name string) string {
+		if i, ok := col[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	tickets := make(map[string]*Ticket, len(rows)-1)
+	for i, row := range rows[1:] {
+		t, err := newTicket(field(row, "number"), field(row, "state"), field(row, "approval"), field(row, "start"), field(row, "end"))
+		if err != nil {
+			return fmt.Errorf("igrm export %s line %d: %w", v.Path, i+2, err)
+		}
+		if t.Number != "" {
+			tickets[t.Number] = t
+		}
+	}
+	v.tickets, v.modTime = tickets, info.ModTime()
+	return nil
+}
@@ -0,0 +1,131 @@
+package igrm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// serviceNowTime is the layout of ServiceNow date-time fields, in UTC.
+const serviceNowTime = "2006-01-02 15:04:05"
+
+// HTTPValidator looks tickets up in a ServiceNow-style table API:
+// GET <base>/api/now/table/change_request?sysparm_query=number=<n>.
+type HTTPValidator struct {
+	BaseURL string
+	User    string // basic auth when set, otherwise Token is sent as a bearer token
+	Token   string
+	Client  *http.Client
+}
+
+func NewHTTPValidator(baseURL, user, token string) *HTTPValidator {
+	return &HTTPValidator{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		User:    user,
+		Token:   token,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type changeRequest struct {
+	Number    string `json:"number"`
+	State     string `json:"state"`
+	Approval  string `json:"approval"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+// Lookup finds the change request with exactly this number. Numbers that are
+// not plain ticket ids are never sent: they come from build parameters and
+// would otherwise extend the encoded query (e.g. "X^ORapproval=approved").
+func (v *HTTPValidator) Lookup(number string) (*Ticket, error) {
+	if !WellFormed(number) {
+		return nil, nil
+	}
+	q := url.Values{}
+	q.Set("sysparm_query", "number="+number)
+	q.Set("sysparm_fields", "number,state,approval,start_date,end_date")
+	q.Set("sysparm_limit", "1")
+	apiURL := v.BaseURL + "/api/now/table/change_request?" + q.Encode()
+
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", apiURL, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if v.User != "" {
+		req.SetBasicAuth(v.User, v.Token)
+	} else if v.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+v.Token)
+	}
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", apiURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("change-management API returned status %d for %s", resp.StatusCode, number)
+	}
+
+	var body struct {
+		Result []changeRequest `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("error decoding change request %s: %w", number, err)
+	}
+	if len(body.Result) == 0 {
+		return nil, nil
+	}
+	cr := body.Result[0]
+	t, err := newTicket(cr.Number, cr.State, cr.Approval, cr.StartDate, cr.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	// only the ticket asked for counts, never some other match of the query
+	if t.Number != strings.ToUpper(strings.TrimSpace(number)) {
+		return nil, nil
+	}
+	return t, nil
+}
+
+func (v *HTTPValidator) String() string {
+	return v.BaseURL
+}
+
+// newTicket builds a ticket from the text fields shared by the API and CSV
+// exports. Dates are ServiceNow date-times in UTC or RFC 3339.
+func newTicket(number, state, approval, start, end string) (*Ticket, error) {
+	t := &Ticket{
+		Number:   strings.ToUpper(strings.TrimSpace(number)),
+		State:    strings.TrimSpace(state),
+		Approval: strings.ToLower(strings.TrimSpace(approval)),
+	}
+	t.Approved = t.Approval == "approved"
+	var err error
+	if t.Start, err = parseTicketTime(start); err != nil {
+		return nil, fmt.Errorf("ticket %s start: %w", t.Number, err)
+	}
+	if t.End, err = parseTicketTime(end); err != nil {
+		return nil, fmt.Errorf("ticket %s end: %w", t.Number, err)
+	}
+	return t, nil
+}
+
+func parseTicketTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(serviceNowTime, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,189 @@
+// Package igrm checks the IGRM change tickets of deployments against a
+// change-management system.
+package igrm
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/config"
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// Ticket is a change request as known to the change-management system.
+type Ticket struct {
+	Number   string
+	State    string
+	Approval string
+	Approved bool
+	Start    time.Time // scheduled window, zero when not scheduled
+	End      time.Time
+}
+
+// Validator looks up change tickets. A nil ticket with a nil error means the
+// ticket does not exist.
+type Validator interface {
+	Lookup(number string) (*Ticket, error)
+	String() string
+}
+
+// Outcomes stored in builds.igrm_status.
+const (
+	StatusValid         = "valid"
+	StatusNotFound      = "not_found"
+	StatusNotApproved   = "not_approved"
+	StatusOutsideWindow = "outside_window"
+	StatusError         = "error"
+)
+
+// Result is the outcome of checking one deployment.
+type Result struct {
+	Status string
+	Detail string
+}
+
+// Final reports whether the outcome is stored for good; errors are retried.
+func (r Result) Final() bool {
+	return r.Status != StatusError
+}
+
+// NewValidator returns the cached validator configured by IGRM_VALIDATOR_*, or
+// nil when no change-management system is configured.
+func NewValidator(cfg config.IGRMValidatorConfig) (Validator, error) {
+	var v Validator
+	switch {
+	case cfg.URL != "":
+		v = NewHTTPValidator(cfg.URL, cfg.User, cfg.Token)
+	case cfg.CSVFile != "":
+		csv := &CSVValidator{Path: cfg.CSVFile}
+		if err := csv.load(); err != nil {
+			return nil, err
+		}
+		v = csv
+	default:
+		return nil, nil
+	}
+	return NewCache(v, cfg.CacheTTL), nil
+}
+
+// ticketNumber is what a change ticket id may look like, whatever the format
+// configured for the compliance report.
+var ticketNumber = regexp.MustCompile(`^[A-Z0-9][A-Z0-9._-]*$`)
+
+// WellFormed reports whether number, once normalised, is a plain ticket id that
+// is safe to look up: no query operators (^, =), spaces or other punctuation.
+func WellFormed(number string) bool {
+	return ticketNumber.MatchString(strings.ToUpper(strings.TrimSpace(number)))
+}
+
+// Check validates the ticket of a deployment started at.
+func Check(v Validator, number string, at time.Time) Result {
+	number = strings.ToUpper(strings.TrimSpace(number))
+	if !WellFormed(number) {
+		return Result{Status: StatusNotFound, Detail: fmt.Sprintf("%q is not a valid ticket number", number)}
+	}
+	t, err := v.Lookup(number)
+	switch {
+	case err != nil:
+		return Result{Status: StatusError, Detail: err.Error()}
+	case t == nil:
+		return Result{Status: StatusNotFound, Detail: fmt.Sprintf("%s not found in %s", number, v)}
+	case !t.Approved:
+		return Result{Status: StatusNotApproved, Detail: fmt.Sprintf("approval %q, state %q", t.Approval, t.State)}
+	case !t.Start.IsZero() && at.Before(t.Start), !t.End.IsZero() && at.After(t.End):
+		return Result{Status: StatusOutsideWindow, Detail: fmt.Sprintf("scheduled %s – %s",
+			t.Start.UTC().Format("2006-01-02 15:04"), t.End.UTC().Format("2006-01-02 15:04"))}
+	}
+	return Result{Status: StatusValid}
+}
+
+// store is the part of *db.DB CheckPending uses.
+type store interface {
+	GetBuildsPendingIGRMCheck(env string, deploysOnly bool, limit int) ([]*models.Build, error)
+	SetIGRMStatus(b *models.Build, status, detail string, final bool) error
+	RecordCollectFailure(collector string, buildID int, fetchErr error) (int, error)
+	ClearCollectAttempts(collector string, buildID int) error
+}
+
+var _ store = (*db.DB)(nil)
+
+// CheckPending checks up to batch deployments of env whose ticket has not been
+// checked yet and stores the outcomes. Lookup errors leave the build pending
+// and back it off, so an unreachable system is not asked again every run.
+func CheckPending(database store, v Validator, env string, deploysOnly bool, batch int) (int, error) {
+	builds, err := database.GetBuildsPendingIGRMCheck(env, deploysOnly, batch)
+	if err != nil {
+		return 0, err
+	}
+
+	checked := 0
+	for _, b := range builds {
+		// the ticket window is a real instant, the stored timestamp app-local
+		r := Check(v, b.IGRMNo, db.LocalWallClock(b.Timestamp))
+		if err := database.SetIGRMStatus(b, r.Status, r.Detail, r.Final()); err != nil {
+			log.Printf("[IGRM] store failed for build ID %d: %v", b.ID, err)
+			continue
+		}
+		if !r.Final() {
+			attempts, err := database.RecordCollectFailure(db.CollectIGRM, b.ID, errors.New(r.Detail))
+			if err != nil {
+				log.Printf("[IGRM] %v", err)
+			} else {
+				log.Printf("[IGRM] lookup failed for build ID %d (attempt %d), retrying later: %s", b.ID, attempts, r.Detail)
+			}
+			continue
+		}
+		if err := database.ClearCollectAttempts(db.CollectIGRM, b.ID); err != nil {
+			log.Printf("[IGRM] %v", err)
+		}
+		checked++
+	}
+	return checked, nil
+}
+
+// Cache remembers looked up tickets, including missing ones, for TTL.
+// Lookup errors are not cached.
+type Cache struct {
+	Validator Validator
+	TTL       time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	ticket  *Ticket
+	expires time.Time
+}
+
+func NewCache(v Validator, ttl time.Duration) *Cache {
+	return &Cache{Validator: v, TTL: ttl, entries: make(map[string]cacheEntry)}
+}
+
+func (c *Cache) Lookup(number string) (*Ticket, error) {
+	c.mu.Lock()
+	e, ok := c.entries[number]
+	c.mu.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		return e.ticket, nil
+	}
+
+	t, err := c.Validator.Lookup(number)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[number] = cacheEntry{ticket: t, expires: time.Now().Add(c.TTL)}
+	c.mu.Unlock()
+	return t, nil
+}
+
+func (c *Cache) String() string {
+	return c.Validator.String()
+}
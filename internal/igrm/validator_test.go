@@ -0,0 +1,227 @@
+package igrm
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+func TestHTTPValidator(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if user, pass, ok := r.BasicAuth(); !ok || user != "svc" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/now/table/change_request" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("sysparm_query") {
+		case "number=IGRM000001":
+			w.Write([]byte(`{"result":[{"number":"IGRM000001","state":"Scheduled","approval":"approved",
+				"start_date":"2025-03-04 19:00:00","end_date":"2025-03-04 23:00:00"}]}`))
+		case "number=IGRM000002":
+			w.Write([]byte(`{"result":[{"number":"IGRM000002","state":"Assess","approval":"requested"}]}`))
+		case "number=IGRM000500":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`{"result":[]}`))
+		}
+	}))
+	defer srv.Close()
+
+	v := NewCache(NewHTTPValidator(srv.URL+"/", "svc", "secret"), time.Hour)
+	at := time.Date(2025, 3, 4, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		number string
+		at     time.Time
+		want   string
+	}{
+		{"igrm000001 ", at, StatusValid},
+		{"IGRM000001", at.Add(4 * time.Hour), StatusOutsideWindow},
+		{"IGRM000002", at, StatusNotApproved},
+		{"IGRM999999", at, StatusNotFound},
+		{"IGRM000500", at, StatusError},
+	}
+	for _, tt := range tests {
+		r := Check(v, tt.number, tt.at)
+		if r.Status != tt.want {
+			t.Errorf("Check(%q) = %+v, want %s", tt.number, r, tt.want)
+		}
+		if r.Final() != (tt.want != StatusError) {
+			t.Errorf("Check(%q).Final() = %v", tt.number, r.Final())
+		}
+	}
+
+	// found and missing tickets are cached, errors are retried
+	before := calls
+	Check(v, "IGRM000001", at)
+	Check(v, "IGRM999999", at)
+	Check(v, "IGRM000500", at)
+	if calls-before != 1 {
+		t.Errorf("expected only the failed lookup to reach the API again, got %d calls", calls-before)
+	}
+}
+
+func TestCSVValidator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "changes.csv")
+	export := "Number,Approval,State,Start,End\n" +
+		"IGRM000001,Approved,Scheduled,2025-03-04 19:00:00,2025-03-04 23:00:00\n" +
+		"igrm000002,Rejected,Closed,,\n"
+	if err := os.WriteFile(path, []byte(export), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	v := &CSVValidator{Path: path}
+
+	at := time.Date(2025, 3, 4, 20, 0, 0, 0, time.UTC)
+	if r := Check(v, "IGRM000001", at); r.Status != StatusValid {
+		t.Errorf("IGRM000001: %+v", r)
+	}
+	if r := Check(v, "IGRM000002", at); r.Status != StatusNotApproved {
+		t.Errorf("IGRM000002: %+v", r)
+	}
+	if r := Check(v, "IGRM000003", at); r.Status != StatusNotFound {
+		t.Errorf("IGRM000003: %+v", r)
+	}
+
+	// a new export is picked up without a restart
+	export += "IGRM000003,approved,Implement,,\n"
+	if err := os.WriteFile(path, []byte(export), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if r := Check(v, "IGRM000003", at); r.Status != StatusValid {
+		t.Errorf("IGRM000003 after reload: %+v", r)
+	}
+
+	if err := os.WriteFile(path, []byte("approval,state\napproved,x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, later.Add(time.Minute), later.Add(time.Minute))
+	if r := Check(v, "IGRM000001", at); r.Status != StatusError {
+		t.Errorf("export without number column should fail the check: %+v", r)
+	}
+}
+
+func TestHTTPValidatorQueryInjection(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("sysparm_query")
+		queries = append(queries, q)
+		w.Header().Set("Content-Type", "application/json")
+		// like ServiceNow, an injected ^OR clause matches some other approved change,
+		// and a lenient match can return a ticket with another number
+		switch q {
+		case "number=IGRM000003", "number=X^ORapproval=approved":
+			w.Write([]byte(`{"result":[{"number":"IGRM000777","state":"Scheduled","approval":"approved"}]}`))
+		default:
+			w.Write([]byte(`{"result":[]}`))
+		}
+	}))
+	defer srv.Close()
+
+	v := NewHTTPValidator(srv.URL, "", "token")
+	at := time.Date(2025, 3, 4, 20, 0, 0, 0, time.UTC)
+	for _, number := range []string{"X^ORapproval=approved", "IGRM1 ^NQnumber=IGRM000777", "IGRM=1", "IGRM 1"} {
+		if r := Check(v, number, at); r.Status != StatusNotFound {
+			t.Errorf("Check(%q) = %+v, want %s", number, r, StatusNotFound)
+		}
+		if ticket, err := v.Lookup(number); ticket != nil || err != nil {
+			t.Errorf("Lookup(%q) = %+v, %v, want no ticket", number, ticket, err)
+		}
+	}
+	if len(queries) != 0 {
+		t.Errorf("malformed numbers must not reach the API, got queries %q", queries)
+	}
+
+	if r := Check(v, "IGRM000003", at); r.Status != StatusNotFound {
+		t.Errorf("a ticket with another number must not validate IGRM000003, got %+v", r)
+	}
+}
+
+type fakeValidator map[string]*Ticket
+
+func (f fakeValidator) Lookup(number string) (*Ticket, error) {
+	if number == "IGRM000500" {
+		return nil, errors.New("change-management system returned 503")
+	}
+	return f[number], nil
+}
+
+func (f fakeValidator) String() string { return "fake" }
+
+type fakeStore struct {
+	pending  []*models.Build
+	env      string
+	statuses map[int]string
+	failures map[int]int
+	cleared  []int
+}
+
+func (s *fakeStore) GetBuildsPendingIGRMCheck(env string, deploysOnly bool, limit int) ([]*models.Build, error) {
+	s.env = env
+	return s.pending, nil
+}
+
+func (s *fakeStore) SetIGRMStatus(b *models.Build, status, detail string, final bool) error {
+	s.statuses[b.ID] = status
+	return nil
+}
+
+func (s *fakeStore) RecordCollectFailure(collector string, buildID int, fetchErr error) (int, error) {
+	if collector != db.CollectIGRM {
+		return 0, errors.New("unexpected collector " + collector)
+	}
+	s.failures[buildID]++
+	return s.failures[buildID], nil
+}
+
+func (s *fakeStore) ClearCollectAttempts(collector string, buildID int) error {
+	s.cleared = append(s.cleared, buildID)
+	return nil
+}
+
+func TestCheckPending(t *testing.T) {
+	defer func(loc *time.Location) { time.Local = loc }(time.Local)
+	time.Local = time.FixedZone("CET", 3600)
+
+	// stored as 19:30 app time, i.e. 18:30 UTC, before the ticket's window
+	stored := time.Date(2025, 3, 4, 19, 30, 0, 0, time.UTC)
+	v := fakeValidator{"IGRM000001": {Number: "IGRM000001", Approved: true,
+		Start: time.Date(2025, 3, 4, 19, 0, 0, 0, time.UTC), End: time.Date(2025, 3, 4, 23, 0, 0, 0, time.UTC)}}
+	s := &fakeStore{
+		pending:  []*models.Build{{ID: 1, IGRMNo: "IGRM000001", Timestamp: stored}, {ID: 2, IGRMNo: "IGRM000500", Timestamp: stored}},
+		statuses: map[int]string{},
+		failures: map[int]int{},
+	}
+
+	n, err := CheckPending(s, v, "PROD_AND_DR", true, 10)
+	if err != nil || n != 1 {
+		t.Fatalf("CheckPending = %d, %v; want 1 checked", n, err)
+	}
+	if s.env != "PROD_AND_DR" {
+		t.Errorf("pending builds not filtered on the policy env, got %q", s.env)
+	}
+	if s.statuses[1] != StatusOutsideWindow {
+		t.Errorf("build 1 status = %q, want %q", s.statuses[1], StatusOutsideWindow)
+	}
+	if s.statuses[2] != StatusError || s.failures[2] != 1 {
+		t.Errorf("lookup error should be stored and backed off: status %q, failures %d", s.statuses[2], s.failures[2])
+	}
+	if len(s.cleared) != 1 || s.cleared[0] != 1 {
+		t.Errorf("attempts cleared for %v, want only build 1", s.cleared)
+	}
+}
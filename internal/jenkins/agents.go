@@ -0,0 +1,109 @@
+package jenkins
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// builtInNode is the name stored for the controller's own executors, which
+// Jenkins reports as "Built-In Node" (or "master" on older versions).
+const builtInNode = "built-in"
+
+type computer struct {
+	Class              string `json:"_class"`
+	DisplayName        string `json:"displayName"`
+	NumExecutors       int    `json:"numExecutors"`
+	Offline            bool   `json:"offline"`
+	TemporarilyOffline bool   `json:"temporarilyOffline"`
+	OfflineCauseReason string `json:"offlineCauseReason"`
+	AssignedLabels     []struct {
+		Name string `json:"name"`
+	} `json:"assignedLabels"`
+	Executors []executor `json:"executors"`
+}
+
+type executor struct {
+	Idle              bool `json:"idle"`
+	CurrentExecutable *struct {
+		URL string `json:"url"`
+	} `json:"currentExecutable"`
+}
+
+func (c computer) nodeName() string {
+	if strings.Contains(c.Class, "MasterComputer") || strings.Contains(c.Class, "BuiltInComputer") {
+		return builtInNode
+	}
+	return c.DisplayName
+}
+
+// FetchComputers returns the controller's nodes from /computer/api/json.
+func (jc *JenkinsClient) FetchComputers() ([]computer, error) {
+	apiURL := fmt.Sprintf("%s/computer/api/json?tree=computer[_class,displayName,numExecutors,offline,temporarilyOffline,offlineCauseReason,assignedLabels[name],executors[idle,currentExecutable[url]]]", strings.TrimSuffix(jc.BaseURL, "/"))
+
+	var data struct {
+		Computer []computer `json:"computer"`
+	}
+	if err := jc.getJSON(apiURL, &data); err != nil {
+		return nil, err
+	}
+	return data.Computer, nil
+}
+
+// SnapshotAgents stores the current state of every node and attributes running
+// builds to the node executing them. It returns the number of nodes stored.
+func SnapshotAgents(database *db.DB, client *JenkinsClient, retention time.Duration) (int, error) {
+	computers, err := client.FetchComputers()
+	if err != nil {
+		return 0, fmt.Errorf("fetch computers failed: %w", err)
+	}
+
+	snapshots := agentSnapshots(client.Name, computers, time.Now().UTC().Truncate(time.Second))
+	for _, c := range computers {
+		// only regular executors: a Pipeline's one-off executor stays on the
+		// controller while its node blocks take these
+		for _, e := range c.Executors {
+			if e.CurrentExecutable == nil || e.CurrentExecutable.URL == "" {
+				continue
+			}
+			if err := database.SetBuiltOnByExecutable(client.Name, e.CurrentExecutable.URL, c.nodeName()); err != nil {
+				log.Printf("[Agents] %v", err)
+			}
+		}
+	}
+
+	if err := database.SaveAgentSnapshots(snapshots, retention); err != nil {
+		return 0, err
+	}
+	return len(snapshots), nil
+}
+
+// agentSnapshots turns the nodes of a controller into snapshots taken at takenAt.
+func agentSnapshots(controller string, computers []computer, takenAt time.Time) []models.AgentSnapshot {
+	snapshots := make([]models.AgentSnapshot, 0, len(computers))
+	for _, c := range computers {
+		s := models.AgentSnapshot{
+			Controller:         controller,
+			NodeName:           c.nodeName(),
+			NumExecutors:       c.NumExecutors,
+			Offline:            c.Offline,
+			TemporarilyOffline: c.TemporarilyOffline,
+			OfflineReason:      c.OfflineCauseReason,
+			TakenAt:            takenAt,
+		}
+		for _, l := range c.AssignedLabels {
+			s.Labels = append(s.Labels, l.Name)
+		}
+		for _, e := range c.Executors {
+			if !e.Idle {
+				s.BusyExecutors++
+			}
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots
+}
@@ -0,0 +1,54 @@
+package jenkins
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestAgentSnapshots(t *testing.T) {
+	client := NewJenkinsClient("http://jenkins.local", "user", "token")
+	client.Name = "ci"
+	httpmock.ActivateNonDefault(client.Client)
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET",
+		"http://jenkins.local/computer/api/json?tree=computer[_class,displayName,numExecutors,offline,temporarilyOffline,offlineCauseReason,assignedLabels[name],executors[idle,currentExecutable[url]]]",
+		httpmock.NewStringResponder(200, `{"computer": [
+			{"_class": "hudson.model.Hudson$MasterComputer", "displayName": "Built-In Node", "numExecutors": 2,
+			 "assignedLabels": [{"name": "built-in"}],
+			 "executors": [{"idle": true}, {"idle": false, "currentExecutable": {"url": "http://jenkins.local/job/app/3/"}}]},
+			{"_class": "hudson.slaves.SlaveComputer", "displayName": "linux-1", "numExecutors": 1,
+			 "offline": true, "temporarilyOffline": true, "offlineCauseReason": "maintenance",
+			 "assignedLabels": [{"name": "linux"}, {"name": "docker"}],
+			 "executors": [{"idle": true}]}
+		]}`))
+
+	computers, err := client.FetchComputers()
+	if err != nil {
+		t.Fatalf("FetchComputers failed: %v", err)
+	}
+	takenAt := time.Date(2025, 3, 5, 10, 0, 0, 0, time.UTC)
+	snapshots := agentSnapshots(client.Name, computers, takenAt)
+	if len(snapshots) != 2 {
+		t.Fatalf("expected 2 snapshots, got %d", len(snapshots))
+	}
+
+	builtIn, agent := snapshots[0], snapshots[1]
+	if builtIn.NodeName != builtInNode || builtIn.NumExecutors != 2 || builtIn.BusyExecutors != 1 || builtIn.Offline {
+		t.Errorf("unexpected built-in node snapshot %+v", builtIn)
+	}
+	if agent.NodeName != "linux-1" || agent.BusyExecutors != 0 || !agent.Offline || !agent.TemporarilyOffline || agent.OfflineReason != "maintenance" {
+		t.Errorf("unexpected agent snapshot %+v", agent)
+	}
+	if strings.Join(agent.Labels, ",") != "linux,docker" {
+		t.Errorf("expected labels linux,docker, got %v", agent.Labels)
+	}
+	for _, s := range snapshots {
+		if s.Controller != "ci" || !s.TakenAt.Equal(takenAt) {
+			t.Errorf("snapshot %s: controller %q taken at %s", s.NodeName, s.Controller, s.TakenAt)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package jenkins
+
+import (
+	"log"
+	"strings"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// changeSetFields selects the commits of a build. Freestyle builds report a
+// single changeSet, Pipeline runs one changeSets entry per checkout.
+const changeSetFields = "changeSet[kind,items[msg,author[fullName],commitId,affectedPaths]]," +
+	"changeSets[kind,items[msg,author[fullName],commitId,affectedPaths]]"
+
+type ChangeSet struct {
+	Kind  string       `json:"kind"`
+	Items []ChangeItem `json:"items"`
+}
+
+type ChangeItem struct {
+	CommitID string `json:"commitId"`
+	Msg      string `json:"msg"`
+	Author   struct {
+		FullName string `json:"fullName"`
+	} `json:"author"`
+	AffectedPaths []string `json:"affectedPaths"`
+}
+
+// extractChanges flattens the change sets of a build. It returns nil when the
+// response carried neither field, so the change collector fetches them later.
+func extractChanges(b Build) []models.BuildChange {
+	if b.ChangeSet == nil && b.ChangeSets == nil {
+		return nil
+	}
+	sets := b.ChangeSets
+	if b.ChangeSet != nil {
+		sets = append([]ChangeSet{*b.ChangeSet}, sets...)
+	}
+
+	changes := []models.BuildChange{}
+	seen := make(map[string]bool)
+	for _, set := range sets {
+		for _, item := range set.Items {
+			id := strings.ToLower(item.CommitID)
+			if id == "" || seen[id] {
+				continue
+			}
+			seen[id] = true
+			changes = append(changes, models.BuildChange{
+				CommitID: id,
+				Author:   item.Author.FullName,
+				Message:  strings.TrimSpace(item.Msg),
+				Paths:    item.AffectedPaths,
+				Kind:     set.Kind,
+			})
+		}
+	}
+	return changes
+}
+
+// FetchBuildChanges calls <build>/api/json for the change sets of a build.
+func (jc *JenkinsClient) FetchBuildChanges(buildURL string) ([]models.BuildChange, error) {
+	var b Build
+	if err := jc.getJSON(strings.TrimSuffix(buildURL, "/")+"/api/json?tree="+changeSetFields, &b); err != nil {
+		return nil, err
+	}
+	changes := extractChanges(b)
+	if changes == nil {
+		// job type without change sets
+		changes = []models.BuildChange{}
+	}
+	return changes, nil
+}
+
+// CollectBuildChanges stores the commits of builds stored without them, e.g.
+// builds synced before changes were kept. Builds whose request fails are
+// retried with backoff and recorded without commits once Jenkins no longer has
+// them or after MaxCollectAttempts, so they do not block the batch.
+func CollectBuildChanges(database *db.DB, client *JenkinsClient, batch int) (int, error) {
+	builds, err := database.GetBuildsMissingChanges(client.Name, batch)
+	if err != nil {
+		return 0, err
+	}
+
+	stored := 0
+	for _, b := range builds {
+		if b.JobURL == "" {
+			continue
+		}
+		changes, err := client.FetchBuildChanges(b.JobURL)
+		if err != nil {
+			if !isGone(err) && retryLater(database, db.CollectChanges, b.ID, err) {
+				continue
+			}
+			changes = []models.BuildChange{}
+		}
+		if err := database.SaveBuildChanges(b.ID, changes); err != nil {
+			log.Printf("[Changes] store failed for build ID %d: %v", b.ID, err)
+			continue
+		}
+		if err := database.ClearCollectAttempts(db.CollectChanges, b.ID); err != nil {
+			log.Printf("[Changes] %v", err)
+		}
+		stored++
+	}
+
+	return stored, nil
+}
@@ -0,0 +1,242 @@
+package jenkins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+const (
+	// buildsWindowSize is how many builds Jenkins returns for `builds` per job.
+	buildsWindowSize = 100
+	// historyPageSize is the allBuilds{from,to} range requested per call.
+	historyPageSize = 100
+)
+
+// JobRef is a buildable job found while walking the folder tree.
+type JobRef struct {
+	Name string
+	URL  string
+}
+
+// ListJobs walks the folder tree and returns every non-folder job.
+func (jc *JenkinsClient) ListJobs() ([]JobRef, error) {
+	return jc.listJobsRecursive(jc.BaseURL)
+}
+
+func (jc *JenkinsClient) listJobsRecursive(folderURL string) ([]JobRef, error) {
+	apiURL := fmt.Sprintf("%s/api/json?tree=jobs[name,url,_class]", strings.TrimSuffix(folderURL, "/"))
+
+	var data struct {
+		Jobs []struct {
+			Class string `json:"_class"`
+			Name  string `json:"name"`
+			URL   string `json:"url"`
+		} `json:"jobs"`
+	}
+	if err := jc.getJSON(apiURL, &data); err != nil {
+		return nil, err
+	}
+
+	var jobs []JobRef
+	for _, job := range data.Jobs {
+		if strings.Contains(job.Class, "Folder") {
+			children, err := jc.listJobsRecursive(job.URL)
+			if err != nil {
+				log.Printf("Error listing nested folder: %s: %v", job.URL, err)
+				continue
+			}
+			jobs = append(jobs, children...)
+			continue
+		}
+		jobs = append(jobs, JobRef{Name: job.Name, URL: job.URL})
+	}
+	return jobs, nil
+}
+
+// FetchBuildPage returns allBuilds{from,to} of a job, newest first.
+func (jc *JenkinsClient) FetchBuildPage(jobURL, jobName string, from, to int) ([]Build, error) {
+	apiURL := fmt.Sprintf("%s/api/json?tree=allBuilds[%s]{%d,%d}", strings.TrimSuffix(jobURL, "/"), buildTreeFields, from, to)
+
+	var data struct {
+		AllBuilds []Build `json:"allBuilds"`
+	}
+	if err := jc.getJSON(apiURL, &data); err != nil {
+		return nil, err
+	}
+	for i := range data.AllBuilds {
+		data.AllBuilds[i].ProjectName = jobName
+	}
+	return data.AllBuilds, nil
+}
+
+// FetchBuildsSince pages allBuilds of a job from offset until it reaches a
+// build numbered after or lower, or runs out of history.
+func (jc *JenkinsClient) FetchBuildsSince(jobURL, jobName string, offset, after int) ([]Build, error) {
+	var out []Build
+	for {
+		page, err := jc.FetchBuildPage(jobURL, jobName, offset, offset+historyPageSize)
+		if err != nil {
+			return out, err
+		}
+		for _, b := range page {
+			if b.Number <= after {
+				return out, nil
+			}
+			out = append(out, b)
+		}
+		if len(page) < historyPageSize {
+			return out, nil
+		}
+		offset += historyPageSize
+	}
+}
+
+// ErrBackfillIncomplete is returned, wrapped, when the history of some jobs
+// could not be fetched. Everything else was stored and the failed jobs resume
+// from their checkpoint on the next run.
+var ErrBackfillIncomplete = errors.New("backfill incomplete")
+
+// buildStore is the part of *db.DB the build pullers read and write.
+type buildStore interface {
+	InsertBuild(b *models.Build) error
+	GetJobCursors(controller string) (map[string]models.JobCursor, error)
+	SaveJobCursors(cursors []models.JobCursor) error
+	GetSyncStatus(key string) (value string, ok bool, err error)
+	SetSyncStatus(key, value string) error
+}
+
+var _ buildStore = (*db.DB)(nil)
+
+// BackfillHistory stores the complete build history of every job of a controller.
+// Progress is checkpointed per job in sync_status, so an interrupted run resumes
+// where it stopped instead of starting over. A job that cannot be fetched is
+// logged, its error recorded on its cursor and skipped; the backfill only fails
+// outright when no job could be fetched at all.
+func BackfillHistory(database buildStore, client *JenkinsClient) (int, int, []int, error) {
+	jobs, err := client.ListJobs()
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("list jobs failed: %w", err)
+	}
+	if len(jobs) == 0 {
+		return 0, 0, nil, fmt.Errorf("no jobs found on %s", client.BaseURL)
+	}
+
+	saved, failed := 0, 0
+	var failedBuilds []int
+	var failedJobs []string
+	var lastErr error
+
+	for i, job := range jobs {
+		jobPath := jobPathFromURL(job.URL, client.BaseURL)
+		key := db.SyncKey("backfill", client.Name) + ":" + jobPath
+
+		checkpoint, _, err := database.GetSyncStatus(key)
+		if err != nil {
+			return saved, failed, failedBuilds, err
+		}
+		if checkpoint == "done" {
+			continue
+		}
+		// Builds added since the checkpoint shift older ones to higher offsets, so
+		// resuming one page early only re-reads rows that are already stored.
+		offset, _ := strconv.Atoi(checkpoint)
+		offset = max(offset-historyPageSize, 0)
+
+		cursor := models.JobCursor{Controller: client.Name, ProjectPath: jobPath}
+		for {
+			page, err := client.FetchBuildPage(job.URL, job.Name, offset, offset+historyPageSize)
+			if err != nil {
+				cursor.LastError = fmt.Sprintf("backfill at %d: %v", offset, err)
+				break
+			}
+			if len(page) > 0 {
+				cursor.LastBuildNumber = max(cursor.LastBuildNumber, page[0].Number)
+			}
+			for _, b := range page {
+				if err := database.InsertBuild(toModelBuild(b, client)); err != nil {
+					log.Printf("Insert failed for build #%d: %v", b.Number, err)
+					failedBuilds = append(failedBuilds, b.Number)
+					failed++
+					continue
+				}
+				saved++
+			}
+			if len(page) < historyPageSize {
+				break
+			}
+			offset += historyPageSize
+			// the cursor is saved with every checkpoint, as a resumed run does not
+			// see the newest page again
+			if err := database.SaveJobCursors([]models.JobCursor{cursor}); err != nil {
+				return saved, failed, failedBuilds, err
+			}
+			if err := database.SetSyncStatus(key, strconv.Itoa(offset)); err != nil {
+				return saved, failed, failedBuilds, err
+			}
+		}
+
+		if err := database.SaveJobCursors([]models.JobCursor{cursor}); err != nil {
+			return saved, failed, failedBuilds, err
+		}
+		if cursor.LastError != "" {
+			// the checkpoint stays, the next run resumes this job
+			log.Printf("[Backfill] %s: %s failed, skipping it (%d/%d jobs): %s", client.Name, jobPath, i+1, len(jobs), cursor.LastError)
+			failedJobs = append(failedJobs, jobPath)
+			lastErr = errors.New(cursor.LastError)
+			continue
+		}
+		if err := database.SetSyncStatus(key, "done"); err != nil {
+			return saved, failed, failedBuilds, err
+		}
+		log.Printf("[Backfill] %s: %s complete (%d/%d jobs)", client.Name, jobPath, i+1, len(jobs))
+	}
+
+	if len(failedJobs) == len(jobs) {
+		return saved, failed, failedBuilds, fmt.Errorf("no job of %s could be fetched, last error: %w", client.Name, lastErr)
+	}
+	if len(failedJobs) > 0 {
+		return saved, failed, failedBuilds, fmt.Errorf("%w: %d of %d jobs of %s failed: %s",
+			ErrBackfillIncomplete, len(failedJobs), len(jobs), client.Name, strings.Join(failedJobs, ", "))
+	}
+	return saved, failed, failedBuilds, nil
+}
+
+// getJSON performs an authenticated GET and decodes the JSON response into v.
+func (jc *JenkinsClient) getJSON(apiURL string, v interface{}) error {
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request for %s: %w", apiURL, err)
+	}
+	if jc.Username != "" && jc.APIToken != "" {
+		req.SetBasicAuth(jc.Username, jc.APIToken)
+	}
+
+	resp, err := jc.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error fetching %s: %w", apiURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{URL: apiURL, Code: resp.StatusCode}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("error decoding %s: %w", apiURL, err)
+	}
+	return nil
+}
+
+// jobPathFromURL mirrors extractProjectPathFromURL for a job (not build) URL.
+func jobPathFromURL(jobURL, baseURL string) string {
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(jobURL, baseURL), "/")
+	trimmed = strings.TrimPrefix(trimmed, "/job/")
+	return strings.ReplaceAll(trimmed, "/job/", "/")
+}
@@ -0,0 +1,131 @@
+package jenkins
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/jarcoal/httpmock"
+)
+
+// fakeBuildStore keeps builds, job cursors and sync_status in memory.
+type fakeBuildStore struct {
+	builds  map[string]bool // project path #number
+	cursors map[string]models.JobCursor
+	status  map[string]string
+	failOn  map[int]bool // build numbers whose insert fails
+}
+
+func newFakeBuildStore() *fakeBuildStore {
+	return &fakeBuildStore{
+		builds:  make(map[string]bool),
+		cursors: make(map[string]models.JobCursor),
+		status:  make(map[string]string),
+		failOn:  make(map[int]bool),
+	}
+}
+
+func (s *fakeBuildStore) InsertBuild(b *models.Build) error {
+	if s.failOn[b.BuildNumber] {
+		return errors.New("insert failed")
+	}
+	s.builds[fmt.Sprintf("%s#%d", b.ProjectPath, b.BuildNumber)] = true
+	return nil
+}
+
+func (s *fakeBuildStore) GetJobCursors(controller string) (map[string]models.JobCursor, error) {
+	out := make(map[string]models.JobCursor)
+	for path, c := range s.cursors {
+		out[path] = c
+	}
+	return out, nil
+}
+
+// SaveJobCursors mirrors the upsert: the number never moves backwards, the error is replaced.
+func (s *fakeBuildStore) SaveJobCursors(cursors []models.JobCursor) error {
+	for _, c := range cursors {
+		if prev, ok := s.cursors[c.ProjectPath]; ok {
+			c.LastBuildNumber = max(c.LastBuildNumber, prev.LastBuildNumber)
+		}
+		s.cursors[c.ProjectPath] = c
+	}
+	return nil
+}
+
+func (s *fakeBuildStore) GetSyncStatus(key string) (string, bool, error) {
+	v, ok := s.status[key]
+	return v, ok, nil
+}
+
+func (s *fakeBuildStore) SetSyncStatus(key, value string) error {
+	s.status[key] = value
+	return nil
+}
+
+// registerJobs serves a root folder with the given jobs; each job has builds
+// count..1 unless its status is not 200.
+func registerJobs(jobs map[string]int, status map[string]int) {
+	var refs []string
+	for name := range jobs {
+		refs = append(refs, fmt.Sprintf(`{"_class":"hudson.model.FreeStyleProject","name":%q,"url":"http://jenkins.local/job/%s/"}`, name, name))
+	}
+	httpmock.RegisterResponder("GET", "http://jenkins.local/api/json?tree=jobs[name,url,_class]",
+		httpmock.NewStringResponder(200, `{"jobs":[`+strings.Join(refs, ",")+`]}`))
+
+	for name, count := range jobs {
+		name, count := name, count
+		httpmock.RegisterResponder("GET", "http://jenkins.local/job/"+name+"/api/json",
+			func(req *http.Request) (*http.Response, error) {
+				if code := status[name]; code != 0 {
+					return httpmock.NewStringResponse(code, ""), nil
+				}
+				var from, to int
+				tree := req.URL.Query().Get("tree")
+				fmt.Sscanf(tree[strings.LastIndex(tree, "{"):], "{%d,%d}", &from, &to)
+				var builds []string
+				for n := count - from; n > count-to && n > 0; n-- {
+					builds = append(builds, fmt.Sprintf(`{"number":%d,"timestamp":1680000000000,"url":"http://jenkins.local/job/%s/%d/"}`, n, name, n))
+				}
+				return httpmock.NewStringResponse(200, `{"allBuilds":[`+strings.Join(builds, ",")+`]}`), nil
+			})
+	}
+}
+
+func TestBackfillHistorySkipsFailingJobs(t *testing.T) {
+	client := NewJenkinsClient("http://jenkins.local", "user", "token")
+	httpmock.ActivateNonDefault(client.Client)
+	defer httpmock.DeactivateAndReset()
+
+	status := map[string]int{"locked": http.StatusForbidden}
+	registerJobs(map[string]int{"app": 150, "locked": 10}, status)
+	store := newFakeBuildStore()
+
+	saved, _, _, err := BackfillHistory(store, client)
+	if !errors.Is(err, ErrBackfillIncomplete) || !strings.Contains(err.Error(), "locked") {
+		t.Fatalf("expected an incomplete backfill naming the failed job, got %v", err)
+	}
+	if saved != 150 || store.status["backfill:app"] != "done" {
+		t.Errorf("expected app to be backfilled, saved %d, checkpoint %q", saved, store.status["backfill:app"])
+	}
+	if c := store.cursors["locked"]; !strings.Contains(c.LastError, "403") || store.status["backfill:locked"] == "done" {
+		t.Errorf("expected the failure on the cursor and no done checkpoint, got %+v, %q", c, store.status["backfill:locked"])
+	}
+
+	// the next run only retries the failed job
+	delete(status, "locked")
+	saved, _, _, err = BackfillHistory(store, client)
+	if err != nil || saved != 10 {
+		t.Fatalf("expected the retry to store the 10 builds of locked, got %d (%v)", saved, err)
+	}
+	if c := store.cursors["locked"]; c.LastError != "" || c.LastBuildNumber != 10 {
+		t.Errorf("expected a clean cursor at 10, got %+v", c)
+	}
+
+	status["app"], status["locked"] = http.StatusNotFound, http.StatusNotFound
+	if _, _, _, err := BackfillHistory(newFakeBuildStore(), client); err == nil || errors.Is(err, ErrBackfillIncomplete) {
+		t.Errorf("expected an error when no job can be fetched, got %v", err)
+	}
+}
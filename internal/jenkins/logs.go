@@ -0,0 +1,271 @@
+package jenkins
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/config"
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// tailWindow is how much of the end of a truncated log is fetched for its tail lines.
+const tailWindow = 1 << 20
+
+// StatusError is a non-200 answer from Jenkins.
+type StatusError struct {
+	URL  string
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("Jenkins returned status %d for %s", e.Code, e.URL)
+}
+
+// isGone reports whether Jenkins no longer has the resource, so fetching it
+// again is pointless. Timeouts, auth and server errors may pass.
+func isGone(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && (se.Code == http.StatusNotFound || se.Code == http.StatusGone)
+}
+
+// retryLater records a failed fetch of a build by collector and reports whether
+// it should be tried again; false once MaxCollectAttempts were used up.
+func retryLater(database *db.DB, collector string, buildID int, err error) bool {
+	attempts, rerr := database.RecordCollectFailure(collector, buildID, err)
+	if rerr != nil {
+		log.Printf("[Collect] %v", rerr)
+		return true
+	}
+	if attempts < db.MaxCollectAttempts {
+		log.Printf("[Collect] %s fetch failed for build ID %d (attempt %d), retrying later: %v", collector, buildID, attempts, err)
+		return true
+	}
+	log.Printf("[Collect] %s fetch failed for build ID %d %d times, giving up: %v", collector, buildID, attempts, err)
+	return false
+}
+
+// withTimeout returns a copy of the client whose requests may take up to d.
+func (jc *JenkinsClient) withTimeout(d time.Duration) *JenkinsClient {
+	if d <= 0 {
+		return jc
+	}
+	c := *jc
+	hc := *jc.Client
+	hc.Timeout = d
+	c.Client = &hc
+	return &c
+}
+
+func (jc *JenkinsClient) getLogText(logURL string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", logURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", logURL, err)
+	}
+	if jc.Username != "" && jc.APIToken != "" {
+		req.SetBasicAuth(jc.Username, jc.APIToken)
+	}
+
+	resp, err := jc.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", logURL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &StatusError{URL: logURL, Code: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+// FetchConsoleText downloads <build>/consoleText, reading at most maxBytes.
+// truncated reports whether the log was longer than that.
+func (jc *JenkinsClient) FetchConsoleText(buildURL string, maxBytes int64) (text []byte, truncated bool, err error) {
+	logURL := strings.TrimSuffix(buildURL, "/") + "/consoleText"
+
+	resp, err := jc.getLogText(logURL)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	text, err = io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading %s: %w", logURL, err)
+	}
+	if int64(len(text)) > maxBytes {
+		return text[:maxBytes], true, nil
+	}
+	return text, false, nil
+}
+
+// FetchConsoleEnd returns up to n bytes from the end of a log known to be longer
+// than offset bytes. logText/progressiveText reports the full size in
+// X-Text-Size, so only the end is downloaded even for very large logs.
+func (jc *JenkinsClient) FetchConsoleEnd(buildURL string, offset, n int64) ([]byte, error) {
+	progressURL := strings.TrimSuffix(buildURL, "/") + "/logText/progressiveText?start="
+
+	resp, err := jc.getLogText(progressURL + strconv.FormatInt(offset, 10))
+	if err != nil {
+		return nil, err
+	}
+	size, _ := strconv.ParseInt(resp.Header.Get("X-Text-Size"), 10, 64)
+	if size > offset+n {
+		resp.Body.Close()
+		if resp, err = jc.getLogText(progressURL + strconv.FormatInt(size-n, 10)); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+
+	// without a size the remainder is streamed, keep only its last n bytes
+	var end []byte
+	buf := make([]byte, 32<<10)
+	for {
+		k, err := resp.Body.Read(buf)
+		end = append(end, buf[:k]...)
+		if int64(len(end)) > n {
+			end = append(end[:0], end[int64(len(end))-n:]...)
+		}
+		if err == io.EOF {
+			return end, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading end of %s: %w", buildURL, err)
+		}
+	}
+}
+
+// CollectConsoleLogs captures console logs for finished builds that have none yet.
+// Builds whose log is gone get a row with fetch_error so they are not retried;
+// other failures are retried with backoff until MaxCollectAttempts.
+func CollectConsoleLogs(database *db.DB, client *JenkinsClient, cfg config.LogConfig) (int, error) {
+	builds, err := database.GetBuildsMissingLogs(client.Name, cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	logClient := client.withTimeout(cfg.Timeout)
+
+	stored := 0
+	for _, b := range builds {
+		entry := &models.BuildLog{
+			BuildID:     b.ID,
+			BuildNumber: b.BuildNumber,
+			ProjectName: b.ProjectName,
+		}
+
+		if err := captureLog(logClient, b.JobURL, cfg, entry); err != nil {
+			if !isGone(err) && retryLater(database, db.CollectLogs, b.ID, err) {
+				continue
+			}
+			entry.FetchError = err.Error()
+		}
+
+		if err := database.InsertBuildLog(entry); err != nil {
+			log.Printf("[Logs] store failed for build ID %d: %v", b.ID, err)
+			continue
+		}
+		if err := database.ClearCollectAttempts(db.CollectLogs, b.ID); err != nil {
+			log.Printf("[Logs] %v", err)
+		}
+		if entry.FetchError == "" {
+			stored++
+		}
+	}
+
+	return stored, nil
+}
+
+// captureLog fills entry with the head, tail and compressed copy of a build's
+// console. The tail of a truncated log is taken from the real end of the log,
+// where failures are reported, not from the end of the captured part.
+func captureLog(client *JenkinsClient, buildURL string, cfg config.LogConfig, entry *models.BuildLog) error {
+	text, truncated, err := client.FetchConsoleText(buildURL, cfg.MaxBytes)
+	if err != nil {
+		return err
+	}
+
+	head, tail := splitHeadTail(string(text), cfg.HeadLines, cfg.TailLines)
+	if truncated {
+		end, err := client.FetchConsoleEnd(buildURL, cfg.MaxBytes, tailWindow)
+		if err != nil {
+			return err
+		}
+		// the window starts mid-line, that partial line is dropped
+		if i := bytes.IndexByte(end, '\n'); i >= 0 {
+			end = end[i+1:]
+		}
+		head = firstLines(string(text), cfg.HeadLines)
+		tail = lastLines(string(end), cfg.TailLines)
+	}
+
+	entry.ConsoleLogHead = sanitizeText(head)
+	entry.ConsoleLogTail = sanitizeText(tail)
+	entry.SizeBytes = int64(len(text))
+	entry.Truncated = truncated
+	entry.ConsoleLogGz, err = gzipBytes(text)
+	if err != nil {
+		return fmt.Errorf("compress failed: %w", err)
+	}
+	return nil
+}
+
+// splitHeadTail keeps the first head and last tail lines. When the log is short
+// enough to fit in both, the whole text is returned as head and tail is empty.
+func splitHeadTail(text string, head, tail int) (string, string) {
+	lines := splitLines(text)
+	if len(lines) <= head+tail {
+		return text, ""
+	}
+	return strings.Join(lines[:head], ""), strings.Join(lines[len(lines)-tail:], "")
+}
+
+func firstLines(text string, n int) string {
+	lines := splitLines(text)
+	if len(lines) <= n {
+		return text
+	}
+	return strings.Join(lines[:n], "")
+}
+
+func lastLines(text string, n int) string {
+	lines := splitLines(text)
+	if len(lines) <= n {
+		return text
+	}
+	return strings.Join(lines[len(lines)-n:], "")
+}
+
+// splitLines splits after each newline; a final newline does not start another line.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// sanitizeText makes console output storable as Postgres TEXT, which rejects
+// NUL bytes and invalid UTF-8 (binary output, or a rune cut at maxBytes).
+func sanitizeText(s string) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(s, "�"), "\x00", "")
+}
+
+func gzipBytes(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
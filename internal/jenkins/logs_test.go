@@ -0,0 +1,132 @@
+package jenkins
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/config"
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/jarcoal/httpmock"
+)
+
+func TestCaptureTruncatedLog(t *testing.T) {
+	client := NewJenkinsClient("http://jenkins.local", "user", "token")
+	httpmock.ActivateNonDefault(client.Client)
+	defer httpmock.DeactivateAndReset()
+
+	var full strings.Builder
+	for i := 1; i <= 5000; i++ {
+		fmt.Fprintf(&full, "line %d\n", i)
+	}
+	full.WriteString("ERROR: build failed\x00 \xff\n")
+	console := full.String()
+
+	httpmock.RegisterResponder("GET", "http://jenkins.local/job/app/1/consoleText",
+		httpmock.NewStringResponder(200, console))
+	httpmock.RegisterResponder("GET", "http://jenkins.local/job/app/1/logText/progressiveText",
+		func(req *http.Request) (*http.Response, error) {
+			start, err := strconv.Atoi(req.URL.Query().Get("start"))
+			if err != nil || start > len(console) {
+				return httpmock.NewStringResponse(400, "bad start"), nil
+			}
+			resp := httpmock.NewStringResponse(200, console[start:])
+			resp.Header.Set("X-Text-Size", strconv.Itoa(len(console)))
+			return resp, nil
+		})
+
+	end, err := client.FetchConsoleEnd("http://jenkins.local/job/app/1/", 100, 64)
+	if err != nil || string(end) != console[len(console)-64:] {
+		t.Fatalf("expected the last 64 bytes, got %q (%v)", end, err)
+	}
+
+	cfg := config.LogConfig{HeadLines: 2, TailLines: 3, MaxBytes: 1000}
+	var entry models.BuildLog
+	if err := captureLog(client, "http://jenkins.local/job/app/1/", cfg, &entry); err != nil {
+		t.Fatalf("captureLog failed: %v", err)
+	}
+	if !entry.Truncated || entry.SizeBytes != 1000 || entry.ConsoleLogHead != "line 1\nline 2\n" {
+		t.Errorf("unexpected head %q (truncated %v, size %d)", entry.ConsoleLogHead, entry.Truncated, entry.SizeBytes)
+	}
+	if entry.ConsoleLogTail != "line 4999\nline 5000\nERROR: build failed �\n" {
+		t.Errorf("expected the sanitised end of the log as tail, got %q", entry.ConsoleLogTail)
+	}
+
+	httpmock.RegisterResponder("GET", "http://jenkins.local/job/gone/1/consoleText", httpmock.NewStringResponder(404, ""))
+	httpmock.RegisterResponder("GET", "http://jenkins.local/job/busy/1/consoleText", httpmock.NewStringResponder(503, ""))
+	if err := captureLog(client, "http://jenkins.local/job/gone/1/", cfg, &entry); !isGone(err) {
+		t.Errorf("expected a 404 to be permanent, got %v", err)
+	}
+	if err := captureLog(client, "http://jenkins.local/job/busy/1/", cfg, &entry); err == nil || isGone(err) {
+		t.Errorf("expected a 503 to be retried, got %v", err)
+	}
+}
+
+func TestSanitizeText(t *testing.T) {
+	cut := "naïve"[:3] // cuts the two-byte ï in half
+	cases := map[string]string{
+		"plain\n":      "plain\n",
+		"nul\x00byte":  "nulbyte",
+		cut:            "na�",
+		"bin \xff\xfe": "bin �",
+	}
+	for in, want := range cases {
+		if got := sanitizeText(in); got != want {
+			t.Errorf("sanitizeText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSplitHeadTail(t *testing.T) {
+	tests := []struct {
+		text       string
+		head, tail string
+	}{
+		{"a\nb\nc\n", "a\nb\nc\n", ""}, // fits, kept whole
+		{"1\n2\n3\n4\n5\n6\n", "1\n2\n", "5\n6\n"},
+		{"1\n2\n3\n4\n5\n6", "1\n2\n", "5\n6"}, // no trailing newline
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		head, tail := splitHeadTail(tt.text, 2, 2)
+		if head != tt.head || tail != tt.tail {
+			t.Errorf("splitHeadTail(%q) = %q, %q, want %q, %q", tt.text, head, tail, tt.head, tt.tail)
+		}
+	}
+}
+
+func TestCaptureLog(t *testing.T) {
+	client := NewJenkinsClient("http://jenkins.local", "user", "token")
+	httpmock.ActivateNonDefault(client.Client)
+	defer httpmock.DeactivateAndReset()
+
+	console := "start\nstep 1\nstep 2\nstep 3\nstep 4\nfailed\n"
+	httpmock.RegisterResponder("GET", "http://jenkins.local/job/app/2/consoleText",
+		httpmock.NewStringResponder(200, console))
+
+	cfg := config.LogConfig{HeadLines: 1, TailLines: 2, MaxBytes: 1 << 20}
+	var entry models.BuildLog
+	if err := captureLog(client, "http://jenkins.local/job/app/2/", cfg, &entry); err != nil {
+		t.Fatalf("captureLog failed: %v", err)
+	}
+	if entry.Truncated || entry.SizeBytes != int64(len(console)) {
+		t.Errorf("expected the full log, got size %d (truncated %v)", entry.SizeBytes, entry.Truncated)
+	}
+	if entry.ConsoleLogHead != "start\n" || entry.ConsoleLogTail != "step 4\nfailed\n" {
+		t.Errorf("unexpected head %q and tail %q", entry.ConsoleLogHead, entry.ConsoleLogTail)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(entry.ConsoleLogGz))
+	if err != nil {
+		t.Fatalf("stored log is not gzip: %v", err)
+	}
+	full, err := io.ReadAll(zr)
+	if err != nil || string(full) != console {
+		t.Errorf("gzip round trip: got %q (%v)", full, err)
+	}
+}
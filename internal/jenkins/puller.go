@@ -13,15 +13,19 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gauravkr19/jenkins-analytics/internal/config"
 	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/internal/envrules"
 	"github.com/gauravkr19/jenkins-analytics/models"
 )
 
 type JenkinsClient struct {
+	Name     string // controller name stored with each build
 	BaseURL  string
 	Username string
 	APIToken string
 	Client   *http.Client
+	EnvRules *envrules.RuleSet // environment detection, nil labels every build UNKNOWN
 }
 
 type JenkinsResponse struct {
@@ -45,7 +49,12 @@ type Build struct {
 	GitRepo     string   `json:"giturl"`
 	CommitSHA   string   `json:"gitcommit"`
 	Actions     []Action `json:"actions,omitempty"`
-	Env         string   `json:"env,omitempty"`  
+	Building          bool  `json:"building"`
+	QueueID           int64 `json:"queueId"`
+	EstimatedDuration int64 `json:"estimatedDuration"`
+	BuiltOn           string `json:"builtOn"` // empty for Pipeline runs and the built-in node
+	ChangeSet         *ChangeSet  `json:"changeSet,omitempty"`  // freestyle
+	ChangeSets        []ChangeSet `json:"changeSets,omitempty"` // Pipeline
 }
 
 type Action struct {
@@ -53,6 +62,8 @@ type Action struct {
 	Parameters   []Param      `json:"parameters,omitempty"`
 	RemoteURLs   []string     `json:"remoteUrls,omitempty"`
 	LastRevision *GitRevision `json:"lastBuiltRevision,omitempty"`
+	// TimeInQueueAction of the metrics plugin
+	QueuingDurationMillis int64 `json:"queuingDurationMillis,omitempty"`
 }
 
 type Param struct {
@@ -75,14 +86,33 @@ type Cause struct {
 }
 
 func NewJenkinsClient(baseURL, username, token string) *JenkinsClient {
+	return &JenkinsClient{
+		Name:     config.DefaultController,
+		BaseURL:  baseURL,
+		Username: username,
+		APIToken: token,
+		Client:   newHTTPClient(os.Getenv("JENKINS_CACERT"), os.Getenv("JENKINS_TLS_INSECURE") == "true"),
+	}
+}
+
+// NewControllerClient creates a client for one configured controller, with its
+// own credentials and CA bundle.
+func NewControllerClient(c config.Controller) *JenkinsClient {
+	return &JenkinsClient{
+		Name:     c.Name,
+		BaseURL:  c.URL,
+		Username: c.User,
+		APIToken: c.Token,
+		Client:   newHTTPClient(c.CACert, c.Insecure),
+	}
+}
+
+func newHTTPClient(caCertPath string, insecure bool) *http.Client {
 	timeout := 10 * time.Second
 
 	transport := http.DefaultTransport
 
 	// TLS config
-	caCertPath := os.Getenv("JENKINS_CACERT")
-	insecure := os.Getenv("JENKINS_TLS_INSECURE") == "true"
-
 	if caCertPath != "" {
 		caCert, err := os.ReadFile(caCertPath)
 		if err == nil {
@@ -101,18 +131,46 @@ func NewJenkinsClient(baseURL, username, token string) *JenkinsClient {
 		}
 	}
 
-	return &JenkinsClient{
-		BaseURL:  baseURL,
-		Username: username,
-		APIToken: token,
-		Client: &http.Client{
-			Timeout:   timeout,
-			Transport: transport,
-		},
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
+
+// Owns reports whether url points into this controller, i.e. below its base
+// URL. Only such URLs may be fetched with the controller's credentials.
+func (jc *JenkinsClient) Owns(url string) bool {
+	base := strings.TrimSuffix(jc.BaseURL, "/")
+	return base != "" && strings.HasPrefix(url, base+"/")
+}
+
+// ControllerFor picks the client a build belongs to: by name when given,
+// otherwise by the longest base URL prefix of buildURL.
+func ControllerFor(clients []*JenkinsClient, name, buildURL string) *JenkinsClient {
+	if name != "" {
+		for _, c := range clients {
+			if c.Name == name {
+				return c
+			}
+		}
+		return nil
+	}
+
+	var best *JenkinsClient
+	for _, c := range clients {
+		if c.Owns(buildURL) && (best == nil || len(c.BaseURL) > len(best.BaseURL)) {
+			best = c
+		}
 	}
+	return best
 }
 
+// buildTreeFields is the per-build field selection shared by the builds window
+// and the allBuilds history queries.
+const buildTreeFields = "number,result,duration,timestamp,url,builtOn,building,queueId,estimatedDuration,actions[causes[userId,userName,shortDescription],parameters[name,value],lastBuiltRevision[branch[name],SHA1],remoteUrls,queuingDurationMillis]," + changeSetFields
+
 // call seq: external -> FetchAndStoreBuilds -> FetchBuilds -> fetchBuildsRecursive
+// FetchBuilds returns the builds window (most recent builds) of every job.
 func (jc *JenkinsClient) FetchBuilds() ([]Build, error) {
 	return jc.fetchBuildsRecursive(jc.BaseURL)
 }
@@ -120,7 +178,7 @@ func (jc *JenkinsClient) FetchBuilds() ([]Build, error) {
 func (jc *JenkinsClient) fetchBuildsRecursive(folderURL string) ([]Build, error) {
 
 	// apiURL := fmt.Sprintf("%s/api/json?tree=jobs[name,url,_class,builds[number,result,duration,timestamp,url,builtOn,actions[causes[userId,userName],parameters[name,value],lastBuiltRevision[branch[name],SHA1],remoteUrls],changeSet[items[msg,author[fullName],commitId],kind]]]", strings.TrimSuffix(folderURL, "/"))
-	apiURL := fmt.Sprintf("%s/api/json?tree=jobs[name,url,_class,builds[number,result,duration,timestamp,url,builtOn,actions[causes[userId,userName,shortDescription],parameters[name,value],lastBuiltRevision[branch[name],SHA1],remoteUrls],changeSet[items[msg,author[fullName],commitId],kind]]]", strings.TrimSuffix(folderURL, "/"))
+	apiURL := fmt.Sprintf("%s/api/json?tree=jobs[name,url,_class,builds[%s]]", strings.TrimSuffix(folderURL, "/"), buildTreeFields)
 
 	req, err := http.NewRequest("GET", apiURL, nil)
 	if err != nil {
@@ -155,27 +213,13 @@ func (jc *JenkinsClient) fetchBuildsRecursive(folderURL string) ([]Build, error)
 		if strings.Contains(job.Class, "Folder") {
 			childBuilds, err := jc.fetchBuildsRecursive(job.URL)
 			if err != nil {
-				log.Printf("Error fetching nested folder: %s", job.URL, err)
+				log.Printf("Error fetching nested folder: %s: %v", job.URL, err)
 				continue
 			}
 			builds = append(builds, childBuilds...)
 		} else {
 			for _, b := range job.Builds {
 				b.ProjectName = job.Name
-
-				// Common parameter keys people use; add your exact one here
-				// Exact matches (fast + preferred)
-				if env, ok := b.GetParamString(
-					"ENV", "Environment", "environment",
-					"TARGET_ENV", "DEPLOY_ENV", "DEPLOYING_ENVIRONMENT",
-				); ok {
-					b.Env = normalizeEnv(env)
-				} else {
-					// 2) Fallback heuristic: scan all params and guess
-					if env, ok := b.GuessEnvFromParams(); ok {
-						b.Env = normalizeEnv(env)
-					}
-				}
 				builds = append(builds, b)
 			}
 		}
@@ -184,206 +228,180 @@ func (jc *JenkinsClient) fetchBuildsRecursive(folderURL string) ([]Build, error)
 	return builds, nil
 }
 
-func normalizeEnv(s string) string {
-    return strings.ToLower(strings.TrimSpace(s))
-}
-// GuessEnvFromParams looks for env-ish param names beyond the allowlist.
-func (b *Build) GuessEnvFromParams() (string, bool) {
-    bestScore := -1
-    bestVal := ""
-
-    for _, a := range b.Actions {
-        for _, p := range a.Parameters {
-            name := strings.ToLower(strings.TrimSpace(p.Name))
-            if name == "" {
-                continue
-            }
-
-            // Skip obvious non-env params (avoid false positives)
-            if strings.Contains(name, "git") ||
-                strings.Contains(name, "branch") ||
-                strings.Contains(name, "commit") ||
-                strings.Contains(name, "sha") ||
-                strings.Contains(name, "version") ||
-                strings.Contains(name, "tag") ||
-                strings.Contains(name, "repo") {
-                continue
-            }
-
-            // Score likely env keys
-            score := 0
-            switch {
-            case strings.Contains(name, "deploying_environment"):
-                score = 60
-            case strings.Contains(name, "deploy_env"):
-                score = 55
-            case strings.Contains(name, "target_env"):
-                score = 50
-            case strings.Contains(name, "environment"):
-                score = 45
-            case name == "env" || strings.HasSuffix(name, "_env"):
-                score = 40
-            case strings.Contains(name, "env"):
-                score = 30
-            case name == "stage" || strings.Contains(name, "stage"):
-                score = 25
-            }
-
-            if score == 0 {
-                continue
-            }
-
-            // Convert value to string
-            val := fmt.Sprintf("%v", p.Value)
-            if strings.TrimSpace(val) == "" {
-                continue
-            }
-
-            if score > bestScore {
-                bestScore = score
-                bestVal = val
-            }
-        }
-    }
-
-    if bestScore >= 0 {
-        return bestVal, true
-    }
-    return "", false
-}
+// Fetches build data from Jenkins and writes to DB. The initial (non-incremental)
+// run backfills the complete history of every job; incremental runs store builds
+// newer than the last seen one and page further back whenever the builds window
+// does not reach it.
+func FetchAndStoreBuilds(db buildStore, client *JenkinsClient, incremental bool) (int, int, []int, error) {
+	if !incremental {
+		return BackfillHistory(db, client)
+	}
 
-// Fetches build data from Jenkins and writes to DB
-func FetchAndStoreBuilds(db *db.DB, client *JenkinsClient, incremental bool) (int, int, []int, error) {
 	builds, err := client.FetchBuilds()
 	if err != nil {
 		return 0, 0, nil, fmt.Errorf("fetch builds failed: %w", err)
 	}
 
-	saved, failed := 0, 0
-	var failedBuilds []int
-
+	// group the window per job, keeping Jenkins' newest-first order
+	var jobURLs []string
+	byJob := make(map[string][]Build)
 	for _, b := range builds {
-		if incremental {
-			lastSeen, err := db.GetLastSeenBuildNumber(b.ProjectName)
-			if err != nil {
-				log.Printf("Error getting last seen for project %s: %v", b.ProjectName, err)
-				continue
-			}
-			if b.Number <= lastSeen {
-				continue // Already stored
-			}
+		jobURL := strings.TrimSuffix(b.URL, fmt.Sprintf("%d/", b.Number))
+		if _, ok := byJob[jobURL]; !ok {
+			jobURLs = append(jobURLs, jobURL)
 		}
+		byJob[jobURL] = append(byJob[jobURL], b)
+	}
 
-		userID := extractUserID(b.Actions)
+	// one read for all job cursors of this controller, one write after the poll
+	cursors, err := db.GetJobCursors(client.Name)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	updated := make([]models.JobCursor, 0, len(jobURLs))
 
-		gitURL, branch, sha := extractGitInfo(b.Actions)
-		params := extractParameters(b.Actions)
-		projectPath := extractProjectPathFromURL(b.URL, os.Getenv("JENKINS_URL"), b.Number)
+	saved, failed := 0, 0
+	var failedBuilds []int
 
-		deployEnv := b.Env 			// if env is "", then use params to capture it
-		if strings.TrimSpace(deployEnv) == "" {
-			deployEnv = extractAny(params, "DEPLOY_ENV", "DEPLOYING_ENVIRONMENT", "ENV", "TARGET_ENV")
+	for _, jobURL := range jobURLs {
+		window := byJob[jobURL]
+		jobPath := jobPathFromURL(jobURL, client.BaseURL)
+		lastSeen := cursors[jobPath].LastBuildNumber
+		cursor := models.JobCursor{Controller: client.Name, ProjectPath: jobPath, LastBuildNumber: lastSeen}
+
+		var fresh []Build
+		for _, b := range window {
+			if b.Number > lastSeen {
+				fresh = append(fresh, b)
+			}
 		}
-		deployEnv = strings.ToLower(strings.TrimSpace(deployEnv))
-		igrmNo := strings.TrimSpace(params["IGRM_NO"])		
-
-		dbModel := &models.Build{
-			BuildNumber: b.Number,
-			ProjectName: b.ProjectName,
-			ProjectPath: projectPath,
-			UserID:      userID,
-			Status:      b.Result,
-			Timestamp:   time.UnixMilli(b.Timestamp),
-			DurationMS:  b.Duration,
-			JobURL:      b.URL,
-			GitRepo:     gitURL,
-			Branch:      branch,
-			CommitSHA:   sha,						
-			DeployEnv:   deployEnv,						// parameter env 
-			TriggerType: extractTriggerType(b.Actions), // ShortDescription - Started by user
-			Env: 		 extractEnv(projectPath),		// env from folder path
-			IGRMNo:    	 igrmNo,
+		// window is newest first
+		next := window[0].Number
+
+		// The window is capped; if it is full and still above lastSeen, builds
+		// in between fell out of it since the previous poll.
+		oldest := window[len(window)-1].Number
+		if len(window) >= buildsWindowSize && oldest > lastSeen+1 {
+			older, err := client.FetchBuildsSince(jobURL, window[0].ProjectName, len(window), lastSeen)
+			if err != nil {
+				// keep the cursor so the gap is paged again on the next poll
+				log.Printf("Error paging history of %s: %v", jobPath, err)
+				cursor.LastError = err.Error()
+				next = lastSeen
+			} else {
+				log.Printf("[Poller] %s: recovered %d builds older than the builds window", jobPath, len(older))
+			}
+			fresh = append(fresh, older...)
 		}
 
-		if err := db.InsertBuild(dbModel); err != nil {
-			log.Printf("Insert failed for build #%d: %v", b.Number, err)
-			failedBuilds = append(failedBuilds, b.Number)
-			failed++
-			continue
+		for _, b := range fresh {
+			if err := db.InsertBuild(toModelBuild(b, client)); err != nil {
+				log.Printf("Insert failed for build #%d: %v", b.Number, err)
+				failedBuilds = append(failedBuilds, b.Number)
+				failed++
+				// do not advance past a build that was not stored
+				next = min(next, b.Number-1)
+				cursor.LastError = err.Error()
+				continue
+			}
+			saved++
 		}
-		saved++
+
+		cursor.LastBuildNumber = max(next, lastSeen)
+		updated = append(updated, cursor)
+	}
+
+	if err := db.SaveJobCursors(updated); err != nil {
+		return saved, failed, failedBuilds, err
 	}
 
 	return saved, failed, failedBuilds, nil
 }
 
-func extractAny(params map[string]string, keys ...string) string {
-    for _, k := range keys {
-        if v, ok := params[k]; ok && strings.TrimSpace(v) != "" {
-            return v
-        }
-    }
-    return ""
+// toModelBuild maps a Jenkins build of client's controller onto the DB model
+// using the shared extractors.
+func toModelBuild(b Build, client *JenkinsClient) *models.Build {
+	userID := extractUserID(b.Actions)
+
+	gitURL, branch, sha := extractGitInfo(b.Actions)
+	params := extractParameters(b.Actions)
+	projectPath := extractProjectPathFromURL(b.URL, client.BaseURL, b.Number)
+
+	env := client.EnvRules.Resolve(envrules.Input{ProjectPath: projectPath, JobName: b.ProjectName, Params: params})
+	igrmNo := strings.TrimSpace(params["IGRM_NO"])
+
+	return &models.Build{
+		Controller:  client.Name,
+		BuildNumber: b.Number,
+		ProjectName: b.ProjectName,
+		ProjectPath: projectPath,
+		UserID:      userID,
+		Status:      b.Result,
+		Timestamp:   time.UnixMilli(b.Timestamp),
+		DurationMS:  b.Duration,
+		JobURL:      b.URL,
+		GitRepo:     gitURL,
+		Branch:      branch,
+		CommitSHA:   sha,
+		DeployEnv:   env.DeployEnv,                 // parameter env
+		TriggerType: extractTriggerType(b.Actions), // ShortDescription - Started by user
+		Env:         env.Env,                       // canonical env from the env rules
+		IGRMNo:      igrmNo,
+		Building:            b.Building,
+		QueueID:             b.QueueID,
+		EstimatedDurationMS: b.EstimatedDuration,
+		QueueWaitMS:         extractQueueWait(b.Actions),
+		BuiltOn:             b.BuiltOn,
+		Parameters:          params,
+		Causes:              extractCauses(b.Actions),
+		Changes:             extractChanges(b),
+	}
 }
 
-// Fetches paramaterized values like ENV
-func (b *Build) GetParamString(paramNames ...string) (string, bool) {
-    // Normalize names for matching
-    want := make(map[string]struct{}, len(paramNames))
-    for _, n := range paramNames {
-        want[strings.ToLower(strings.TrimSpace(n))] = struct{}{}
-    }
-
-    for _, a := range b.Actions {
-        for _, p := range a.Parameters {
-            if _, ok := want[strings.ToLower(strings.TrimSpace(p.Name))]; !ok {
-                continue
-            }
-            switch v := p.Value.(type) {
-            case string:
-                if v != "" {
-                    return v, true
-                }
-            case fmt.Stringer:
-                s := v.String()
-                if s != "" {
-                    return s, true
-                }
-            case float64: // Jenkins numbers decode as float64 into interface{}
-                return strconv.FormatFloat(v, 'f', -1, 64), true
-            case bool:
-                if v {
-                    return "true", true
-                }
-                return "false", true
-            default:
-                // last resort: stringify JSON-ish values
-                by, err := json.Marshal(v)
-                if err == nil && len(by) > 0 {
-                    return string(by), true
-                }
-            }
-        }
-    }
-    return "", false
+// extractCauses keeps every cause of the build, not only the one used for UserID.
+func extractCauses(actions []Action) models.BuildCauses {
+	var causes models.BuildCauses
+	for _, a := range actions {
+		for _, c := range a.Causes {
+			causes = append(causes, models.BuildCause{
+				UserID:           c.UserID,
+				UserName:         c.UserName,
+				ShortDescription: c.ShortDescription,
+			})
+		}
+	}
+	return causes
 }
 
-// extractEnv derives env from project path
-func extractEnv(path string) string {
-    parts := strings.Split(path, "/")
-    if len(parts) == 0 {
-        return "UNKNOWN"
-    }
+// extractQueueWait reads the queue time recorded by the metrics plugin, if installed.
+func extractQueueWait(actions []Action) int64 {
+	for _, a := range actions {
+		if a.QueuingDurationMillis > 0 {
+			return a.QueuingDurationMillis
+		}
+	}
+	return 0
+}
 
-    switch strings.ToUpper(parts[0]) {
-    case "DEV":
-        return "DEV"
-    case "NONPROD", "NON_PROD":
-        return "NON_PROD"
-    case "PROD_AND_DR", "PROD-DR":
-        return "PROD_AND_DR"
-    }
-    return "UNKNOWN"
+// paramString renders a parameter value as Jenkins shows it.
+func paramString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64: // Jenkins numbers decode as float64 into interface{}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		// last resort: stringify JSON-ish values
+		by, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(by)
+	}
 }
 
 func extractUserID(actions []Action) string {
@@ -440,18 +458,18 @@ func extractTriggerType(actions []Action) string {
 	return "unknown"
 }
 
-func extractParameters(actions []Action) map[string]string {
-	params := make(map[string]string)
+func extractParameters(actions []Action) models.BuildParams {
+	params := make(models.BuildParams)
 	for _, action := range actions {
 		for _, p := range action.Parameters {
-			params[p.Name] = fmt.Sprintf("%v", p.Value)
+			params[p.Name] = paramString(p.Value)
 		}
 	}
 	return params
 }
 
 func PatchMissingStatuses(db *db.DB, client *JenkinsClient, patchLimit int) error {
-    builds, err := db.GetRecentBuildsMissingStatus(patchLimit)
+    builds, err := db.GetRecentBuildsMissingStatus(client.Name, patchLimit)
     if err != nil {
         return err
     }
@@ -1,13 +1,18 @@
 package jenkins
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
 	"testing"
 
+	"github.com/gauravkr19/jenkins-analytics/models"
 	"github.com/jarcoal/httpmock"
 )
 
 func TestFetchBuilds(t *testing.T) {
-	client := NewJenkinsClient("http://jenkins.local")
+	client := NewJenkinsClient("http://jenkins.local", "user", "token")
 	httpmock.ActivateNonDefault(client.Client)
 	defer httpmock.DeactivateAndReset()
 
@@ -31,7 +36,7 @@ func TestFetchBuilds(t *testing.T) {
 	}`
 
 	httpmock.RegisterResponder("GET",
-		"http://jenkins.local/api/json?tree=jobs[name,url,builds[number,result,duration,timestamp,url,builtOn]]",
+		"http://jenkins.local/api/json?tree=jobs[name,url,_class,builds["+buildTreeFields+"]]",
 		httpmock.NewStringResponder(200, mockResponse),
 	)
 
@@ -44,3 +49,226 @@ func TestFetchBuilds(t *testing.T) {
 		t.Errorf("unexpected builds: %+v", builds)
 	}
 }
+
+func TestFetchBuildsSince(t *testing.T) {
+	client := NewJenkinsClient("http://jenkins.local", "user", "token")
+	httpmock.ActivateNonDefault(client.Client)
+	defer httpmock.DeactivateAndReset()
+
+	// job with builds 250..1; each allBuilds{from,to} page is served newest first
+	httpmock.RegisterResponder("GET", "http://jenkins.local/job/app/api/json",
+		func(req *http.Request) (*http.Response, error) {
+			var from, to int
+			tree := req.URL.Query().Get("tree")
+			if _, err := fmt.Sscanf(tree[strings.LastIndex(tree, "{"):], "{%d,%d}", &from, &to); err != nil {
+				return httpmock.NewStringResponse(400, err.Error()), nil
+			}
+			var builds []string
+			for n := 250 - from; n > 250-to && n > 0; n-- {
+				builds = append(builds, fmt.Sprintf(`{"number":%d,"url":"http://jenkins.local/job/app/%d/"}`, n, n))
+			}
+			return httpmock.NewStringResponse(200, `{"allBuilds":[`+strings.Join(builds, ",")+`]}`), nil
+		})
+
+	builds, err := client.FetchBuildsSince("http://jenkins.local/job/app/", "app", 100, 20)
+	if err != nil {
+		t.Fatalf("FetchBuildsSince failed: %v", err)
+	}
+	if len(builds) != 130 || builds[0].Number != 150 || builds[len(builds)-1].Number != 21 {
+		t.Fatalf("expected builds 150..21, got %d builds", len(builds))
+	}
+	if builds[0].ProjectName != "app" {
+		t.Errorf("expected project name to be set, got %q", builds[0].ProjectName)
+	}
+
+	all, err := client.FetchBuildsSince("http://jenkins.local/job/app/", "app", 0, 0)
+	if err != nil {
+		t.Fatalf("FetchBuildsSince failed: %v", err)
+	}
+	if len(all) != 250 {
+		t.Errorf("expected the complete history of 250 builds, got %d", len(all))
+	}
+}
+
+func TestJobPathFromURL(t *testing.T) {
+	got := jobPathFromURL("http://jenkins.local/job/team/job/app/", "http://jenkins.local")
+	if got != "team/app" {
+		t.Errorf("expected team/app, got %q", got)
+	}
+}
+
+func TestExtractChanges(t *testing.T) {
+	var b Build
+	if extractChanges(b) != nil {
+		t.Fatal("expected nil changes when the response had no change sets")
+	}
+
+	body := `{
+		"changeSets": [
+			{"kind": "git", "items": [
+				{"commitId": "ABC123", "msg": "fix login\n\ndetails", "author": {"fullName": "Dev One"}, "affectedPaths": ["src/login.go"]},
+				{"commitId": "def456", "msg": "bump", "author": {"fullName": "Dev Two"}}
+			]},
+			{"kind": "git", "items": [
+				{"commitId": "abc123", "msg": "fix login", "author": {"fullName": "Dev One"}}
+			]}
+		]
+	}`
+	if err := json.Unmarshal([]byte(body), &b); err != nil {
+		t.Fatal(err)
+	}
+	changes := extractChanges(b)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 distinct commits, got %+v", changes)
+	}
+	if changes[0].CommitID != "abc123" || changes[0].Author != "Dev One" || changes[0].Summary() != "fix login" || changes[0].Paths[0] != "src/login.go" {
+		t.Errorf("unexpected first change: %+v", changes[0])
+	}
+
+	empty := Build{ChangeSet: &ChangeSet{}}
+	if changes := extractChanges(empty); changes == nil || len(changes) != 0 {
+		t.Errorf("expected an empty, non-nil list for a build without commits, got %#v", changes)
+	}
+}
+
+func TestFetchBuildChanges(t *testing.T) {
+	client := NewJenkinsClient("http://jenkins.local", "user", "token")
+	httpmock.ActivateNonDefault(client.Client)
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "http://jenkins.local/job/app/7/api/json",
+		httpmock.NewStringResponder(200, `{"changeSet": {"kind": "git", "items": []}}`))
+	httpmock.RegisterResponder("GET", "http://jenkins.local/job/app/8/api/json",
+		httpmock.NewStringResponder(404, "Not Found"))
+
+	changes, err := client.FetchBuildChanges("http://jenkins.local/job/app/7/")
+	if err != nil || changes == nil || len(changes) != 0 {
+		t.Errorf("build without commits: changes %#v, err %v", changes, err)
+	}
+
+	// a removed build must be recognised so the collector stops retrying it
+	if _, err := client.FetchBuildChanges("http://jenkins.local/job/app/8/"); !isGone(err) {
+		t.Errorf("expected a gone StatusError, got %v", err)
+	}
+}
+
+func TestParseWebhookPayload(t *testing.T) {
+	notification := `{
+		"name": "app",
+		"url": "job/team/job/app/",
+		"build": {
+			"number": 7,
+			"phase": "COMPLETED",
+			"status": "FAILURE",
+			"timestamp": 1680000000000,
+			"url": "job/team/job/app/7/",
+			"scm": {"url": "https://git.local/app.git", "branch": "origin/main", "commit": "abc123"},
+			"parameters": {"DEPLOY_ENV": "prod"}
+		}
+	}`
+	b, phase, err := ParseWebhookPayload([]byte(notification), "http://jenkins.local/")
+	if err != nil {
+		t.Fatalf("ParseWebhookPayload failed: %v", err)
+	}
+	if phase != "COMPLETED" || b.Number != 7 || b.Result != "FAILURE" || b.ProjectName != "app" {
+		t.Errorf("unexpected build %+v, phase %q", b, phase)
+	}
+	if b.URL != "http://jenkins.local/job/team/job/app/7/" {
+		t.Errorf("expected the relative url to be resolved against the controller, got %q", b.URL)
+	}
+	action := b.Actions[0]
+	if len(action.Parameters) != 1 || action.Parameters[0].Name != "DEPLOY_ENV" || action.LastRevision.SHA1 != "abc123" || action.LastRevision.Branch[0].Name != "origin/main" {
+		t.Errorf("unexpected action %+v", action)
+	}
+
+	started := strings.Replace(notification, `"COMPLETED"`, `"STARTED"`, 1)
+	if b, phase, err = ParseWebhookPayload([]byte(started), "http://jenkins.local"); err != nil || phase != "STARTED" || b.Result != "" {
+		t.Errorf("a started build must carry no result, got %q (phase %q, err %v)", b.Result, phase, err)
+	}
+
+	b, phase, err = ParseWebhookPayload([]byte(`{"number": 3, "url": "http://jenkins.local/job/app/3/", "timestamp": 1680000000000, "building": true}`), "")
+	if err != nil || phase != "STARTED" || b.Number != 3 {
+		t.Errorf("unexpected generic payload result %+v, phase %q, err %v", b, phase, err)
+	}
+
+	for _, body := range []string{
+		`not json`,
+		`{"name": "app", "build": {"phase": "STARTED"}}`,
+		`{"result": "SUCCESS"}`,
+		// the start time is never made up
+		`{"name": "app", "build": {"number": 7, "phase": "STARTED", "url": "job/app/7/"}}`,
+		`{"number": 3, "url": "http://jenkins.local/job/app/3/", "result": "SUCCESS"}`,
+	} {
+		if _, _, err := ParseWebhookPayload([]byte(body), "http://jenkins.local"); err == nil {
+			t.Errorf("expected %s to be rejected", body)
+		}
+	}
+}
+
+func TestControllerFor(t *testing.T) {
+	primary := &JenkinsClient{Name: "main", BaseURL: "http://jenkins.local/"}
+	nested := &JenkinsClient{Name: "nested", BaseURL: "http://jenkins.local/teams"}
+	clients := []*JenkinsClient{primary, nested}
+
+	cases := []struct {
+		name, url string
+		want      *JenkinsClient
+	}{
+		{"main", "", primary},
+		{"unknown", "", nil},
+		{"", "http://jenkins.local/job/app/1/", primary},
+		{"", "http://jenkins.local/teams/job/app/1/", nested},
+		{"", "http://jenkins.local.attacker.test/job/app/1/", nil},
+		{"", "http://attacker.test/job/app/1/", nil},
+	}
+	for _, tc := range cases {
+		if got := ControllerFor(clients, tc.name, tc.url); got != tc.want {
+			t.Errorf("ControllerFor(%q, %q) = %v, want %v", tc.name, tc.url, got, tc.want)
+		}
+	}
+
+	// a single controller is not a catch-all for foreign hosts
+	if got := ControllerFor([]*JenkinsClient{primary}, "", "http://attacker.test/job/app/1/"); got != nil {
+		t.Errorf("expected no controller for a foreign url, got %s", got.Name)
+	}
+	if primary.Owns("http://jenkins.local") || !primary.Owns("http://jenkins.local/job/app/") {
+		t.Error("Owns must only accept urls below the base url")
+	}
+}
+
+func TestFetchAndStoreBuildsCursor(t *testing.T) {
+	client := NewJenkinsClient("http://jenkins.local", "user", "token")
+	httpmock.ActivateNonDefault(client.Client)
+	defer httpmock.DeactivateAndReset()
+
+	var builds []string
+	for n := 6; n >= 3; n-- {
+		builds = append(builds, fmt.Sprintf(`{"number":%d,"timestamp":1680000000000,"url":"http://jenkins.local/job/app/%d/"}`, n, n))
+	}
+	httpmock.RegisterResponder("GET",
+		"http://jenkins.local/api/json?tree=jobs[name,url,_class,builds["+buildTreeFields+"]]",
+		httpmock.NewStringResponder(200, `{"jobs":[{"name":"app","url":"http://jenkins.local/job/app/","builds":[`+strings.Join(builds, ",")+`]}]}`))
+
+	store := newFakeBuildStore()
+	store.cursors["app"] = models.JobCursor{Controller: client.Name, ProjectPath: "app", LastBuildNumber: 3}
+	store.failOn[5] = true
+
+	saved, failed, _, err := FetchAndStoreBuilds(store, client, true)
+	if err != nil {
+		t.Fatalf("FetchAndStoreBuilds failed: %v", err)
+	}
+	if saved != 2 || failed != 1 {
+		t.Errorf("expected 2 saved and 1 failed, got %d and %d", saved, failed)
+	}
+	if c := store.cursors["app"]; c.LastBuildNumber != 4 || c.LastError == "" {
+		t.Errorf("the cursor must stop before the build that was not stored, got %+v", c)
+	}
+
+	delete(store.failOn, 5)
+	if saved, _, _, err := FetchAndStoreBuilds(store, client, true); err != nil || saved != 2 {
+		t.Fatalf("expected builds 5 and 6 to be stored again, got %d (%v)", saved, err)
+	}
+	if c := store.cursors["app"]; c.LastBuildNumber != 6 || c.LastError != "" {
+		t.Errorf("expected the cursor to advance to 6 and clear the error, got %+v", c)
+	}
+}
@@ -0,0 +1,107 @@
+package jenkins
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// queueItem is an entry of /queue/api/json.
+type queueItem struct {
+	ID           int64  `json:"id"`
+	InQueueSince int64  `json:"inQueueSince"`
+	Why          string `json:"why"`
+	Blocked      bool   `json:"blocked"`
+	Stuck        bool   `json:"stuck"`
+	Task         struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"task"`
+}
+
+// FetchQueue returns the items currently waiting in the controller's build queue.
+func (jc *JenkinsClient) FetchQueue() ([]models.QueueItem, error) {
+	apiURL := fmt.Sprintf("%s/queue/api/json?tree=items[id,inQueueSince,why,blocked,stuck,task[name,url]]", strings.TrimSuffix(jc.BaseURL, "/"))
+
+	var data struct {
+		Items []queueItem `json:"items"`
+	}
+	if err := jc.getJSON(apiURL, &data); err != nil {
+		return nil, err
+	}
+
+	items := make([]models.QueueItem, 0, len(data.Items))
+	for _, it := range data.Items {
+		items = append(items, models.QueueItem{
+			Controller:   jc.Name,
+			QueueID:      it.ID,
+			TaskName:     it.Task.Name,
+			TaskURL:      it.Task.URL,
+			Why:          it.Why,
+			Blocked:      it.Blocked,
+			Stuck:        it.Stuck,
+			InQueueSince: time.UnixMilli(it.InQueueSince),
+		})
+	}
+	return items, nil
+}
+
+// runningStore is the part of *db.DB TrackRunningBuilds reads and writes.
+type runningStore interface {
+	SaveQueueItems(controller string, items []models.QueueItem) error
+	GetRunningBuilds(controller string) ([]models.Build, error)
+	FinishBuild(id int, status string, durationMS, queueWaitMS int64) error
+	ApplyQueueWaits(controller string) (int64, error)
+}
+
+var _ runningStore = (*db.DB)(nil)
+
+// TrackRunningBuilds snapshots the queue, moves builds that finished since the
+// last run to their final state and derives queue waits from the snapshots.
+// It returns the number of builds finished.
+func TrackRunningBuilds(database runningStore, client *JenkinsClient) (int, error) {
+	items, err := client.FetchQueue()
+	if err != nil {
+		return 0, fmt.Errorf("fetch queue failed: %w", err)
+	}
+	if err := database.SaveQueueItems(client.Name, items); err != nil {
+		return 0, err
+	}
+
+	running, err := database.GetRunningBuilds(client.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	finished := 0
+	for _, b := range running {
+		live, err := client.FetchBuildByURL(strings.TrimSuffix(b.JobURL, "/") + "/api/json")
+		if err != nil {
+			// deleted builds would otherwise stay "running" forever
+			if time.Since(b.Timestamp) > 24*time.Hour {
+				log.Printf("[Running] %s #%d no longer readable, clearing running state: %v", b.ProjectPath, b.BuildNumber, err)
+				if err := database.FinishBuild(b.ID, "", 0, 0); err != nil {
+					log.Printf("[Running] %v", err)
+				}
+			}
+			continue
+		}
+		if live.Building {
+			continue
+		}
+		if err := database.FinishBuild(b.ID, live.Result, live.Duration, extractQueueWait(live.Actions)); err != nil {
+			log.Printf("[Running] %v", err)
+			continue
+		}
+		finished++
+	}
+
+	if _, err := database.ApplyQueueWaits(client.Name); err != nil {
+		return finished, err
+	}
+	return finished, nil
+}
@@ -0,0 +1,131 @@
+package jenkins
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/jarcoal/httpmock"
+)
+
+func TestTrackRunningBuildsJenkinsCalls(t *testing.T) {
+	client := NewJenkinsClient("http://jenkins.local/", "user", "token")
+	client.Name = "ci"
+	httpmock.ActivateNonDefault(client.Client)
+	defer httpmock.DeactivateAndReset()
+
+	registerRunningJenkins()
+
+	items, err := client.FetchQueue()
+	if err != nil {
+		t.Fatalf("FetchQueue failed: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 queue item, got %d", len(items))
+	}
+	it := items[0]
+	if it.Controller != "ci" || it.QueueID != 7 || it.TaskName != "app" || !it.Stuck || it.InQueueSince.UnixMilli() != 1680000000000 {
+		t.Errorf("unexpected queue item %+v", it)
+	}
+
+	running, err := client.FetchBuildByURL("http://jenkins.local/job/app/41/api/json")
+	if err != nil || !running.Building {
+		t.Errorf("expected build 41 to still be building, got %+v (%v)", running, err)
+	}
+	done, err := client.FetchBuildByURL("http://jenkins.local/job/app/40/api/json")
+	if err != nil || done.Building || done.Result != "FAILURE" {
+		t.Fatalf("expected build 40 to be finished, got %+v (%v)", done, err)
+	}
+	if wait := extractQueueWait(done.Actions); wait != 2500 {
+		t.Errorf("expected a queue wait of 2500ms, got %d", wait)
+	}
+	if _, err := client.FetchBuildByURL("http://jenkins.local/job/app/39/api/json"); err == nil {
+		t.Error("expected an error for a deleted build")
+	}
+}
+
+// registerRunningJenkins serves a queue with one stuck item, app #41 still
+// building and app #40 finished after a 2.5s queue wait; other builds are gone.
+func registerRunningJenkins() {
+	httpmock.RegisterResponder("GET",
+		"http://jenkins.local/queue/api/json?tree=items[id,inQueueSince,why,blocked,stuck,task[name,url]]",
+		httpmock.NewStringResponder(200, `{"items": [
+			{"id": 7, "inQueueSince": 1680000000000, "why": "Waiting for next available executor", "stuck": true,
+			 "task": {"name": "app", "url": "http://jenkins.local/job/app/"}}
+		]}`))
+	httpmock.RegisterResponder("GET", "http://jenkins.local/job/app/41/api/json",
+		httpmock.NewStringResponder(200, `{"number": 41, "building": true, "result": null}`))
+	httpmock.RegisterResponder("GET", "http://jenkins.local/job/app/40/api/json",
+		httpmock.NewStringResponder(200, `{"number": 40, "building": false, "result": "FAILURE", "duration": 9000,
+			"actions": [{}, {"_class": "jenkins.metrics.impl.TimeInQueueAction", "queuingDurationMillis": 2500}]}`))
+}
+
+// fakeRunningStore records what TrackRunningBuilds writes.
+type fakeRunningStore struct {
+	running  []models.Build
+	queued   []models.QueueItem
+	finished map[int]models.Build // id -> status, duration and queue wait written
+	applied  bool
+}
+
+func (s *fakeRunningStore) SaveQueueItems(controller string, items []models.QueueItem) error {
+	s.queued = items
+	return nil
+}
+
+func (s *fakeRunningStore) GetRunningBuilds(controller string) ([]models.Build, error) {
+	return s.running, nil
+}
+
+func (s *fakeRunningStore) FinishBuild(id int, status string, durationMS, queueWaitMS int64) error {
+	s.finished[id] = models.Build{Status: status, DurationMS: durationMS, QueueWaitMS: queueWaitMS}
+	return nil
+}
+
+func (s *fakeRunningStore) ApplyQueueWaits(controller string) (int64, error) {
+	s.applied = true
+	return 0, nil
+}
+
+func TestTrackRunningBuilds(t *testing.T) {
+	client := NewJenkinsClient("http://jenkins.local/", "user", "token")
+	client.Name = "ci"
+	httpmock.ActivateNonDefault(client.Client)
+	defer httpmock.DeactivateAndReset()
+	registerRunningJenkins()
+
+	job := func(id, number int, age time.Duration) models.Build {
+		return models.Build{ID: id, BuildNumber: number, ProjectPath: "app", Building: true,
+			JobURL: fmt.Sprintf("http://jenkins.local/job/app/%d/", number), Timestamp: time.Now().Add(-age)}
+	}
+	store := &fakeRunningStore{finished: make(map[int]models.Build)}
+	store.running = []models.Build{
+		job(1, 41, time.Hour),    // still building
+		job(2, 40, time.Hour),    // finished
+		job(3, 39, 48*time.Hour), // deleted a day after it started
+		job(4, 38, 2*time.Hour),  // unreadable, but maybe only for now
+	}
+
+	finished, err := TrackRunningBuilds(store, client)
+	if err != nil {
+		t.Fatalf("TrackRunningBuilds failed: %v", err)
+	}
+	if finished != 1 {
+		t.Errorf("expected 1 finished build, got %d", finished)
+	}
+	if len(store.queued) != 1 || !store.applied {
+		t.Errorf("expected the queue to be saved and waits applied, got %d items, applied %v", len(store.queued), store.applied)
+	}
+	if b, ok := store.finished[2]; !ok || b.Status != "FAILURE" || b.DurationMS != 9000 || b.QueueWaitMS != 2500 {
+		t.Errorf("expected #40 to finish as FAILURE after 9000ms with a 2500ms wait, got %+v (%v)", b, ok)
+	}
+	if b, ok := store.finished[3]; !ok || b.Status != "" {
+		t.Errorf("expected the stale #39 to be cleared keeping its status, got %+v (%v)", b, ok)
+	}
+	for _, id := range []int{1, 4} {
+		if b, ok := store.finished[id]; ok {
+			t.Errorf("build id %d must stay running, got %+v", id, b)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package jenkins
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// WorkflowRun is the subset of <build>/wfapi/describe that is stored.
+type WorkflowRun struct {
+	ID     string          `json:"id"`
+	Name   string          `json:"name"`
+	Status string          `json:"status"`
+	Stages []WorkflowStage `json:"stages"`
+}
+
+type WorkflowStage struct {
+	ID                  string `json:"id"`
+	Name                string `json:"name"`
+	Status              string `json:"status"`
+	StartTimeMillis     int64  `json:"startTimeMillis"`
+	DurationMillis      int64  `json:"durationMillis"`
+	PauseDurationMillis int64  `json:"pauseDurationMillis"`
+}
+
+// FetchWorkflowRun calls <build>/wfapi/describe. A nil run with no error means
+// Jenkins has no stage data for the build (freestyle job, or the build is gone).
+func (jc *JenkinsClient) FetchWorkflowRun(buildURL string) (*WorkflowRun, error) {
+	apiURL := strings.TrimSuffix(buildURL, "/") + "/wfapi/describe"
+
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", apiURL, err)
+	}
+	if jc.Username != "" && jc.APIToken != "" {
+		req.SetBasicAuth(jc.Username, jc.APIToken)
+	}
+
+	resp, err := jc.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", apiURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: apiURL, Code: resp.StatusCode}
+	}
+
+	var run WorkflowRun
+	if err := json.NewDecoder(resp.Body).Decode(&run); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", apiURL, err)
+	}
+	return &run, nil
+}
+
+// toModelStages maps wfapi stages onto build_stages rows, keeping run order.
+func (run *WorkflowRun) toModelStages() []models.BuildStage {
+	stages := make([]models.BuildStage, 0, len(run.Stages))
+	for i, s := range run.Stages {
+		stages = append(stages, models.BuildStage{
+			StageID:         s.ID,
+			Name:            s.Name,
+			Status:          s.Status,
+			StartTime:       time.UnixMilli(s.StartTimeMillis),
+			DurationMS:      s.DurationMillis,
+			PauseDurationMS: s.PauseDurationMillis,
+			Position:        i,
+		})
+	}
+	return stages
+}
+
+// CollectStages stores pipeline stages for finished builds not visited yet.
+// Builds whose request fails are retried with backoff and recorded without
+// stages after MaxCollectAttempts, so they do not block the batch.
+func CollectStages(database *db.DB, client *JenkinsClient, batch int) (int, error) {
+	builds, err := database.GetBuildsMissingStages(client.Name, batch)
+	if err != nil {
+		return 0, err
+	}
+
+	stored := 0
+	for _, b := range builds {
+		var run *WorkflowRun
+		if b.JobURL != "" {
+			run, err = client.FetchWorkflowRun(b.JobURL)
+			if err != nil && retryLater(database, db.CollectStages, b.ID, err) {
+				continue
+			}
+		}
+
+		var stages []models.BuildStage
+		if run != nil {
+			stages = run.toModelStages()
+		}
+		if err := database.SaveBuildStages(b.ID, stages); err != nil {
+			log.Printf("[Stages] store failed for build ID %d: %v", b.ID, err)
+			continue
+		}
+		if err := database.ClearCollectAttempts(db.CollectStages, b.ID); err != nil {
+			log.Printf("[Stages] %v", err)
+		}
+		if len(stages) > 0 {
+			stored++
+		}
+	}
+
+	return stored, nil
+}
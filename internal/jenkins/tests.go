@@ -0,0 +1,166 @@
+package jenkins
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+const testReportTree = "duration,passCount,failCount,skipCount,totalCount," +
+	"suites[name,cases[className,name,status,duration]]," +
+	"childReports[result[duration,passCount,failCount,skipCount,suites[name,cases[className,name,status,duration]]]]"
+
+// TestResult is the JUnit plugin's testReport document. Matrix and aggregated
+// reports nest the per-job results under childReports.
+type TestResult struct {
+	Duration     float64     `json:"duration"` // seconds
+	PassCount    int         `json:"passCount"`
+	FailCount    int         `json:"failCount"`
+	SkipCount    int         `json:"skipCount"`
+	Suites       []TestSuite `json:"suites"`
+	ChildReports []struct {
+		Result TestResult `json:"result"`
+	} `json:"childReports"`
+}
+
+type TestSuite struct {
+	Name  string         `json:"name"`
+	Cases []TestCaseJSON `json:"cases"`
+}
+
+type TestCaseJSON struct {
+	ClassName string  `json:"className"`
+	Name      string  `json:"name"`
+	Status    string  `json:"status"` // PASSED, FIXED, FAILED, REGRESSION, SKIPPED
+	Duration  float64 `json:"duration"`
+}
+
+// FetchTestReport calls <build>/testReport/api/json. A nil result with no error
+// means the build published no test results.
+func (jc *JenkinsClient) FetchTestReport(buildURL string) (*TestResult, error) {
+	apiURL := strings.TrimSuffix(buildURL, "/") + "/testReport/api/json?tree=" + testReportTree
+
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", apiURL, err)
+	}
+	if jc.Username != "" && jc.APIToken != "" {
+		req.SetBasicAuth(jc.Username, jc.APIToken)
+	}
+
+	resp, err := jc.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", apiURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: apiURL, Code: resp.StatusCode}
+	}
+
+	var tr TestResult
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", apiURL, err)
+	}
+	return &tr, nil
+}
+
+// toModel flattens the report (including child reports) into totals and cases.
+func (tr *TestResult) toModel() (*models.TestReport, []models.TestCase) {
+	report := &models.TestReport{DurationMS: int64(tr.Duration * 1000)}
+	var cases []models.TestCase
+
+	var walk func(r *TestResult)
+	walk = func(r *TestResult) {
+		for _, s := range r.Suites {
+			for _, c := range s.Cases {
+				cases = append(cases, models.TestCase{
+					Suite:      s.Name,
+					ClassName:  c.ClassName,
+					Name:       c.Name,
+					Status:     normalizeTestStatus(c.Status),
+					DurationMS: int64(c.Duration * 1000),
+				})
+			}
+		}
+		for i := range r.ChildReports {
+			walk(&r.ChildReports[i].Result)
+		}
+	}
+	walk(tr)
+
+	for _, c := range cases {
+		switch c.Status {
+		case "PASSED":
+			report.Passed++
+		case "FAILED":
+			report.Failed++
+		default:
+			report.Skipped++
+		}
+	}
+	if len(cases) == 0 {
+		// tree may have been cut by Jenkins; fall back to the reported counts
+		report.Passed, report.Failed, report.Skipped = tr.PassCount, tr.FailCount, tr.SkipCount
+	}
+	report.Total = report.Passed + report.Failed + report.Skipped
+	return report, cases
+}
+
+func normalizeTestStatus(s string) string {
+	switch strings.ToUpper(s) {
+	case "PASSED", "FIXED":
+		return "PASSED"
+	case "FAILED", "REGRESSION":
+		return "FAILED"
+	default:
+		return "SKIPPED"
+	}
+}
+
+// CollectTestResults stores JUnit results for finished builds not visited yet.
+// Builds whose request fails are retried with backoff and recorded without a
+// report after MaxCollectAttempts, so they do not block the batch.
+func CollectTestResults(database *db.DB, client *JenkinsClient, batch int) (int, error) {
+	builds, err := database.GetBuildsMissingTests(client.Name, batch)
+	if err != nil {
+		return 0, err
+	}
+
+	stored := 0
+	for _, b := range builds {
+		var tr *TestResult
+		if b.JobURL != "" {
+			tr, err = client.FetchTestReport(b.JobURL)
+			if err != nil && retryLater(database, db.CollectTests, b.ID, err) {
+				continue
+			}
+		}
+
+		var report *models.TestReport
+		var cases []models.TestCase
+		if tr != nil {
+			report, cases = tr.toModel()
+		}
+		if err := database.SaveTestResults(b.ID, report, cases); err != nil {
+			log.Printf("[Tests] store failed for build ID %d: %v", b.ID, err)
+			continue
+		}
+		if err := database.ClearCollectAttempts(db.CollectTests, b.ID); err != nil {
+			log.Printf("[Tests] %v", err)
+		}
+		if report != nil {
+			stored++
+		}
+	}
+
+	return stored, nil
+}
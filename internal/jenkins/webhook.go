@@ -0,0 +1,146 @@
+package jenkins
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// Notification is the payload posted by the Jenkins Notification plugin.
+type Notification struct {
+	Name        string            `json:"name"`
+	DisplayName string            `json:"display_name"`
+	URL         string            `json:"url"`
+	Build       NotificationBuild `json:"build"`
+}
+
+type NotificationBuild struct {
+	FullURL    string                 `json:"full_url"`
+	Number     int                    `json:"number"`
+	QueueID    int64                  `json:"queue_id"`
+	Timestamp  int64                  `json:"timestamp"`
+	Duration   int64                  `json:"duration"`
+	Phase      string                 `json:"phase"` // QUEUED, STARTED, COMPLETED, FINALIZED
+	Status     string                 `json:"status"`
+	URL        string                 `json:"url"`
+	SCM        NotificationSCM        `json:"scm"`
+	Parameters map[string]interface{} `json:"parameters"`
+}
+
+type NotificationSCM struct {
+	URL    string `json:"url"`
+	Branch string `json:"branch"`
+	Commit string `json:"commit"`
+}
+
+// ParseWebhookPayload accepts either a Notification plugin payload or a
+// generic webhook that posts the build's own /api/json document, and returns
+// the equivalent Build plus the reported phase.
+func ParseWebhookPayload(body []byte, baseURL string) (Build, string, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return Build{}, "", fmt.Errorf("invalid JSON payload: %w", err)
+	}
+
+	if _, ok := probe["build"]; ok {
+		var n Notification
+		if err := json.Unmarshal(body, &n); err != nil {
+			return Build{}, "", fmt.Errorf("invalid notification payload: %w", err)
+		}
+		b := n.toBuild(baseURL)
+		phase := strings.ToUpper(n.Build.Phase)
+		if b.Number == 0 || b.URL == "" {
+			return Build{}, "", fmt.Errorf("notification payload is missing build number or url")
+		}
+		// the start time is part of a build's identity, it is never guessed
+		if b.Timestamp == 0 && phase != "QUEUED" {
+			return Build{}, "", fmt.Errorf("notification payload is missing the build timestamp")
+		}
+		return b, phase, nil
+	}
+
+	var b Build
+	if err := json.Unmarshal(body, &b); err != nil {
+		return Build{}, "", fmt.Errorf("invalid build payload: %w", err)
+	}
+	if b.Number == 0 || b.URL == "" || b.Timestamp == 0 {
+		return Build{}, "", fmt.Errorf("build payload is missing number, url or timestamp")
+	}
+	phase := "COMPLETED"
+	if b.Result == "" {
+		phase = "STARTED"
+	}
+	return b, phase, nil
+}
+
+// toBuild maps the notification onto the same Build shape the tree API returns,
+// so that the regular extractors can be reused.
+func (n Notification) toBuild(baseURL string) Build {
+	nb := n.Build
+
+	fullURL := nb.FullURL
+	if fullURL == "" && nb.URL != "" {
+		fullURL = strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(nb.URL, "/")
+	}
+
+	b := Build{
+		Number:      nb.Number,
+		Duration:    nb.Duration,
+		Timestamp:   nb.Timestamp,
+		URL:         fullURL,
+		ProjectName: n.Name,
+	}
+	if phase := strings.ToUpper(nb.Phase); phase == "COMPLETED" || phase == "FINALIZED" {
+		b.Result = nb.Status
+	}
+
+	var action Action
+	for name, value := range nb.Parameters {
+		action.Parameters = append(action.Parameters, Param{Name: name, Value: value})
+	}
+	if nb.SCM.URL != "" {
+		action.RemoteURLs = []string{nb.SCM.URL}
+	}
+	if nb.SCM.Commit != "" || nb.SCM.Branch != "" {
+		action.LastRevision = &GitRevision{SHA1: nb.SCM.Commit}
+		if nb.SCM.Branch != "" {
+			action.LastRevision.Branch = []GitBranch{{Name: nb.SCM.Branch}}
+		}
+	}
+	b.Actions = []Action{action}
+	return b
+}
+
+// StoreWebhookBuild upserts a build reported by webhook for client's controller.
+// The build's /api/json is fetched so causes and SCM details match what the
+// poller would have stored; the payload is used as a fallback. Builds whose URL
+// is not on the controller are refused, so its credentials never leave it.
+func StoreWebhookBuild(database *db.DB, client *JenkinsClient, b Build) (*models.Build, error) {
+	if !client.Owns(b.URL) {
+		return nil, fmt.Errorf("build url %s is not on controller %s", b.URL, client.Name)
+	}
+
+	full, err := client.FetchBuildByURL(strings.TrimSuffix(b.URL, "/") + "/api/json")
+	if err != nil {
+		log.Printf("[Webhook] could not enrich %s from Jenkins, using payload: %v", b.URL, err)
+	} else {
+		full.ProjectName = b.ProjectName
+		if full.URL == "" {
+			full.URL = b.URL
+		}
+		b = *full
+	}
+
+	dbModel := toModelBuild(b, client)
+	if dbModel.ProjectName == "" {
+		dbModel.ProjectName = dbModel.ProjectPath[strings.LastIndex(dbModel.ProjectPath, "/")+1:]
+	}
+	if err := database.UpsertBuild(dbModel); err != nil {
+		return nil, err
+	}
+	return dbModel, nil
+}
@@ -0,0 +1,61 @@
+// Package mailer sends the mails of alert channels and report schedules
+// through an SMTP server.
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the SMTP server mails are sent through.
+type Config struct {
+	Host        string `yaml:"host"`
+	Port        int    `yaml:"port"`
+	User        string `yaml:"user"`
+	Password    string `yaml:"password"`
+	PasswordEnv string `yaml:"password_env"`
+	From        string `yaml:"from"`
+}
+
+// Resolve reads the password from PasswordEnv when set and defaults the port
+// to 25.
+func (c *Config) Resolve() {
+	if c.PasswordEnv != "" {
+		c.Password = os.Getenv(c.PasswordEnv)
+	}
+	if c.Port == 0 {
+		c.Port = 25
+	}
+}
+
+// WriteHeader writes the From, To, Subject, Date and MIME-Version headers of a
+// mail to to. The subject is Q-encoded, as it carries job names that need not
+// be ASCII; the caller adds Content-Type and the body.
+func (c Config) WriteHeader(w io.Writer, to []string, subject string) {
+	fmt.Fprintf(w, "From: %s\r\n", c.From)
+	fmt.Fprintf(w, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(w, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(w, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(w, "MIME-Version: 1.0\r\n")
+}
+
+// Send delivers a complete message to the recipients, authenticating when a
+// user is configured.
+func (c Config) Send(to []string, msg []byte) error {
+	var auth smtp.Auth
+	if c.User != "" {
+		auth = smtp.PlainAuth("", c.User, c.Password, c.Host)
+	}
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	if err := smtp.SendMail(addr, auth, c.From, to, msg); err != nil {
+		return fmt.Errorf("send mail via %s: %w", addr, err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"bytes"
+	"mime"
+	"net/mail"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	t.Setenv("TEST_SMTP_PASSWORD", "secret")
+	c := Config{Host: "smtp.local", Password: "ignored", PasswordEnv: "TEST_SMTP_PASSWORD"}
+	c.Resolve()
+	if c.Password != "secret" || c.Port != 25 {
+		t.Errorf("resolved config = %+v", c)
+	}
+}
+
+func TestWriteHeader(t *testing.T) {
+	var buf bytes.Buffer
+	Config{From: "ci@example.com"}.WriteHeader(&buf, []string{"a@example.com", "b@example.com"}, "Rapport hebdomadaire – été")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\nbody\r\n")
+
+	m, err := mail.ReadMessage(&buf)
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	if got := m.Header.Get("To"); got != "a@example.com, b@example.com" {
+		t.Errorf("To = %q", got)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil || subject != "Rapport hebdomadaire – été" {
+		t.Errorf("subject = %q, %v", subject, err)
+	}
+	if _, err := m.Header.Date(); err != nil {
+		t.Errorf("Date header: %v", err)
+	}
+}
@@ -4,15 +4,21 @@ import (
 	"log"
 	"time"
 
+	"github.com/gauravkr19/jenkins-analytics/internal/alerts"
+	"github.com/gauravkr19/jenkins-analytics/internal/archive"
+	"github.com/gauravkr19/jenkins-analytics/internal/classify"
 	"github.com/gauravkr19/jenkins-analytics/internal/config"
 	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/internal/igrm"
 	"github.com/gauravkr19/jenkins-analytics/internal/jenkins"
+	"github.com/gauravkr19/jenkins-analytics/internal/reports"
+	"github.com/gauravkr19/jenkins-analytics/internal/retention"
 )
 
 func StartIncrementalPoller(database *db.DB, client *jenkins.JenkinsClient, interval time.Duration) {
     go func() {
         for {
-            log.Println("[Poller] Checking for new Jenkins builds...")
+            log.Printf("[Poller] Checking %s for new Jenkins builds...", client.Name)
             saved, failed, failedIDs, err := jenkins.FetchAndStoreBuilds(database, client, true)
             if err != nil {
                 log.Printf("[Poller] Error fetching builds: %v", err)
@@ -39,30 +45,210 @@ func StartStatusPatcher(database *db.DB, client *jenkins.JenkinsClient, patchInt
     }()
 }
 
-func DeletionRoutine(database *db.DB, client *jenkins.JenkinsClient, interval time.Duration, cfg config.RetentionConfig) {
+func StartRunningTracker(database *db.DB, client *jenkins.JenkinsClient, interval time.Duration) {
     go func() {
         for {
-            if !cfg.CleanupEnabled {
-                time.Sleep(interval)
-                continue
+            n, err := jenkins.TrackRunningBuilds(database, client)
+            if err != nil {
+                log.Printf("[Running] Error tracking running builds: %v", err)
+            } else if n > 0 {
+                log.Printf("[Running] %d builds finished", n)
+            }
+
+            time.Sleep(interval)
+        }
+    }()
+}
+
+func StartAgentCollector(database *db.DB, client *jenkins.JenkinsClient, interval, retention time.Duration) {
+    go func() {
+        for {
+            if _, err := jenkins.SnapshotAgents(database, client, retention); err != nil {
+                log.Printf("[Agents] Error snapshotting nodes: %v", err)
+            }
+
+            time.Sleep(interval)
+        }
+    }()
+}
+
+func StartLogCollector(database *db.DB, client *jenkins.JenkinsClient, interval time.Duration, cfg config.LogConfig) {
+    if !cfg.Enabled {
+        log.Println("[Logs] Console log collection disabled")
+        return
+    }
+    go func() {
+        for {
+            stored, err := jenkins.CollectConsoleLogs(database, client, cfg)
+            if err != nil {
+                log.Printf("[Logs] Error collecting console logs: %v", err)
+            } else if stored > 0 {
+                log.Printf("[Logs] Stored %d console logs", stored)
             }
 
-            // 1) Check current record count to determine cleanup
-            var total int
-            total, err := database.CountBuilds()
+            time.Sleep(interval)
+        }
+    }()
+}
+
+func StartStageCollector(database *db.DB, client *jenkins.JenkinsClient, interval time.Duration, batch int) {
+    go func() {
+        for {
+            n, err := jenkins.CollectStages(database, client, batch)
             if err != nil {
-                log.Printf("[Retention] count check error: %v", err)
-                time.Sleep(interval)
-                continue
+                log.Printf("[Stages] Error collecting pipeline stages: %v", err)
+            } else if n > 0 {
+                log.Printf("[Stages] Stored stages for %d builds", n)
             }
 
-            if total > cfg.MaxRecords {
-                log.Printf("[Retention] total records=%d, exceeding limit=%d. Performing cleanup.", total, cfg.MaxRecords)
-                if err := database.CleanupToMax(cfg.MaxRecords, cfg.DeleteMultiple); err != nil {
-                    log.Printf("[Retention] cleanup error: %v", err)
+            time.Sleep(interval)
+        }
+    }()
+}
+
+func StartTestCollector(database *db.DB, client *jenkins.JenkinsClient, interval time.Duration, batch int) {
+    go func() {
+        for {
+            n, err := jenkins.CollectTestResults(database, client, batch)
+            if err != nil {
+                log.Printf("[Tests] Error collecting test results: %v", err)
+            } else if n > 0 {
+                log.Printf("[Tests] Stored test reports for %d builds", n)
+            }
+
+            time.Sleep(interval)
+        }
+    }()
+}
+
+// StartChangeCollector stores the changeSet commits of builds synced without them.
+func StartChangeCollector(database *db.DB, client *jenkins.JenkinsClient, interval time.Duration, batch int) {
+    go func() {
+        for {
+            n, err := jenkins.CollectBuildChanges(database, client, batch)
+            if err != nil {
+                log.Printf("[Changes] Error collecting build changes: %v", err)
+            } else if n > 0 {
+                log.Printf("[Changes] Stored changes of %d builds", n)
+            }
+
+            time.Sleep(interval)
+        }
+    }()
+}
+
+// StartIGRMValidator checks the IGRM numbers of new deployments of env against
+// the change-management system.
+func StartIGRMValidator(database *db.DB, v igrm.Validator, env string, deploysOnly bool, interval time.Duration, batch int) {
+    go func() {
+        for {
+            n, err := igrm.CheckPending(database, v, env, deploysOnly, batch)
+            if err != nil {
+                log.Printf("[IGRM] Error checking change tickets: %v", err)
+            } else if n > 0 {
+                log.Printf("[IGRM] Checked change tickets of %d builds", n)
+            }
+
+            time.Sleep(interval)
+        }
+    }()
+}
+
+// StartAlertEvaluator runs the alert rules over builds as they finish.
+func StartAlertEvaluator(database *db.DB, engine *alerts.Engine, interval time.Duration, batch int) {
+    go func() {
+        for {
+            n, err := engine.EvaluatePending(database, batch)
+            if err != nil {
+                log.Printf("[Alerts] Error evaluating alert rules: %v", err)
+            } else if n > 0 {
+                log.Printf("[Alerts] Fired %d alerts", n)
+            }
+
+            time.Sleep(interval)
+        }
+    }()
+}
+
+// StartReportScheduler emails the scheduled digests when their cron schedule fires.
+func StartReportScheduler(scheduler *reports.Scheduler, interval time.Duration) {
+    go func() {
+        for {
+            n, err := scheduler.RunDue(time.Now())
+            if err != nil {
+                log.Printf("[Reports] Error sending scheduled reports: %v", err)
+            }
+            if n > 0 {
+                log.Printf("[Reports] Sent %d scheduled reports", n)
+            }
+
+            time.Sleep(interval)
+        }
+    }()
+}
+
+func StartFailureClassifier(database *db.DB, classifier *classify.Classifier, interval time.Duration, batch int) {
+    go func() {
+        for {
+            n, err := classify.ClassifyPending(database, classifier, batch)
+            if err != nil {
+                log.Printf("[Classifier] Error classifying failures: %v", err)
+            } else if n > 0 {
+                log.Printf("[Classifier] Classified %d failed builds", n)
+            }
+
+            time.Sleep(interval)
+        }
+    }()
+}
+
+// StartRetention keeps monthly partitions ready and removes builds past their
+// retention policy. The caller decides whether retention is enabled.
+func StartRetention(database *db.DB, policies *retention.Policies, store archive.Store, interval time.Duration, cfg config.RetentionConfig) {
+    opts := retention.Options{
+        PartitionAction: cfg.PartitionAction,
+        DeleteBatch:     cfg.DeleteBatch,
+        MonthsAhead:     cfg.MonthsAhead,
+        Archive:         store,
+    }
+    go func() {
+        for {
+            report, err := retention.Run(database, policies, opts, time.Now())
+            if err != nil {
+                log.Printf("[Retention] Error applying retention: %v", err)
+            } else if len(report.Expired) > 0 {
+                log.Printf("[Retention] %s %d partitions holding %d builds: %v", cfg.PartitionAction, len(report.Expired), report.Affected, report.Expired)
+            }
+
+            time.Sleep(interval)
+        }
+    }()
+}
+
+// StartRollupRefresher recomputes the daily rollups of days and jobs whose
+// builds changed, draining the queue before sleeping. With retention policies,
+// days they may already have trimmed keep their rollups.
+func StartRollupRefresher(database *db.DB, policies *retention.Policies, interval time.Duration, batch int) {
+    go func() {
+        for {
+            var retainedFrom time.Time
+            if policies != nil {
+                retainedFrom = policies.DeleteCutoff(time.Now())
+            }
+            total := 0
+            for {
+                n, err := database.RefreshDirtyRollups(batch, retainedFrom)
+                if err != nil {
+                    log.Printf("[Rollups] Error refreshing rollups: %v", err)
+                    break
                 }
-            } else {
-                log.Printf("[Retention] total records=%d, under limit=%d. Skipping cleanup.", total, cfg.MaxRecords)
+                total += n
+                if n < batch {
+                    break
+                }
+            }
+            if total > 0 {
+                log.Printf("[Rollups] Refreshed %d day/job rollups", total)
             }
 
             time.Sleep(interval)
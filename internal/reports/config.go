@@ -0,0 +1,108 @@
+// Package reports builds the scheduled build digests and emails them as HTML
+// with an Excel attachment.
+package reports
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/mailer"
+	"gopkg.in/yaml.v3"
+)
+
+// Report is one scheduled digest.
+type Report struct {
+	Name       string   `yaml:"name"`
+	Subject    string   `yaml:"subject"`  // default "<name>: <from> – <to>"
+	Schedule   string   `yaml:"schedule"` // cron expression in the config's timezone
+	Range      string   `yaml:"range"`    // named range, e.g. previous_week
+	Env        string   `yaml:"env"`      // only builds of this canonical env
+	Folder     string   `yaml:"folder"`   // only builds under this project path
+	ProdEnv    string   `yaml:"prod_env"` // env of the deployments section, default PROD_AND_DR
+	Top        int      `yaml:"top"`      // rows of the top failing and slowest lists, default 10
+	Recipients []string `yaml:"recipients"`
+
+	Cron *Schedule `yaml:"-"`
+}
+
+// Config is a parsed report schedules document.
+type Config struct {
+	Timezone string        `yaml:"timezone"` // zone of the cron schedules
+	SMTP     mailer.Config `yaml:"smtp"`
+	Reports  []Report      `yaml:"reports"`
+
+	Location *time.Location `yaml:"-"`
+}
+
+// RangeFunc resolves a named date range such as previous_week.
+type RangeFunc func(key string) (from, to time.Time, err error)
+
+// ParseConfig decodes and validates a report schedules document. Range names
+// are checked with ranges.
+func ParseConfig(data []byte, ranges RangeFunc) (*Config, error) {
+	cfg := &Config{Timezone: "UTC"}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid report schedules file: %w", err)
+	}
+	var err error
+	if cfg.Location, err = time.LoadLocation(cfg.Timezone); err != nil {
+		return nil, fmt.Errorf("report schedules timezone: %w", err)
+	}
+	cfg.SMTP.Resolve()
+	if cfg.SMTP.Host == "" || cfg.SMTP.From == "" {
+		return nil, fmt.Errorf("report schedules: smtp host and from are required")
+	}
+
+	seen := make(map[string]bool)
+	for i := range cfg.Reports {
+		r := &cfg.Reports[i]
+		if r.Name == "" {
+			return nil, fmt.Errorf("report %d: name is required", i+1)
+		}
+		if seen[r.Name] {
+			return nil, fmt.Errorf("report %q is listed twice", r.Name)
+		}
+		seen[r.Name] = true
+		if r.Cron, err = ParseSchedule(r.Schedule); err != nil {
+			return nil, fmt.Errorf("report %q: %w", r.Name, err)
+		}
+		if r.Range == "" {
+			r.Range = "previous_week"
+		}
+		if _, _, err := ranges(r.Range); err != nil {
+			return nil, fmt.Errorf("report %q: %w", r.Name, err)
+		}
+		if len(r.Recipients) == 0 {
+			return nil, fmt.Errorf("report %q: recipients are required", r.Name)
+		}
+		r.Folder = strings.Trim(r.Folder, "/")
+		if r.ProdEnv == "" {
+			r.ProdEnv = "PROD_AND_DR"
+		}
+		if r.Top == 0 {
+			r.Top = 10
+		}
+	}
+	return cfg, nil
+}
+
+// LoadConfig reads a report schedules document from disk.
+func LoadConfig(path string, ranges RangeFunc) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read report schedules file: %w", err)
+	}
+	return ParseConfig(data, ranges)
+}
+
+// Find returns the report with the given name, or nil.
+func (cfg *Config) Find(name string) *Report {
+	for i := range cfg.Reports {
+		if cfg.Reports[i].Name == name {
+			return &cfg.Reports[i]
+		}
+	}
+	return nil
+}
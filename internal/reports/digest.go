@@ -0,0 +1,135 @@
+package reports
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/xuri/excelize/v2"
+)
+
+//go:embed digest.html
+var digestHTML string
+
+var digestTemplate = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"percent": func(f float64) string { return fmt.Sprintf("%.1f%%", f*100) },
+}).Parse(digestHTML))
+
+// Digest is the content of one report delivery.
+type Digest struct {
+	Report      *Report
+	From        time.Time
+	To          time.Time
+	Location    *time.Location
+	Totals      []models.EnvTotal
+	Overall     models.EnvTotal
+	TopFailing  []models.PipelineStat
+	Deployments []models.Build // deployments to the report's prod env
+	Slowest     []models.PipelineStat
+	GeneratedAt time.Time
+}
+
+// Load gathers the digest of r over [from, to].
+func Load(database *db.DB, r *Report, from, to time.Time, loc *time.Location) (*Digest, error) {
+	f := db.DigestFilter{From: from, To: to, Env: r.Env, Folder: r.Folder}
+	d := &Digest{Report: r, From: from, To: to, Location: loc, Overall: models.EnvTotal{Env: "total"}, GeneratedAt: time.Now()}
+
+	var err error
+	if d.Totals, err = database.EnvTotals(f); err != nil {
+		return nil, err
+	}
+	for _, t := range d.Totals {
+		d.Overall.AvgDurationMS = (d.Overall.AvgDurationMS*int64(d.Overall.Builds) + t.AvgDurationMS*int64(t.Builds)) / max(1, int64(d.Overall.Builds+t.Builds))
+		d.Overall.Builds += t.Builds
+		d.Overall.Succeeded += t.Succeeded
+		d.Overall.Failed += t.Failed
+		d.Overall.Aborted += t.Aborted
+	}
+	if d.TopFailing, err = database.TopFailingPipelines(f, r.Top); err != nil {
+		return nil, err
+	}
+	if d.Slowest, err = database.SlowestPipelines(f, r.Top); err != nil {
+		return nil, err
+	}
+
+	deploys, err := database.GetEnvDeployments(r.ProdEnv, true, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range deploys {
+		if r.Folder == "" || b.ProjectPath == r.Folder || strings.HasPrefix(b.ProjectPath, r.Folder+"/") {
+			d.Deployments = append(d.Deployments, b)
+		}
+	}
+	return d, nil
+}
+
+// Subject is the email subject of the digest.
+func (d *Digest) Subject() string {
+	if d.Report.Subject != "" {
+		return d.Report.Subject
+	}
+	return fmt.Sprintf("%s: %s – %s", d.Report.Name, d.From.Format("Jan 02"), d.To.Format("Jan 02 2006"))
+}
+
+// HTML renders the digest as a self-contained HTML page for the email body.
+func (d *Digest) HTML() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := digestTemplate.Execute(&buf, d); err != nil {
+		return nil, fmt.Errorf("render digest %s: %w", d.Report.Name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Excel renders the digest as a workbook with one sheet per section.
+func (d *Digest) Excel() ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetSheetName("Sheet1", "Summary")
+
+	header := []interface{}{"Env", "Builds", "Succeeded", "Failed", "Aborted", "Success %", "Avg duration (ms)"}
+	f.SetSheetRow("Summary", "A1", &header)
+	for i, t := range append(d.Totals, d.Overall) {
+		row := []interface{}{t.Env, t.Builds, t.Succeeded, t.Failed, t.Aborted, fmt.Sprintf("%.1f", t.SuccessRate()*100), t.AvgDurationMS}
+		f.SetSheetRow("Summary", fmt.Sprintf("A%d", i+2), &row)
+	}
+
+	pipelines := func(sheet string, stats []models.PipelineStat) {
+		f.NewSheet(sheet)
+		header := []interface{}{"#", "Controller", "Project", "Builds", "Failures", "Failure %", "Avg duration (ms)", "P95 duration (ms)", "Max duration (ms)", "Last build"}
+		f.SetSheetRow(sheet, "A1", &header)
+		for i, p := range stats {
+			row := []interface{}{i + 1, p.Controller, p.ProjectPath, p.Builds, p.Failures, fmt.Sprintf("%.1f", p.FailureRate()*100),
+				p.AvgDurationMS, p.P95DurationMS, p.MaxDurationMS, p.LastBuildAt.In(d.Location).Format("2006-01-02 15:04")}
+			f.SetSheetRow(sheet, fmt.Sprintf("A%d", i+2), &row)
+		}
+	}
+	pipelines("Top failing", d.TopFailing)
+
+	f.NewSheet("Deployments")
+	header = []interface{}{"Time", "Controller", "Project", "Build#", "DeployEnv", "Status", "User", "IGRM#", "IGRM check"}
+	f.SetSheetRow("Deployments", "A1", &header)
+	for i, b := range d.Deployments {
+		row := []interface{}{b.Timestamp.In(d.Location).Format("2006-01-02 15:04"), b.Controller, b.ProjectPath, b.BuildNumber,
+			b.DeployEnv, b.Status, b.UserID, b.IGRMNo, b.IGRMStatus}
+		f.SetSheetRow("Deployments", fmt.Sprintf("A%d", i+2), &row)
+	}
+
+	pipelines("Slowest", d.Slowest)
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, fmt.Errorf("write digest workbook: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Filename is the name of the Excel attachment.
+func (d *Digest) Filename() string {
+	return fmt.Sprintf("%s_%s_to_%s.xlsx", d.Report.Name, d.From.Format("2006-01-02"), d.To.Format("2006-01-02"))
+}
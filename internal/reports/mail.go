@@ -0,0 +1,55 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/mailer"
+)
+
+// buildMessage assembles a multipart/mixed mail with an HTML body and one
+// attachment.
+func buildMessage(smtp mailer.Config, to []string, subject string, html []byte, filename string, attachment []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	smtp.WriteHeader(&buf, to, subject)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	writeBase64(part, html)
+
+	part, err = mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {fmt.Sprintf(`application/vnd.openxmlformats-officedocument.spreadsheetml.sheet; name="%s"`, filename)},
+		"Content-Disposition":       {fmt.Sprintf(`attachment; filename="%s"`, filename)},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	writeBase64(part, attachment)
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64 writes data base64 encoded in lines of 76 characters.
+func writeBase64(w interface{ Write([]byte) (int, error) }, data []byte) {
+	enc := base64.StdEncoding.EncodeToString(data)
+	for len(enc) > 76 {
+		w.Write([]byte(enc[:76] + "\r\n"))
+		enc = enc[76:]
+	}
+	w.Write([]byte(enc + "\r\n"))
+}
@@ -0,0 +1,176 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/mailer"
+	"github.com/gauravkr19/jenkins-analytics/models"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestSchedule(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	// 2025-03-05 is a Wednesday
+	at := func(day, hour, min int) time.Time { return time.Date(2025, 3, day, hour, min, 0, 0, berlin) }
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"0 7 * * mon", at(5, 10, 0), at(10, 7, 0)},
+		{"0 7 * * MON", at(10, 7, 0), at(17, 7, 0)}, // strictly after
+		{"*/15 * * * *", at(5, 10, 7), at(5, 10, 15)},
+		{"30 6 1 * *", at(5, 10, 0), time.Date(2025, 4, 1, 6, 30, 0, 0, berlin)},
+		{"0 9-17/4 * * 1-5", at(7, 17, 0), at(10, 9, 0)},
+		{"0 0 13 * 5", at(5, 10, 0), at(7, 0, 0)}, // day of month or Friday
+		{"0 8 * * 7", at(5, 10, 0), at(9, 8, 0)},  // 7 is Sunday
+		{"@daily", at(5, 10, 0), at(6, 0, 0)},
+		{"0 2 * * *", at(29, 12, 0), time.Date(2025, 3, 31, 2, 0, 0, 0, berlin)}, // 30 Mar 02:00 does not exist
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%q after %s: got %s, want %s", tt.expr, tt.from, got, tt.want)
+		}
+	}
+
+	if s, _ := ParseSchedule("0 0 30 2 *"); !s.Next(at(5, 0, 0)).IsZero() {
+		t.Error("30 Feb should never fire")
+	}
+	for _, bad := range []string{"", "* * * *", "60 * * * *", "0 7 * * funday", "*/0 * * * *", "5-1 * * * *"} {
+		if _, err := ParseSchedule(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestDigestMessage(t *testing.T) {
+	r := &Report{Name: "weekly-digest", ProdEnv: "PROD_AND_DR"}
+	from := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	d := &Digest{
+		Report:   r,
+		From:     from,
+		To:       from.AddDate(0, 0, 7).Add(-time.Nanosecond),
+		Location: time.UTC,
+		Totals:   []models.EnvTotal{{Env: "PROD_AND_DR", Builds: 10, Succeeded: 7, Failed: 3, AvgDurationMS: 90000}},
+		Overall:  models.EnvTotal{Env: "total", Builds: 10, Succeeded: 7, Failed: 3, AvgDurationMS: 90000},
+		TopFailing: []models.PipelineStat{{Controller: "prod", ProjectPath: "PROD_AND_DR/pay/deploy", Builds: 5, Failures: 3,
+			LastBuildAt: from.Add(time.Hour)}},
+		Deployments: []models.Build{{ProjectPath: "PROD_AND_DR/pay/deploy", BuildNumber: 4, DeployEnv: "prod", Status: "FAILURE",
+			Timestamp: from.Add(time.Hour)}},
+	}
+
+	html, err := d.HTML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"PROD_AND_DR/pay/deploy #4", "70.0%", "missing", "1.5 min", "No builds in this range."} {
+		if !bytes.Contains(html, []byte(want)) {
+			t.Errorf("HTML digest lacks %q", want)
+		}
+	}
+	xlsx, err := d.Excel()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := buildMessage(mailer.Config{From: "ci@example.com"}, []string{"a@example.com", "b@example.com"}, d.Subject(), html, d.Filename(), xlsx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := mail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if subject != "weekly-digest: Mar 03 – Mar 09 2025" {
+		t.Errorf("subject = %q", subject)
+	}
+	_, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	var parts []string
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(base64.NewDecoder(base64.StdEncoding, p))
+		parts = append(parts, p.FileName())
+		if p.FileName() == "" && !bytes.Equal(body, html) {
+			t.Error("HTML part does not round-trip")
+		}
+		if p.FileName() != "" {
+			f, err := excelize.OpenReader(bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("attachment is not a workbook: %v", err)
+			}
+			if got := strings.Join(f.GetSheetList(), ","); got != "Summary,Top failing,Deployments,Slowest" {
+				t.Errorf("sheets = %s", got)
+			}
+		}
+	}
+	if len(parts) != 2 || parts[1] != "weekly-digest_2025-03-03_to_2025-03-09.xlsx" {
+		t.Errorf("parts = %q", parts)
+	}
+}
+
+func TestResumeFrom(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	weekly, err := ParseSchedule("0 8 * * mon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2025-03-10 is a Monday, the process restarts a minute after the digest was due
+	now := time.Date(2025, 3, 10, 8, 1, 0, 0, berlin)
+	monday8 := time.Date(2025, 3, 10, 8, 0, 0, 0, berlin)
+	lastWeek := time.Date(2025, 3, 3, 8, 0, 0, 0, berlin)
+	recent := time.Date(2025, 3, 10, 8, 0, 30, 0, berlin)
+
+	tests := []struct {
+		name string
+		last *time.Time
+		due  bool
+	}{
+		{"no previous run", nil, true},
+		{"last run a week ago", &lastWeek, true},
+		{"already sent", &recent, false},
+	}
+	for _, tt := range tests {
+		due := weekly.Next(resumeFrom(tt.last, now))
+		if got := due.Equal(monday8); got != tt.due {
+			t.Errorf("%s: next firing %s, want due at %s: %v", tt.name, due, monday8, tt.due)
+		}
+	}
+
+	if got, want := resumeFrom(nil, now), now.Add(-catchUp); !got.Equal(want) {
+		t.Errorf("without a previous run: got %s, want %s", got, want)
+	}
+	old := now.Add(-30 * 24 * time.Hour)
+	if got, want := resumeFrom(&old, now), now.Add(-catchUp); !got.Equal(want) {
+		t.Errorf("old run is capped at catchUp: got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,186 @@
+package reports
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"github.com/gauravkr19/jenkins-analytics/models"
+)
+
+// catchUp is how late a missed scheduled run, e.g. during a restart, is still sent.
+const catchUp = 24 * time.Hour
+
+// Run triggers.
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// Scheduler delivers the reports of a Config on their cron schedules.
+type Scheduler struct {
+	Config *Config
+	DB     *db.DB
+	Ranges RangeFunc
+
+	mu      sync.Mutex           // one delivery at a time
+	checked map[string]time.Time // per report, cron times up to here are handled
+}
+
+func NewScheduler(cfg *Config, database *db.DB, ranges RangeFunc) *Scheduler {
+	return &Scheduler{Config: cfg, DB: database, Ranges: ranges, checked: make(map[string]time.Time)}
+}
+
+// RunDue sends every report whose schedule fired since the last call and
+// returns the number sent. Several missed firings result in one delivery.
+func (s *Scheduler) RunDue(now time.Time) (int, error) {
+	now = now.In(s.Config.Location)
+	sent := 0
+	var errs []string
+	for i := range s.Config.Reports {
+		r := &s.Config.Reports[i]
+		since, err := s.checkedUntil(r, now)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		due := r.Cron.Next(since)
+		if due.IsZero() || due.After(now) {
+			continue
+		}
+		// skip firings missed in between, the range covers them
+		for next := r.Cron.Next(due); !next.IsZero() && !next.After(now); next = r.Cron.Next(due) {
+			due = next
+		}
+
+		run, err := s.deliver(r, TriggerSchedule, &due)
+		s.mu.Lock()
+		s.checked[r.Name] = due
+		s.mu.Unlock()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", r.Name, err))
+			continue
+		}
+		if run.Status == "sent" {
+			sent++
+		}
+	}
+	if len(errs) > 0 {
+		return sent, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return sent, nil
+}
+
+// checkedUntil returns the time after which firings of r are still due. On
+// the first call it resumes from the last scheduled run of a previous process.
+func (s *Scheduler) checkedUntil(r *Report, now time.Time) (time.Time, error) {
+	s.mu.Lock()
+	t, ok := s.checked[r.Name]
+	s.mu.Unlock()
+	if ok {
+		return t, nil
+	}
+
+	last, err := s.DB.LastScheduledRun(r.Name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t = resumeFrom(last, now)
+	s.mu.Lock()
+	s.checked[r.Name] = t
+	s.mu.Unlock()
+	return t, nil
+}
+
+// resumeFrom is where a new process starts looking for firings: the last
+// scheduled run, but no more than catchUp back. Without a previous run, e.g.
+// on first start or a restart before the first delivery, firings within
+// catchUp are still due.
+func resumeFrom(last *time.Time, now time.Time) time.Time {
+	t := now.Add(-catchUp)
+	if last != nil && last.After(t) {
+		t = last.In(now.Location())
+	}
+	return t
+}
+
+// NextRun is the next scheduled delivery of r after now.
+func (s *Scheduler) NextRun(r *Report, now time.Time) time.Time {
+	return r.Cron.Next(now.In(s.Config.Location))
+}
+
+// SendNow delivers a report outside its schedule.
+func (s *Scheduler) SendNow(name string) (*models.ReportRun, error) {
+	r := s.Config.Find(name)
+	if r == nil {
+		return nil, fmt.Errorf("unknown report %q", name)
+	}
+	return s.deliver(r, TriggerManual, nil)
+}
+
+// Digest loads the current digest of a report, e.g. for a preview.
+func (s *Scheduler) Digest(name string) (*Digest, error) {
+	r := s.Config.Find(name)
+	if r == nil {
+		return nil, fmt.Errorf("unknown report %q", name)
+	}
+	from, to, err := s.Ranges(r.Range)
+	if err != nil {
+		return nil, err
+	}
+	return Load(s.DB, r, from, to, s.Config.Location)
+}
+
+// deliver builds and mails a digest and records the run. The run is returned
+// with status failed when only the delivery failed.
+func (s *Scheduler) deliver(r *Report, trigger string, scheduledFor *time.Time) (*models.ReportRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from, to, err := s.Ranges(r.Range)
+	if err != nil {
+		return nil, err
+	}
+	run := &models.ReportRun{
+		Report:       r.Name,
+		Trigger:      trigger,
+		ScheduledFor: scheduledFor,
+		RangeFrom:    from,
+		RangeTo:      to,
+		Recipients:   r.Recipients,
+		Status:       "sent",
+		StartedAt:    time.Now(),
+	}
+	if err := s.send(r, from, to); err != nil {
+		log.Printf("[Reports] %s failed: %v", r.Name, err)
+		run.Status, run.Error = "failed", err.Error()
+	}
+	run.FinishedAt = time.Now()
+	if err := s.DB.InsertReportRun(run); err != nil {
+		return run, err
+	}
+	return run, nil
+}
+
+func (s *Scheduler) send(r *Report, from, to time.Time) error {
+	d, err := Load(s.DB, r, from, to, s.Config.Location)
+	if err != nil {
+		return err
+	}
+	html, err := d.HTML()
+	if err != nil {
+		return err
+	}
+	xlsx, err := d.Excel()
+	if err != nil {
+		return err
+	}
+	msg, err := buildMessage(s.Config.SMTP, r.Recipients, d.Subject(), html, d.Filename(), xlsx)
+	if err != nil {
+		return fmt.Errorf("build mail: %w", err)
+	}
+	return s.Config.SMTP.Send(r.Recipients, msg)
+}
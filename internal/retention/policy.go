@@ -0,0 +1,176 @@
+package retention
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+	"gopkg.in/yaml.v3"
+)
+
+// Age is a retention period in calendar units, e.g. 7y, 18m, 90d or 2w.
+// The zero Age keeps builds forever.
+type Age struct {
+	Years, Months, Days int
+}
+
+// ParseAge reads "<n>y", "<n>m", "<n>w" or "<n>d"; "forever" and "" keep
+// builds forever.
+func ParseAge(s string) (Age, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "forever" {
+		return Age{}, nil
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return Age{}, fmt.Errorf("invalid age %q, want e.g. 7y, 18m, 2w or 90d", s)
+	}
+	switch s[len(s)-1] {
+	case 'y':
+		return Age{Years: n}, nil
+	case 'm':
+		return Age{Months: n}, nil
+	case 'w':
+		return Age{Days: 7 * n}, nil
+	case 'd':
+		return Age{Days: n}, nil
+	}
+	return Age{}, fmt.Errorf("invalid age %q, want e.g. 7y, 18m, 2w or 90d", s)
+}
+
+// Forever reports whether the age keeps builds forever.
+func (a Age) Forever() bool {
+	return a == Age{}
+}
+
+// Cutoff is the time before which builds are expired; zero for Forever.
+func (a Age) Cutoff(now time.Time) time.Time {
+	if a.Forever() {
+		return time.Time{}
+	}
+	return now.AddDate(-a.Years, -a.Months, -a.Days)
+}
+
+func (a Age) String() string {
+	switch {
+	case a.Forever():
+		return "forever"
+	case a.Years > 0:
+		return fmt.Sprintf("%dy", a.Years)
+	case a.Months > 0:
+		return fmt.Sprintf("%dm", a.Months)
+	}
+	return fmt.Sprintf("%dd", a.Days)
+}
+
+// Policy keeps builds of an env and/or folder for Keep.
+type Policy struct {
+	Name        string `yaml:"name"`
+	Env         string `yaml:"env"`          // canonical env, see config/env-rules.yaml
+	Folder      string `yaml:"folder"`       // project path prefix
+	DeploysOnly bool   `yaml:"deploys_only"` // only builds with a deploy_env parameter
+	KeepText    string `yaml:"keep"`
+
+	Keep Age `yaml:"-"`
+}
+
+type policyFile struct {
+	Default  string   `yaml:"default"`
+	Policies []Policy `yaml:"policies"`
+}
+
+// Policies is a parsed retention document. The first matching policy decides
+// how long a build is kept; builds matching none are kept for Default.
+type Policies struct {
+	Default  Age
+	Policies []Policy
+}
+
+// ParsePolicies decodes and validates a retention document.
+func ParsePolicies(data []byte) (*Policies, error) {
+	var f policyFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid retention file: %w", err)
+	}
+
+	def, err := ParseAge(f.Default)
+	if err != nil {
+		return nil, fmt.Errorf("retention default: %w", err)
+	}
+	ps := &Policies{Default: def}
+	for i, p := range f.Policies {
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("policy-%d", i+1)
+		}
+		if p.Env == "" && p.Folder == "" && !p.DeploysOnly {
+			return nil, fmt.Errorf("retention policy %s: set env, folder or deploys_only", p.Name)
+		}
+		if p.Keep, err = ParseAge(p.KeepText); err != nil {
+			return nil, fmt.Errorf("retention policy %s: %w", p.Name, err)
+		}
+		ps.Policies = append(ps.Policies, p)
+	}
+	return ps, nil
+}
+
+// LoadPolicies reads a retention document from disk.
+func LoadPolicies(path string) (*Policies, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read retention file: %w", err)
+	}
+	return ParsePolicies(data)
+}
+
+// Rules turns the policies into db rules in match order, the default last.
+func (ps *Policies) Rules(now time.Time) []db.RetentionRule {
+	rules := make([]db.RetentionRule, 0, len(ps.Policies)+1)
+	for _, p := range ps.Policies {
+		rules = append(rules, db.RetentionRule{Env: p.Env, Folder: p.Folder, DeploysOnly: p.DeploysOnly, Cutoff: p.Keep.Cutoff(now)})
+	}
+	return append(rules, db.RetentionRule{Cutoff: ps.Default.Cutoff(now)})
+}
+
+// Names lists the policy names in Rules order.
+func (ps *Policies) Names() []string {
+	names := make([]string, 0, len(ps.Policies)+1)
+	for _, p := range ps.Policies {
+		names = append(names, p.Name)
+	}
+	return append(names, "default")
+}
+
+// DeleteCutoff is the time before which some policy may have deleted builds:
+// the latest cutoff of any policy; zero if every policy keeps builds forever.
+func (ps *Policies) DeleteCutoff(now time.Time) time.Time {
+	cutoff := ps.Default.Cutoff(now)
+	for _, p := range ps.Policies {
+		if c := p.Keep.Cutoff(now); c.After(cutoff) {
+			cutoff = c
+		}
+	}
+	return cutoff
+}
+
+// PartitionCutoff is the time before which no policy keeps any build, so
+// whole partitions ending before it can go; zero if some policy keeps builds
+// forever.
+func (ps *Policies) PartitionCutoff(now time.Time) time.Time {
+	cutoff := ps.Default.Cutoff(now)
+	if cutoff.IsZero() {
+		return cutoff
+	}
+	for _, p := range ps.Policies {
+		c := p.Keep.Cutoff(now)
+		if c.IsZero() {
+			return c
+		}
+		if c.Before(cutoff) {
+			cutoff = c
+		}
+	}
+	return cutoff
+}
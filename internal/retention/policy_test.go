@@ -0,0 +1,94 @@
+package retention
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	cases := map[string]Age{
+		"7y":      {Years: 7},
+		"18m":     {Months: 18},
+		"2w":      {Days: 14},
+		"90D":     {Days: 90},
+		"forever": {},
+		"":        {},
+	}
+	for in, want := range cases {
+		got, err := ParseAge(in)
+		if err != nil || got != want {
+			t.Errorf("ParseAge(%q) = %+v, %v; want %+v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"7", "y", "-1d", "3h", "tenyears"} {
+		if _, err := ParseAge(in); err == nil {
+			t.Errorf("ParseAge(%q): expected an error", in)
+		}
+	}
+}
+
+func TestPoliciesCutoffs(t *testing.T) {
+	ps, err := ParsePolicies([]byte(`
+default: 1y
+policies:
+  - name: prod-deploys
+    env: PROD_AND_DR
+    deploys_only: true
+    keep: 7y
+  - name: dev
+    env: DEV
+    keep: 90d
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)
+
+	rules := ps.Rules(now)
+	if len(rules) != 3 {
+		t.Fatalf("got %d rules, want 3 (two policies and the default)", len(rules))
+	}
+	if want := time.Date(2018, 6, 15, 0, 0, 0, 0, time.UTC); !rules[0].Cutoff.Equal(want) {
+		t.Errorf("prod-deploys cutoff = %v, want %v", rules[0].Cutoff, want)
+	}
+	if rules[2].Env != "" || rules[2].Folder != "" {
+		t.Errorf("default rule must match every build, got %+v", rules[2])
+	}
+
+	// partitions go only once the longest policy has expired them
+	if got := ps.PartitionCutoff(now); !got.Equal(rules[0].Cutoff) {
+		t.Errorf("PartitionCutoff = %v, want %v", got, rules[0].Cutoff)
+	}
+
+	// rollups of days the shortest policy reaches may miss builds
+	if got := ps.DeleteCutoff(now); !got.Equal(rules[1].Cutoff) {
+		t.Errorf("DeleteCutoff = %v, want %v", got, rules[1].Cutoff)
+	}
+
+	ps.Policies[1].Keep = Age{}
+	if got := ps.PartitionCutoff(now); !got.IsZero() {
+		t.Errorf("PartitionCutoff with a forever policy = %v, want zero", got)
+	}
+	if got := ps.DeleteCutoff(now); !got.Equal(rules[2].Cutoff) {
+		t.Errorf("DeleteCutoff with a forever policy = %v, want the default %v", got, rules[2].Cutoff)
+	}
+}
+
+func TestParsePoliciesInvalid(t *testing.T) {
+	docs := []string{
+		"policies:\n  - name: all\n    keep: 1y\n",
+		"policies:\n  - env: DEV\n    keep: soon\n",
+		"default: 5x\n",
+	}
+	for _, doc := range docs {
+		if _, err := ParsePolicies([]byte(doc)); err == nil {
+			t.Errorf("expected an error for %q", doc)
+		}
+	}
+}
+
+func TestShippedPolicies(t *testing.T) {
+	if _, err := LoadPolicies("../../config/retention.yaml"); err != nil {
+		t.Fatal(err)
+	}
+}
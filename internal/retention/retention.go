@@ -0,0 +1,206 @@
+package retention
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gauravkr19/jenkins-analytics/internal/archive"
+	"github.com/gauravkr19/jenkins-analytics/internal/db"
+)
+
+const (
+	DropPartitions   = "drop"
+	DetachPartitions = "detach"
+)
+
+// Options tune a retention run.
+type Options struct {
+	PartitionAction string        // DropPartitions or DetachPartitions for months past every policy
+	DeleteBatch     int           // builds deleted per statement under shorter policies
+	MonthsAhead     int           // future monthly partitions kept ready
+	DryRun          bool          // only count what would be removed
+	Archive         archive.Store // builds are archived here before they are deleted, nil to skip
+}
+
+// Report summarises a retention run.
+type Report struct {
+	Created  []string       // partitions created ahead
+	Expired  []string       // partitions dropped or detached
+	Deleted  map[string]int // builds deleted per policy name
+	Affected int            // builds in expired partitions
+	Archived []string       // archive files written
+}
+
+// Run creates upcoming partitions, drops or detaches partitions that every
+// policy has expired, then deletes expired builds of shorter policies from the
+// partitions that remain.
+func Run(database *db.DB, ps *Policies, opts Options, now time.Time) (*Report, error) {
+	report := &Report{Deleted: make(map[string]int)}
+	if opts.PartitionAction != DropPartitions && opts.PartitionAction != DetachPartitions {
+		return report, fmt.Errorf("unknown partition action %q (want %s or %s)", opts.PartitionAction, DropPartitions, DetachPartitions)
+	}
+
+	if !opts.DryRun {
+		created, err := database.EnsureBuildPartitions(now, opts.MonthsAhead)
+		report.Created = created
+		if err != nil {
+			return report, err
+		}
+	}
+
+	// builds before expiredTo go with their partition
+	var expiredTo time.Time
+	if cutoff := ps.PartitionCutoff(now); !cutoff.IsZero() {
+		partitions, err := database.ListBuildPartitions()
+		if err != nil {
+			return report, err
+		}
+		for _, p := range partitions {
+			if p.To.After(cutoff) {
+				break
+			}
+			n, err := database.CountPartitionBuilds(p)
+			if err != nil {
+				return report, err
+			}
+			if !opts.DryRun {
+				if opts.PartitionAction == DetachPartitions {
+					err = archiveAndDetach(database, opts.Archive, p, n, report)
+				} else if opts.Archive != nil && n > 0 {
+					err = archiveAndDrop(database, opts.Archive, p, report)
+				} else {
+					err = database.DropBuildPartition(p)
+				}
+				if err != nil {
+					return report, err
+				}
+			}
+			report.Expired = append(report.Expired, p.Name)
+			report.Affected += n
+			expiredTo = p.To
+		}
+	}
+
+	rules := ps.Rules(now)
+	names := ps.Names()
+	batch := opts.DeleteBatch
+	if batch <= 0 {
+		batch = 1000
+	}
+	for i := range rules {
+		if opts.DryRun {
+			n, err := database.CountExpiredBuilds(rules, i, expiredTo)
+			if err != nil {
+				return report, err
+			}
+			report.Deleted[names[i]] = n
+			continue
+		}
+		if opts.Archive != nil {
+			n, err := archiveAndDelete(database, opts.Archive, rules, i, names[i], batch, report)
+			report.Deleted[names[i]] += n
+			if err != nil {
+				return report, fmt.Errorf("policy %s: %w", names[i], err)
+			}
+			continue
+		}
+		for {
+			n, err := database.DeleteExpiredBuilds(rules, i, batch)
+			if err != nil {
+				return report, fmt.Errorf("policy %s: %w", names[i], err)
+			}
+			report.Deleted[names[i]] += n
+			if n < batch {
+				break
+			}
+		}
+	}
+
+	for name, n := range report.Deleted {
+		if n > 0 {
+			log.Printf("[Retention] policy %s: %d expired builds", name, n)
+		}
+	}
+	return report, nil
+}
+
+// archiveAndDrop archives the builds of a partition with their child rows and
+// then drops it.
+func archiveAndDrop(database *db.DB, store archive.Store, p db.BuildPartition, report *Report) error {
+	manifest := archive.Manifest{Scope: p.Name, Reason: "drop partition", From: p.From, To: p.To}
+	m, ids, err := archive.Write(store, manifest,
+		func(emit func(int, []byte) error) error { return database.ExportPartition(p, emit) })
+	if err != nil {
+		return fmt.Errorf("archive partition %s: %w", p.Name, err)
+	}
+	// a build stored into the month meanwhile would be dropped unarchived
+	if n, err := database.CountPartitionBuilds(p); err != nil {
+		return err
+	} else if n != len(ids) {
+		return fmt.Errorf("partition %s changed while archiving (%d archived, %d now), retrying next run", p.Name, len(ids), n)
+	}
+	if m.File != "" {
+		report.Archived = append(report.Archived, m.File)
+	}
+	files, err := archive.WriteBuildChildren(database, store, manifest, ids)
+	report.Archived = append(report.Archived, files...)
+	if err != nil {
+		return fmt.Errorf("archive partition %s: %w", p.Name, err)
+	}
+	return database.DropBuildPartition(p)
+}
+
+// archiveAndDetach detaches a partition. The detached table keeps its builds,
+// so only their child rows, which go on detach, are archived first.
+func archiveAndDetach(database *db.DB, store archive.Store, p db.BuildPartition, n int, report *Report) error {
+	if store != nil && n > 0 {
+		ids, err := database.PartitionBuildIDs(p)
+		if err != nil {
+			return err
+		}
+		files, err := archive.WriteBuildChildren(database, store, archive.Manifest{Scope: p.Name, Reason: "detach partition", From: p.From, To: p.To}, ids)
+		report.Archived = append(report.Archived, files...)
+		if err != nil {
+			return fmt.Errorf("archive partition %s: %w", p.Name, err)
+		}
+	}
+	return database.DetachBuildPartition(p)
+}
+
+// archiveAndDelete archives every build expired under rules[i] with its child
+// rows and then deletes exactly the archived builds, so a build stored
+// meanwhile is never lost.
+func archiveAndDelete(database *db.DB, store archive.Store, rules []db.RetentionRule, i int, name string, batch int, report *Report) (int, error) {
+	if rules[i].Cutoff.IsZero() {
+		return 0, nil
+	}
+	manifest := archive.Manifest{Scope: name, Reason: "retention policy", To: rules[i].Cutoff}
+	m, ids, err := archive.Write(store, manifest,
+		func(emit func(int, []byte) error) error { return database.ExportExpiredBuilds(rules, i, emit) })
+	if err != nil {
+		return 0, fmt.Errorf("archive: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	report.Archived = append(report.Archived, m.File)
+	files, err := archive.WriteBuildChildren(database, store, manifest, ids)
+	report.Archived = append(report.Archived, files...)
+	if err != nil {
+		return 0, fmt.Errorf("archive: %w", err)
+	}
+
+	deleted := 0
+	for start := 0; start < len(ids); start += batch {
+		end := start + batch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		if err := database.DeleteBuilds(ids[start:end], rules[i].Cutoff); err != nil {
+			return deleted, err
+		}
+		deleted += end - start
+	}
+	return deleted, nil
+}
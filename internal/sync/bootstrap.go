@@ -1,53 +1,55 @@
 package sync
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"os"
 
 	"github.com/gauravkr19/jenkins-analytics/internal/db"
 	"github.com/gauravkr19/jenkins-analytics/internal/jenkins"
 )
 
-// SyncInitialBuildsIfNeeded ensures DB has the initial Jenkins builds which runs once
-func SyncInitialBuildsIfNeeded(database *db.DB) error {
+// SyncInitialBuildsIfNeeded ensures DB has the initial builds of a controller which runs once
+func SyncInitialBuildsIfNeeded(database *db.DB, client *jenkins.JenkinsClient) error {
 
-	syncDone, err := database.IsInitialSyncDone()
+	syncDone, err := database.IsInitialSyncDone(client.Name)
 	if err != nil {
 		return err
 	}
 
 	if syncDone {
-		log.Println("Initial sync already completed. Skipping...")
+		log.Printf("Initial sync of %s already completed. Skipping...", client.Name)
 		return nil
 	}
 
-	log.Println("Initial sync not found. Fetching builds from Jenkins...")
-	client := jenkins.NewJenkinsClient(
-		os.Getenv("JENKINS_URL"),
-		os.Getenv("JENKINS_USER"),
-		os.Getenv("JENKINS_TOKEN"),
-	)
+	log.Printf("Initial sync of %s not found. Fetching builds from Jenkins...", client.Name)
 
 	if client.BaseURL == "" || client.Username == "" || client.APIToken == "" {
-		log.Fatalf("Jenkins client not properly configured. Check environment variables.")
+		log.Fatalf("Jenkins client %s not properly configured. Check controller settings.", client.Name)
 	}
 
 	saved, failed, _, err := jenkins.FetchAndStoreBuilds(database, client, false)
+	if errors.Is(err, jenkins.ErrBackfillIncomplete) {
+		// start anyway; the sync is not marked done, so the next start resumes
+		// the failed jobs while the poller keeps the others current
+		log.Printf("Initial sync of %s incomplete, saved=%d, failed=%d: %v", client.Name, saved, failed, err)
+		return nil
+	}
 	if err != nil {
 		log.Printf("Initial sync failed: %v", err)
 		return err
 	}
 
-	// Intial sync invoked by no records written to DB
+	// A backfill resumed after its last job checkpoint has nothing left to insert,
+	// so zero saved builds is not an error on its own; an empty controller is
+	// already rejected by the backfill.
 	if saved == 0 {
-		log.Printf("Initial sync skipped marking as complete: no builds inserted.")
-		return fmt.Errorf("zero builds inserted during initial sync")
+		log.Printf("Initial sync of %s inserted no new builds (history already backfilled).", client.Name)
 	}
 
-	log.Printf("Initial sync complete: saved=%d, failed=%d\n", saved, failed)
+	log.Printf("Initial sync of %s complete: saved=%d, failed=%d\n", client.Name, saved, failed)
 
-	if err := database.MarkInitialSyncDone(); err != nil {
+	if err := database.MarkInitialSyncDone(client.Name); err != nil {
 		return fmt.Errorf("failed to mark initial sync done: %w", err)
 	}
 
@@ -20,6 +20,9 @@ var FuncMap = template.FuncMap{
 	"sub": func(a, b int) int { return a - b },
 	"mul": func(a, b int) int { return a * b },
 	"upper": func(s string) string { return strings.ToUpper(s) },
+	"list":  func(items ...string) []string { return items },
+	// percent renders a 0..1 ratio as "12.5%"
+	"percent": func(f float64) string { return fmt.Sprintf("%.1f%%", f*100) },
 
 	// seq(start, end) returns a slice [start, start+1, …, end]
 	"seq": func(start, end int) []int {
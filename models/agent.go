@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// AgentSnapshot is the state of one node at one collector run.
+type AgentSnapshot struct {
+	Controller         string         `db:"controller"`
+	NodeName           string         `db:"node_name"`
+	Labels             pq.StringArray `db:"labels"`
+	NumExecutors       int            `db:"num_executors"`
+	BusyExecutors      int            `db:"busy_executors"`
+	Offline            bool           `db:"offline"`
+	TemporarilyOffline bool           `db:"temporarily_offline"`
+	OfflineReason      string         `db:"offline_reason"`
+	TakenAt            time.Time      `db:"taken_at"`
+}
+
+// AgentUsage is the executor time builds spent on one agent.
+type AgentUsage struct {
+	Agent           string         `db:"agent" json:"agent"`
+	Labels          pq.StringArray `db:"labels" json:"labels"`
+	Builds          int            `db:"builds" json:"builds"`
+	ExecutorMinutes float64        `db:"executor_minutes" json:"executor_minutes"`
+}
+
+// LabelUsage is the executor time builds spent on agents carrying a label.
+type LabelUsage struct {
+	Label           string  `db:"label" json:"label"`
+	Agents          int     `db:"agents" json:"agents"`
+	Builds          int     `db:"builds" json:"builds"`
+	ExecutorMinutes float64 `db:"executor_minutes" json:"executor_minutes"`
+}
+
+// HourlyUtilisation is the share of executors busy in an hour of the day.
+type HourlyUtilisation struct {
+	Hour         int     `db:"hour" json:"hour"`
+	AvgBusy      float64 `db:"avg_busy" json:"avg_busy"`
+	AvgExecutors float64 `db:"avg_executors" json:"avg_executors"`
+	Utilisation  float64 `db:"utilisation" json:"utilisation"`
+}
+
+// OfflineIncident is a stretch of consecutive snapshots with a node offline.
+type OfflineIncident struct {
+	Controller string    `db:"controller" json:"controller"`
+	Agent      string    `db:"agent" json:"agent"`
+	Start      time.Time `db:"start_at" json:"start"`
+	End        time.Time `db:"end_at" json:"end"`
+	Reason     string    `db:"reason" json:"reason"`
+	Ongoing    bool      `db:"ongoing" json:"ongoing"`
+}
+
+// Duration is the observed length of the incident.
+func (o OfflineIncident) Duration() string {
+	return formatDurationMS(o.End.Sub(o.Start).Milliseconds())
+}
+
+// FolderPoolUsage is the executor time a folder consumed on agents of a label.
+type FolderPoolUsage struct {
+	Folder          string  `db:"folder" json:"folder"`
+	Label           string  `db:"label" json:"label"`
+	Builds          int     `db:"builds" json:"builds"`
+	ExecutorMinutes float64 `db:"executor_minutes" json:"executor_minutes"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Delivery states of a fired alert.
+const (
+	AlertSent     = "sent"
+	AlertFailed   = "failed" // at least one channel could not be reached
+	AlertSilenced = "silenced"
+)
+
+// Alert is one firing of an alert rule, kept as history.
+type Alert struct {
+	ID          int64          `db:"id" json:"id"`
+	Rule        string         `db:"rule" json:"rule"`
+	Severity    string         `db:"severity" json:"severity"`
+	DedupKey    string         `db:"dedup_key" json:"dedup_key"`
+	BuildID     int            `db:"build_id" json:"build_id,omitempty"` // build that triggered the rule
+	Controller  string         `db:"controller" json:"controller,omitempty"`
+	ProjectPath string         `db:"project_path" json:"project_path,omitempty"`
+	Title       string         `db:"title" json:"title"`
+	Message     string         `db:"message" json:"message"`
+	URL         string         `db:"url" json:"url,omitempty"`
+	Channels    pq.StringArray `db:"channels" json:"channels"`
+	State       string         `db:"state" json:"state"`
+	Error       string         `db:"error" json:"error,omitempty"`
+	FiredAt     time.Time      `db:"fired_at" json:"fired_at"`
+}
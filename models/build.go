@@ -7,6 +7,7 @@ import (
 
 type Build struct {
 	ID          int       `db:"id"`
+	Controller  string    `db:"controller"` // Jenkins controller the build ran on
 	BuildNumber int       `db:"build_number"`
 	ProjectName string    `db:"project_name"`
 	ProjectPath string    `db:"project_path"`
@@ -22,26 +23,53 @@ type Build struct {
 	TriggerType string    `db:"trigger_type"` // cause.shortDescription
 	Env 		string 	  `db:"env"`		  // folder proj path
 	IGRMNo 		string 	  `db:"igrm_no"`	  // string params
+	IGRMStatus      string `db:"igrm_status"`      // change-management check: valid, not_found, ...
+	IGRMDetail      string `db:"igrm_detail"`
+	FailureCategory string `db:"failure_category"` // set by the failure classifier
+	FailureRule     string `db:"failure_rule"`
+	Building            bool  `db:"building"`              // still running on Jenkins
+	QueueID             int64 `db:"queue_id"`              // Jenkins queue item the build started from
+	EstimatedDurationMS int64 `db:"estimated_duration_ms"` // Jenkins' estimate while running
+	QueueWaitMS         int64 `db:"queue_wait_ms"`         // time in queue before an executor was assigned
+	BuiltOn             string `db:"built_on"`             // agent the build executed on
+	Parameters          BuildParams `db:"parameters"`       // every build parameter
+	Causes              BuildCauses `db:"causes"`
+	Changes             []BuildChange `db:"-"`             // commits from the changeSet, nil when not fetched
 }
 
 // models/folder_tree.go
 type FolderNode struct {
-	Name     string
-	FullPath string
-	IsLeaf   bool
-	Children map[string]*FolderNode
+	Name       string
+	FullPath   string // project path, without the controller level
+	Controller string // set when the tree is grouped by controller
+	IsLeaf     bool
+	Children   map[string]*FolderNode
 }
 
 type BuildLog struct {
-	ID             int    `db:"id"`
-	BuildNumber    int    `db:"build_number"`
-	ProjectName    string `db:"project_name"`
-	ConsoleLogHead string `db:"console_log_head"`
-	ConsoleLogTail string `db:"console_log_tail"`
+	ID             int       `db:"id"`
+	BuildID        int       `db:"build_id"`
+	BuildNumber    int       `db:"build_number"`
+	ProjectName    string    `db:"project_name"`
+	ConsoleLogHead string    `db:"console_log_head"`
+	ConsoleLogTail string    `db:"console_log_tail"`
+	ConsoleLogGz   []byte    `db:"console_log_gz"` // gzip of the full console text
+	SizeBytes      int64     `db:"size_bytes"`
+	Truncated      bool      `db:"truncated"`
+	FetchError     string    `db:"fetch_error"`
+	FetchedAt      time.Time `db:"fetched_at"`
 }
 
 func (b *Build) FormattedDuration() string {
-    ms := b.DurationMS
+    return formatDurationMS(b.DurationMS)
+}
+
+// FormattedQueueWait renders the queue wait; DurationMS is the execution time.
+func (b *Build) FormattedQueueWait() string {
+    return formatDurationMS(b.QueueWaitMS)
+}
+
+func formatDurationMS(ms int64) string {
     if ms < 60000 {
         return fmt.Sprintf("%.1f sec", float64(ms)/1000)
     }
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// BuildChange is one SCM commit shipped by a build, from its changeSet.
+type BuildChange struct {
+	ID       int            `db:"id" json:"-"`
+	BuildID  int            `db:"build_id" json:"-"`
+	CommitID string         `db:"commit_id" json:"commit_id"`
+	Author   string         `db:"author" json:"author"`
+	Message  string         `db:"message" json:"message"`
+	Paths    pq.StringArray `db:"paths" json:"paths"`
+	Kind     string         `db:"kind" json:"kind"`
+	Position int            `db:"position" json:"-"`
+}
+
+// ShortID is the abbreviated commit id shown in tables.
+func (c BuildChange) ShortID() string {
+	if len(c.CommitID) > 10 {
+		return c.CommitID[:10]
+	}
+	return c.CommitID
+}
+
+// Summary is the first line of the commit message.
+func (c BuildChange) Summary() string {
+	line, _, _ := strings.Cut(c.Message, "\n")
+	return line
+}
+
+// BreakingChange is a commit shipped by a failed build whose previous build of
+// the same job succeeded.
+type BreakingChange struct {
+	BuildID     int       `db:"build_id" json:"build_id"`
+	Controller  string    `db:"controller" json:"controller"`
+	ProjectPath string    `db:"project_path" json:"project_path"`
+	BuildNumber int       `db:"build_number" json:"build_number"`
+	Status      string    `db:"status" json:"status"`
+	Timestamp   time.Time `db:"timestamp" json:"timestamp"`
+	CommitID    string    `db:"commit_id" json:"commit_id"`
+	Author      string    `db:"author" json:"author"`
+	Message     string    `db:"message" json:"message"`
+}
+
+// BreakingAuthor counts the breaking changes of one author.
+type BreakingAuthor struct {
+	Author  string `db:"author" json:"author"`
+	Commits int    `db:"commits" json:"commits"`
+	Builds  int    `db:"builds" json:"builds"`
+}
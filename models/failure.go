@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// FailureCause is one row of the failure causes report.
+type FailureCause struct {
+	Week     time.Time `db:"week"`
+	Folder   string    `db:"folder"`
+	Category string    `db:"category"`
+	Count    int       `db:"count"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// JobCursor is the sync position of one job on a controller.
+type JobCursor struct {
+	Controller      string     `db:"controller"`
+	ProjectPath     string     `db:"project_path"`
+	LastBuildNumber int        `db:"last_build_number"`
+	LastPolledAt    *time.Time `db:"last_polled_at"`
+	LastError       string     `db:"last_error"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// BuildParams holds every parameter of a build, stored as JSONB.
+type BuildParams map[string]string
+
+// Value encodes the map as JSON text; lib/pq would send []byte as bytea.
+func (p BuildParams) Value() (driver.Value, error) {
+	if p == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(p)
+	return string(b), err
+}
+
+func (p *BuildParams) Scan(src interface{}) error {
+	return scanJSON(src, p)
+}
+
+// BuildCause is one "Started by ..." cause of a build.
+type BuildCause struct {
+	UserID           string `json:"userId,omitempty"`
+	UserName         string `json:"userName,omitempty"`
+	ShortDescription string `json:"shortDescription,omitempty"`
+}
+
+// BuildCauses holds every cause of a build, stored as JSONB.
+type BuildCauses []BuildCause
+
+func (c BuildCauses) Value() (driver.Value, error) {
+	if c == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(c)
+	return string(b), err
+}
+
+func (c *BuildCauses) Scan(src interface{}) error {
+	return scanJSON(src, c)
+}
+
+func scanJSON(src interface{}, dst interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dst)
+	case string:
+		return json.Unmarshal([]byte(v), dst)
+	default:
+		return fmt.Errorf("cannot scan %T as JSON", src)
+	}
+}
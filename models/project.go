@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// ProjectSummary aggregates the builds of one Jenkins job (project path).
+type ProjectSummary struct {
+	ProjectPath string    `db:"project_path"`
+	ProjectName string    `db:"project_name"`
+	BuildCount  int       `db:"build_count"`
+	LastBuildAt time.Time `db:"last_build_at"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// QueueItem is a Jenkins queue entry as last seen by the queue collector.
+type QueueItem struct {
+	Controller   string     `db:"controller"`
+	QueueID      int64      `db:"queue_id"`
+	TaskName     string     `db:"task_name"`
+	TaskURL      string     `db:"task_url"`
+	Why          string     `db:"why"`
+	Blocked      bool       `db:"blocked"`
+	Stuck        bool       `db:"stuck"`
+	InQueueSince time.Time  `db:"in_queue_since"`
+	LastSeenAt   time.Time  `db:"last_seen_at"`
+	LeftAt       *time.Time `db:"left_at"`
+}
+
+// Waiting is how long the item has been queued so far.
+func (q QueueItem) Waiting() string {
+	return formatDurationMS(time.Since(q.InQueueSince).Milliseconds())
+}
+
+// QueueWaitTrend is the weekly queue wait of builds in one folder.
+type QueueWaitTrend struct {
+	Week   time.Time `db:"week" json:"week"`
+	Folder string    `db:"folder" json:"folder"`
+	Builds int       `db:"builds" json:"builds"`
+	AvgMS  int64     `db:"avg_ms" json:"avg_ms"`
+	P90MS  int64     `db:"p90_ms" json:"p90_ms"`
+	MaxMS  int64     `db:"max_ms" json:"max_ms"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// EnvTotal sums the finished builds of one env in a report range.
+type EnvTotal struct {
+	Env           string `db:"env" json:"env"`
+	Builds        int    `db:"builds" json:"builds"`
+	Succeeded     int    `db:"succeeded" json:"succeeded"`
+	Failed        int    `db:"failed" json:"failed"`
+	Aborted       int    `db:"aborted" json:"aborted"`
+	AvgDurationMS int64  `db:"avg_duration_ms" json:"avg_duration_ms"`
+}
+
+// SuccessRate is the share of successful builds, 0..1.
+func (t EnvTotal) SuccessRate() float64 {
+	if t.Builds == 0 {
+		return 0
+	}
+	return float64(t.Succeeded) / float64(t.Builds)
+}
+
+// FormattedAvgDuration renders the average build duration.
+func (t EnvTotal) FormattedAvgDuration() string {
+	return formatDurationMS(t.AvgDurationMS)
+}
+
+// PipelineStat is the outcome and duration summary of one job in a report range.
+type PipelineStat struct {
+	Controller    string    `db:"controller" json:"controller"`
+	ProjectPath   string    `db:"project_path" json:"project_path"`
+	Builds        int       `db:"builds" json:"builds"`
+	Failures      int       `db:"failures" json:"failures"`
+	AvgDurationMS int64     `db:"avg_duration_ms" json:"avg_duration_ms"`
+	P95DurationMS int64     `db:"p95_duration_ms" json:"p95_duration_ms"`
+	MaxDurationMS int64     `db:"max_duration_ms" json:"max_duration_ms"`
+	LastBuildAt   time.Time `db:"last_build_at" json:"last_build_at"`
+}
+
+// FailureRate is the share of failed builds, 0..1.
+func (p PipelineStat) FailureRate() float64 {
+	if p.Builds == 0 {
+		return 0
+	}
+	return float64(p.Failures) / float64(p.Builds)
+}
+
+func (p PipelineStat) FormattedAvgDuration() string {
+	return formatDurationMS(p.AvgDurationMS)
+}
+
+func (p PipelineStat) FormattedP95Duration() string {
+	return formatDurationMS(p.P95DurationMS)
+}
+
+func (p PipelineStat) FormattedMaxDuration() string {
+	return formatDurationMS(p.MaxDurationMS)
+}
+
+// ReportRun is one delivery of a scheduled report.
+type ReportRun struct {
+	ID           int64          `db:"id" json:"id"`
+	Report       string         `db:"report" json:"report"`
+	Trigger      string         `db:"trigger" json:"trigger"` // schedule or manual
+	ScheduledFor *time.Time     `db:"scheduled_for" json:"scheduled_for,omitempty"`
+	RangeFrom    time.Time      `db:"range_from" json:"range_from"`
+	RangeTo      time.Time      `db:"range_to" json:"range_to"`
+	Recipients   pq.StringArray `db:"recipients" json:"recipients"`
+	Status       string         `db:"status" json:"status"` // sent or failed
+	Error        string         `db:"error" json:"error,omitempty"`
+	StartedAt    time.Time      `db:"started_at" json:"started_at"`
+	FinishedAt   time.Time      `db:"finished_at" json:"finished_at"`
+}
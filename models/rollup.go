@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// DailyRollup is the build activity of one day, summed over the rollup groups
+// that match a report's filters.
+type DailyRollup struct {
+	Day             time.Time `db:"day" json:"day"`
+	Builds          int       `db:"builds" json:"builds"`
+	Failures        int       `db:"failures" json:"failures"`
+	TotalDurationMS int64     `db:"total_duration_ms" json:"total_duration_ms"`
+	AvgDurationMS   int64     `db:"avg_duration_ms" json:"avg_duration_ms"`
+	MaxDailyP95MS   int64     `db:"max_daily_p95_ms" json:"max_daily_p95_ms"` // worst p95 of a single job that day, not a p95 of the day
+	Users           int       `db:"users" json:"users"`
+}
+
+// FailureRate is the share of failed builds, 0..1.
+func (r DailyRollup) FailureRate() float64 {
+	if r.Builds == 0 {
+		return 0
+	}
+	return float64(r.Failures) / float64(r.Builds)
+}
+
+// FolderRollup sums the rollups of a top-level folder over a report's range.
+type FolderRollup struct {
+	Folder          string `db:"folder" json:"folder"`
+	Builds          int    `db:"builds" json:"builds"`
+	Failures        int    `db:"failures" json:"failures"`
+	TotalDurationMS int64  `db:"total_duration_ms" json:"total_duration_ms"`
+	AvgDurationMS   int64  `db:"avg_duration_ms" json:"avg_duration_ms"`
+	MaxDailyP95MS   int64  `db:"max_daily_p95_ms" json:"max_daily_p95_ms"` // worst daily p95 of a single job, not a p95 of the folder
+	Users           int    `db:"users" json:"users"`
+}
+
+func (r FolderRollup) FailureRate() float64 {
+	if r.Builds == 0 {
+		return 0
+	}
+	return float64(r.Failures) / float64(r.Builds)
+}
+
+func (r DailyRollup) FormattedAvg() string          { return formatDurationMS(r.AvgDurationMS) }
+func (r DailyRollup) FormattedMaxDailyP95() string  { return formatDurationMS(r.MaxDailyP95MS) }
+func (r FolderRollup) FormattedAvg() string         { return formatDurationMS(r.AvgDurationMS) }
+func (r FolderRollup) FormattedMaxDailyP95() string { return formatDurationMS(r.MaxDailyP95MS) }
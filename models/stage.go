@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// BuildStage is one stage of a Pipeline run as reported by wfapi/describe.
+type BuildStage struct {
+	ID              int       `db:"id"`
+	BuildID         int       `db:"build_id"`
+	StageID         string    `db:"stage_id"`
+	Name            string    `db:"name"`
+	Status          string    `db:"status"` // SUCCESS, FAILED, UNSTABLE, ABORTED, NOT_EXECUTED, ...
+	StartTime       time.Time `db:"start_time"`
+	DurationMS      int64     `db:"duration_ms"`
+	PauseDurationMS int64     `db:"pause_duration_ms"` // time spent waiting on input steps
+	Position        int       `db:"position"`
+}
+
+// StageTrend is the weekly duration summary of one stage of a project.
+type StageTrend struct {
+	Week       time.Time `db:"week" json:"week"`
+	Stage      string    `db:"stage" json:"stage"`
+	Runs       int       `db:"runs" json:"runs"`
+	AvgMS      int64     `db:"avg_ms" json:"avg_duration_ms"`
+	MaxMS      int64     `db:"max_ms" json:"max_duration_ms"`
+	AvgPauseMS int64     `db:"avg_pause_ms" json:"avg_pause_ms"`
+}
+
+func (s BuildStage) FormattedDuration() string {
+	return formatDurationMS(s.DurationMS)
+}
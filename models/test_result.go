@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// TestReport holds the JUnit totals of one build.
+type TestReport struct {
+	BuildID    int       `db:"build_id"`
+	Total      int       `db:"total"`
+	Passed     int       `db:"passed"`
+	Failed     int       `db:"failed"`
+	Skipped    int       `db:"skipped"`
+	DurationMS int64     `db:"duration_ms"`
+	FetchedAt  time.Time `db:"fetched_at"`
+}
+
+// TestCase is the outcome of one JUnit test case in one build.
+type TestCase struct {
+	BuildID    int    `db:"build_id"`
+	Suite      string `db:"suite"`
+	ClassName  string `db:"class_name"`
+	Name       string `db:"name"`
+	Status     string `db:"status"` // PASSED, FAILED, SKIPPED
+	DurationMS int64  `db:"duration_ms"`
+}
+
+// TestOutcome is a test case result together with the build it ran in,
+// ordered input for the flaky test detector.
+type TestOutcome struct {
+	BuildID     int       `db:"build_id"`
+	BuildNumber int       `db:"build_number"`
+	ProjectPath string    `db:"project_path"`
+	CommitSHA   string    `db:"commit_sha"`
+	Timestamp   time.Time `db:"timestamp"`
+	ClassName   string    `db:"class_name"`
+	Name        string    `db:"name"`
+	Status      string    `db:"status"`
+}